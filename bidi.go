@@ -0,0 +1,644 @@
+package canvas
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/bidi"
+)
+
+// Direction is the base direction of a paragraph.
+type Direction int
+
+// see Direction
+const (
+	AutoDirection Direction = iota // determined by the first strong character of each paragraph
+	LeftToRight
+	RightToLeft
+)
+
+// maxBidiDepth is the maximum explicit embedding level, see UAX #9 BD2.
+const maxBidiDepth = 125
+
+// maxBidiBrackets is the maximum number of nested bracket pairs, see UAX #9 BD16.
+const maxBidiBrackets = 63
+
+// bidiLevels returns the resolved embedding levels of the text using the Unicode Bidirectional Algorithm (UAX #9), the text is split into paragraphs at paragraph separators. It returns the embedding level and the paragraph embedding level for each byte of the text, or nil if the text is entirely left-to-right.
+func bidiLevels(s string, dir Direction) ([]int8, []int8) {
+	if dir != RightToLeft {
+		ltr := true
+		for _, r := range s {
+			p, _ := bidi.LookupRune(r)
+			switch p.Class() {
+			case bidi.R, bidi.AL, bidi.AN, bidi.RLE, bidi.RLO, bidi.RLI, bidi.FSI:
+				ltr = false
+			}
+			if !ltr {
+				break
+			}
+		}
+		if ltr {
+			return nil, nil
+		}
+	}
+
+	paraLevel := int8(-1)
+	if dir == LeftToRight {
+		paraLevel = 0
+	} else if dir == RightToLeft {
+		paraLevel = 1
+	}
+
+	levels := make([]int8, len(s))
+	paragraphLevels := make([]int8, len(s))
+	start := 0
+	runes := []rune{}
+	offsets := []int{}
+	for i, r := range s + "\n" {
+		if i < len(s) {
+			runes = append(runes, r)
+			offsets = append(offsets, i)
+			if p, _ := bidi.LookupRune(r); p.Class() != bidi.B {
+				continue
+			}
+			i += utf8.RuneLen(r)
+		}
+
+		runeLevels, level := resolveBidiLevels(runes, paraLevel)
+		for j, offset := range offsets {
+			end := i
+			if j+1 < len(offsets) {
+				end = offsets[j+1]
+			}
+			for k := offset; k < end; k++ {
+				levels[k] = runeLevels[j]
+			}
+		}
+		for k := start; k < i; k++ {
+			paragraphLevels[k] = level
+		}
+		start = i
+		runes = runes[:0]
+		offsets = offsets[:0]
+	}
+	return levels, paragraphLevels
+}
+
+// bidiStatus is an entry of the directional status stack, see UAX #9 X1.
+type bidiStatus struct {
+	level    int8
+	override bidi.Class
+	isolate  bool
+}
+
+// resolveBidiLevels returns the embedding levels of a paragraph and the paragraph embedding level, which is determined from the text if it is negative. It implements the rules P2-P3, X1-X10, W1-W7, N0-N2, I1-I2 and L1 of UAX #9.
+func resolveBidiLevels(runes []rune, paraLevel int8) ([]int8, int8) {
+	n := len(runes)
+	initial := make([]bidi.Class, n)
+	for i, r := range runes {
+		p, _ := bidi.LookupRune(r)
+		initial[i] = p.Class()
+	}
+	types := make([]bidi.Class, n)
+	copy(types, initial)
+
+	// BD9: match isolate initiators with their PDI
+	matchingPDI := make([]int, n)
+	matched := make([]bool, n)
+	isolates := []int{}
+	for i, t := range types {
+		matchingPDI[i] = -1
+		if isIsolateInitiator(t) {
+			isolates = append(isolates, i)
+		} else if t == bidi.PDI && 0 < len(isolates) {
+			matchingPDI[isolates[len(isolates)-1]] = i
+			matched[i] = true
+			isolates = isolates[:len(isolates)-1]
+		}
+	}
+
+	// P2-P3
+	if paraLevel < 0 {
+		paraLevel = firstStrongLevel(types, matchingPDI, 0, n)
+	}
+
+	// X1-X8: explicit embedding levels and directions
+	levels := make([]int8, n)
+	stack := []bidiStatus{{paraLevel, bidi.ON, false}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	for i, t := range initial {
+		top := stack[len(stack)-1]
+		switch t {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.RLI, bidi.LRI, bidi.FSI:
+			isolate := isIsolateInitiator(t)
+			rtl := t == bidi.RLE || t == bidi.RLO || t == bidi.RLI
+			if t == bidi.FSI {
+				end := matchingPDI[i]
+				if end < 0 {
+					end = n
+				}
+				rtl = firstStrongLevel(initial, matchingPDI, i+1, end) == 1
+			}
+
+			levels[i] = top.level
+			if isolate && top.override != bidi.ON {
+				types[i] = top.override
+			}
+
+			level := (top.level + 2) &^ 1
+			if rtl {
+				level = (top.level + 1) | 1
+			}
+			if level <= maxBidiDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				override := bidi.ON
+				if t == bidi.RLO {
+					override = bidi.R
+				} else if t == bidi.LRO {
+					override = bidi.L
+				}
+				if isolate {
+					validIsolates++
+				}
+				stack = append(stack, bidiStatus{level, override, isolate})
+			} else if isolate {
+				overflowIsolates++
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+		case bidi.PDI:
+			if 0 < overflowIsolates {
+				overflowIsolates--
+			} else if 0 < validIsolates {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			levels[i] = top.level
+			if top.override != bidi.ON {
+				types[i] = top.override
+			}
+		case bidi.PDF:
+			if overflowIsolates == 0 {
+				if 0 < overflowEmbeddings {
+					overflowEmbeddings--
+				} else if !top.isolate && 2 <= len(stack) {
+					stack = stack[:len(stack)-1]
+				}
+			}
+			levels[i] = top.level
+		case bidi.B:
+			levels[i] = paraLevel
+		case bidi.BN:
+			levels[i] = top.level
+		default:
+			levels[i] = top.level
+			if top.override != bidi.ON {
+				types[i] = top.override
+			}
+		}
+	}
+
+	// X9-X10: level runs and isolating run sequences, ignoring the characters removed by X9
+	removed := func(i int) bool {
+		switch initial[i] {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.PDF, bidi.BN:
+			return true
+		}
+		return false
+	}
+	runs := [][]int{}
+	runOf := make([]int, n)
+	for i := 0; i < n; i++ {
+		if removed(i) {
+			continue
+		}
+		if len(runs) == 0 || levels[runs[len(runs)-1][0]] != levels[i] {
+			runs = append(runs, []int{})
+		}
+		runs[len(runs)-1] = append(runs[len(runs)-1], i)
+		runOf[i] = len(runs) - 1
+	}
+	for _, run := range runs {
+		if matched[run[0]] {
+			continue // continuation of an isolating run sequence
+		}
+		sequence := append([]int{}, run...)
+		for {
+			last := sequence[len(sequence)-1]
+			if !isIsolateInitiator(initial[last]) || matchingPDI[last] < 0 {
+				break
+			}
+			sequence = append(sequence, runs[runOf[matchingPDI[last]]]...)
+		}
+		resolveBidiSequence(runes, initial, types, levels, sequence, paraLevel, removed, matchingPDI)
+	}
+
+	// characters removed by X9 take the level of the preceding character
+	for i := 0; i < n; i++ {
+		if removed(i) {
+			if i == 0 {
+				levels[i] = paraLevel
+			} else {
+				levels[i] = levels[i-1]
+			}
+		}
+	}
+
+	// L1: reset separators and trailing whitespace to the paragraph level
+	trailing := true
+	for i := n - 1; 0 <= i; i-- {
+		switch initial[i] {
+		case bidi.S, bidi.B:
+			levels[i] = paraLevel
+			trailing = true
+		case bidi.WS, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI, bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO, bidi.PDF, bidi.BN:
+			if trailing {
+				levels[i] = paraLevel
+			}
+		default:
+			trailing = false
+		}
+	}
+	return levels, paraLevel
+}
+
+// resolveBidiSequence resolves the weak and neutral types and the implicit levels of an isolating run sequence, see UAX #9 W1-W7, N0-N2 and I1-I2.
+func resolveBidiSequence(runes []rune, initial, types []bidi.Class, levels []int8, sequence []int, paraLevel int8, removed func(int) bool, matchingPDI []int) {
+	first, last := sequence[0], sequence[len(sequence)-1]
+	level := levels[first]
+
+	// start and end of sequence types
+	prevLevel, nextLevel := paraLevel, paraLevel
+	for i := first - 1; 0 <= i; i-- {
+		if !removed(i) {
+			prevLevel = levels[i]
+			break
+		}
+	}
+	if !isIsolateInitiator(initial[last]) {
+		for i := last + 1; i < len(levels); i++ {
+			if !removed(i) {
+				nextLevel = levels[i]
+				break
+			}
+		}
+	}
+	sos := levelClass(maxLevel(level, prevLevel))
+	eos := levelClass(maxLevel(levels[last], nextLevel))
+	e := levelClass(level)
+
+	t := make([]bidi.Class, len(sequence))
+	for k, i := range sequence {
+		t[k] = types[i]
+	}
+
+	// W1: non-spacing marks take the type of the previous character
+	for k := range t {
+		if t[k] == bidi.NSM {
+			if k == 0 {
+				t[k] = sos
+			} else if prev := initial[sequence[k-1]]; isIsolateInitiator(prev) || prev == bidi.PDI {
+				t[k] = bidi.ON
+			} else {
+				t[k] = t[k-1]
+			}
+		}
+	}
+
+	// W2: European numbers after Arabic letters are Arabic numbers
+	strong := sos
+	for k := range t {
+		if t[k] == bidi.L || t[k] == bidi.R || t[k] == bidi.AL {
+			strong = t[k]
+		} else if t[k] == bidi.EN && strong == bidi.AL {
+			t[k] = bidi.AN
+		}
+	}
+
+	// W3: Arabic letters are right-to-left
+	for k := range t {
+		if t[k] == bidi.AL {
+			t[k] = bidi.R
+		}
+	}
+
+	// W4: single separators between numbers
+	for k := 1; k+1 < len(t); k++ {
+		if t[k] == bidi.ES && t[k-1] == bidi.EN && t[k+1] == bidi.EN {
+			t[k] = bidi.EN
+		} else if t[k] == bidi.CS && t[k-1] == t[k+1] && (t[k-1] == bidi.EN || t[k-1] == bidi.AN) {
+			t[k] = t[k-1]
+		}
+	}
+
+	// W5: terminators adjacent to European numbers
+	for k := 0; k < len(t); k++ {
+		if t[k] != bidi.ET {
+			continue
+		}
+		end := k
+		for end < len(t) && t[end] == bidi.ET {
+			end++
+		}
+		if 0 < k && t[k-1] == bidi.EN || end < len(t) && t[end] == bidi.EN {
+			for ; k < end; k++ {
+				t[k] = bidi.EN
+			}
+		}
+		k = end
+	}
+
+	// W6: remaining separators and terminators are neutral
+	for k := range t {
+		if t[k] == bidi.ES || t[k] == bidi.ET || t[k] == bidi.CS {
+			t[k] = bidi.ON
+		}
+	}
+
+	// W7: European numbers after left-to-right text are left-to-right
+	strong = sos
+	for k := range t {
+		if t[k] == bidi.L || t[k] == bidi.R {
+			strong = t[k]
+		} else if t[k] == bidi.EN && strong == bidi.L {
+			t[k] = bidi.L
+		}
+	}
+
+	// N0: bracket pairs take the embedding direction or the direction of their context
+	strongDirection := func(c bidi.Class) bidi.Class {
+		switch c {
+		case bidi.L:
+			return bidi.L
+		case bidi.R, bidi.EN, bidi.AN:
+			return bidi.R
+		}
+		return bidi.ON
+	}
+	type bracketPair struct{ open, close int }
+	type bracket struct {
+		pos   int
+		close rune
+	}
+	pairs := []bracketPair{}
+	openers := []bracket{}
+	for k, i := range sequence {
+		if t[k] != bidi.ON {
+			continue
+		}
+		r := canonicalBracket(runes[i])
+		p, _ := bidi.LookupRune(r)
+		if !p.IsBracket() {
+			continue
+		} else if p.IsOpeningBracket() {
+			if maxBidiBrackets <= len(openers) {
+				break
+			}
+			close, _ := bidiMirror(r)
+			openers = append(openers, bracket{k, close})
+		} else {
+			for j := len(openers) - 1; 0 <= j; j-- {
+				if openers[j].close == r {
+					pairs = append(pairs, bracketPair{openers[j].pos, k})
+					openers = openers[:j]
+					break
+				}
+			}
+		}
+	}
+	for j := 1; j < len(pairs); j++ {
+		for k := j; 0 < k && pairs[k].open < pairs[k-1].open; k-- {
+			pairs[k], pairs[k-1] = pairs[k-1], pairs[k]
+		}
+	}
+	for _, pair := range pairs {
+		dir := bidi.ON
+		opposite := false
+		for k := pair.open + 1; k < pair.close; k++ {
+			if d := strongDirection(t[k]); d == e {
+				dir = e
+				break
+			} else if d != bidi.ON {
+				opposite = true
+			}
+		}
+		if dir == bidi.ON && opposite {
+			context := sos
+			for k := pair.open - 1; 0 <= k; k-- {
+				if d := strongDirection(t[k]); d != bidi.ON {
+					context = d
+					break
+				}
+			}
+			dir = e
+			if context != e {
+				dir = context
+			}
+		}
+		if dir != bidi.ON {
+			for _, k := range []int{pair.open, pair.close} {
+				t[k] = dir
+				for k++; k < len(t) && initial[sequence[k]] == bidi.NSM; k++ {
+					t[k] = dir
+				}
+			}
+		}
+	}
+
+	// N1-N2: neutrals take the direction of the surrounding text or the embedding direction
+	isNeutral := func(c bidi.Class) bool {
+		switch c {
+		case bidi.B, bidi.S, bidi.WS, bidi.ON, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+			return true
+		}
+		return false
+	}
+	for k := 0; k < len(t); k++ {
+		if !isNeutral(t[k]) {
+			continue
+		}
+		end := k
+		for end < len(t) && isNeutral(t[end]) {
+			end++
+		}
+		before, after := sos, eos
+		if 0 < k {
+			before = strongDirection(t[k-1])
+		}
+		if end < len(t) {
+			after = strongDirection(t[end])
+		}
+		dir := e
+		if before == after {
+			dir = before
+		}
+		for ; k < end; k++ {
+			t[k] = dir
+		}
+		k = end
+	}
+
+	// I1-I2: implicit levels
+	for k, i := range sequence {
+		if levels[i]%2 == 0 {
+			if t[k] == bidi.R {
+				levels[i]++
+			} else if t[k] == bidi.AN || t[k] == bidi.EN {
+				levels[i] += 2
+			}
+		} else if t[k] == bidi.L || t[k] == bidi.EN || t[k] == bidi.AN {
+			levels[i]++
+		}
+		types[i] = t[k]
+	}
+}
+
+// firstStrongLevel returns the level of the first strong character between start and end, skipping isolates, see UAX #9 P2-P3.
+func firstStrongLevel(types []bidi.Class, matchingPDI []int, start, end int) int8 {
+	for i := start; i < end; i++ {
+		switch types[i] {
+		case bidi.L:
+			return 0
+		case bidi.R, bidi.AL:
+			return 1
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			if matchingPDI[i] < 0 {
+				return 0
+			}
+			i = matchingPDI[i]
+		}
+	}
+	return 0
+}
+
+func isIsolateInitiator(c bidi.Class) bool {
+	return c == bidi.LRI || c == bidi.RLI || c == bidi.FSI
+}
+
+func levelClass(level int8) bidi.Class {
+	if level%2 == 0 {
+		return bidi.L
+	}
+	return bidi.R
+}
+
+func maxLevel(a, b int8) int8 {
+	if a < b {
+		return b
+	}
+	return a
+}
+
+// bidiReorder splits the spans of a line where the embedding level changes and reorders them from logical to visual order, see UAX #9 L2. The positions are the byte offsets of the spans into the text of the levels. The spans are placed after each other starting at the position of the first span.
+func bidiReorder(spans []TextSpan, positions []int, levels []int8) []TextSpan {
+	levelAt := func(i int) int8 {
+		if len(levels) <= i {
+			i = len(levels) - 1
+		}
+		return levels[i]
+	}
+
+	runs := []TextSpan{}
+	for j, span := range spans {
+		pos := positions[j]
+		for {
+			level := levelAt(pos)
+			i := 0
+			for i < len(span.Text) && levelAt(pos+i) == level {
+				_, size := utf8.DecodeRuneInString(span.Text[i:])
+				i += size
+			}
+			if i == len(span.Text) {
+				span.level = level
+				runs = append(runs, span)
+				break
+			}
+
+			var run TextSpan
+			run, span = span.cut(i)
+			run.level = level
+			runs = append(runs, run)
+			pos += i
+		}
+	}
+
+	highest, lowestOdd := int8(0), int8(maxBidiDepth+1)
+	for _, run := range runs {
+		if highest < run.level {
+			highest = run.level
+		}
+		if run.level%2 == 1 && run.level < lowestOdd {
+			lowestOdd = run.level
+		}
+	}
+	for level := highest; lowestOdd <= level; level-- {
+		for i := 0; i < len(runs); i++ {
+			if runs[i].level < level {
+				continue
+			}
+			j := i
+			for j < len(runs) && level <= runs[j].level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				runs[a], runs[b] = runs[b], runs[a]
+			}
+			i = j
+		}
+	}
+
+	dx := 0.0
+	if 0 < len(spans) {
+		dx = spans[0].dx
+	}
+	for i := range runs {
+		runs[i].dx = dx
+		dx += runs[i].width
+	}
+	return runs
+}
+
+// bidiMirror returns the mirrored character for characters with the Bidi_Mirrored property, see UAX #9 L4.
+func bidiMirror(r rune) (rune, bool) {
+	mirror, ok := bidiMirrors[r]
+	return mirror, ok
+}
+
+// canonicalBracket returns the canonical equivalent of the deprecated angle brackets.
+func canonicalBracket(r rune) rune {
+	if r == '\u2329' {
+		return '\u3008'
+	} else if r == '\u232A' {
+		return '\u3009'
+	}
+	return r
+}
+
+var bidiMirrors = map[rune]rune{}
+
+func init() {
+	pairs := []rune{
+		'(', ')', '<', '>', '[', ']', '{', '}', '«', '»', '‹', '›',
+		'⁅', '⁆', '⁽', '⁾', '₍', '₎', '∈', '∋', '∉', '∌', '∊', '∍',
+		'∼', '∽', '≃', '⋍', '≤', '≥', '≦', '≧', '≨', '≩', '≪', '≫',
+		'≮', '≯', '≰', '≱', '≲', '≳', '≶', '≷', '≺', '≻', '≼', '≽',
+		'⊂', '⊃', '⊄', '⊅', '⊆', '⊇', '⊈', '⊉', '⊊', '⊋', '⊏', '⊐',
+		'⊑', '⊒', '⊢', '⊣', '⋐', '⋑', '⋖', '⋗', '⋘', '⋙', '⋚', '⋛',
+		'⌈', '⌉', '⌊', '⌋', '\u2329', '\u232A', '❨', '❩', '❪', '❫', '❬', '❭',
+		'❮', '❯', '❰', '❱', '❲', '❳', '❴', '❵', '⟅', '⟆', '⟦', '⟧',
+		'⟨', '⟩', '⟪', '⟫', '⦃', '⦄', '⦅', '⦆', '⦇', '⦈', '⦉', '⦊',
+		'⦋', '⦌', '⦑', '⦒', '⦓', '⦔', '⦕', '⦖', '⦗', '⦘', '⧼', '⧽',
+		'⸂', '⸃', '⸄', '⸅', '⸉', '⸊', '⸌', '⸍', '⸜', '⸝', '⸠', '⸡',
+		'⸢', '⸣', '⸤', '⸥', '⸦', '⸧', '⸨', '⸩', '〈', '〉', '《', '》',
+		'「', '」', '『', '』', '【', '】', '〔', '〕', '〖', '〗', '〘', '〙',
+		'〚', '〛', '﹙', '﹚', '﹛', '﹜', '﹝', '﹞', '﹤', '﹥', '（', '）',
+		'＜', '＞', '［', '］', '｛', '｝', '｟', '｠', '｢', '｣',
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		bidiMirrors[pairs[i]] = pairs[i+1]
+		bidiMirrors[pairs[i+1]] = pairs[i]
+	}
+}
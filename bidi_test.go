@@ -0,0 +1,51 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestBidiLevels(t *testing.T) {
+	var tts = []struct {
+		s         string
+		dir       Direction
+		levels    []int8 // per rune
+		paragraph int8
+	}{
+		{"abc אבג def", AutoDirection, []int8{0, 0, 0, 0, 1, 1, 1, 0, 0, 0, 0}, 0},
+		{"אבג abc 123.", AutoDirection, []int8{1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 1}, 1},
+		{"abc.", RightToLeft, []int8{2, 2, 2, 1}, 1},
+		{"אב (cd) גד", AutoDirection, []int8{1, 1, 1, 1, 2, 2, 1, 1, 1, 1}, 1},
+		{"ab (גד) ef", AutoDirection, []int8{0, 0, 0, 0, 1, 1, 0, 0, 0, 0}, 0},
+		{"אב 1/2 ג", AutoDirection, []int8{1, 1, 1, 2, 2, 2, 1, 1}, 1},
+		{"سلام 12", AutoDirection, []int8{1, 1, 1, 1, 1, 2, 2}, 1},
+		{"abc \u2067אב cd\u2069 ef", AutoDirection, []int8{0, 0, 0, 0, 0, 1, 1, 1, 2, 2, 0, 0, 0, 0}, 0},
+		{"\u202Eabc\u202C d", LeftToRight, []int8{0, 1, 1, 1, 1, 0, 0}, 0},
+		{"אב \n cd", AutoDirection, []int8{1, 1, 1, 1, 0, 0, 0}, 1},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			levels, paragraphLevels := bidiLevels(tt.s, tt.dir)
+			runeLevels := []int8{}
+			for i := range tt.s {
+				runeLevels = append(runeLevels, levels[i])
+			}
+			test.T(t, runeLevels, tt.levels)
+			test.T(t, paragraphLevels[0], tt.paragraph)
+		})
+	}
+
+	levels, _ := bidiLevels("abc def", AutoDirection)
+	test.That(t, levels == nil, "left-to-right text must not be resolved")
+}
+
+func TestBidiMirror(t *testing.T) {
+	r, ok := bidiMirror('(')
+	test.That(t, ok)
+	test.T(t, r, ')')
+	r, _ = bidiMirror('≤')
+	test.T(t, r, '≥')
+	_, ok = bidiMirror('a')
+	test.That(t, !ok)
+}
@@ -29,22 +29,39 @@ const DPI = DPMM(1 / 25.4)
 
 ////////////////////////////////////////////////////////////////
 
-// Style is the path style that defines how to draw the path. When FillColor is transparent it will not fill the path. If StrokeColor is transparent or StrokeWidth is zero, it will not stroke the path. If Dashes is an empty array, it will not draw dashes but instead a solid stroke line. FillRule determines how to fill the path when paths overlap and have certain directions (clockwise, counter clockwise).
+// Paint is the paint used to fill or stroke a path, which is either a solid color or a gradient. Renderers that do not support gradients paint with the color instead.
+type Paint struct {
+	Color    color.RGBA
+	Gradient Gradient
+}
+
+// IsGradient returns true if the paint is a gradient instead of a solid color.
+func (paint Paint) IsGradient() bool {
+	return paint.Gradient != nil
+}
+
+// IsVisible returns true if the paint is a gradient or a non-transparent color.
+func (paint Paint) IsVisible() bool {
+	return paint.Gradient != nil || paint.Color.A != 0
+}
+
+// Style is the path style that defines how to draw the path. When Fill is not visible it will not fill the path. If Stroke is not visible or StrokeWidth is zero, it will not stroke the path. If Dashes is an empty array, it will not draw dashes but instead a solid stroke line. FillRule determines how to fill the path when paths overlap and have certain directions (clockwise, counter clockwise).
 type Style struct {
-	FillColor    color.RGBA
-	StrokeColor  color.RGBA
-	StrokeWidth  float64
-	StrokeCapper Capper
-	StrokeJoiner Joiner
-	DashOffset   float64
-	Dashes       []float64
+	Fill          Paint
+	Stroke        Paint
+	StrokeWidth   float64
+	StrokeProfile StrokeProfile // overrides StrokeWidth when set
+	StrokeCapper  Capper
+	StrokeJoiner  Joiner
+	DashOffset    float64
+	Dashes        []float64
 	FillRule
 }
 
 // DefaultStyle is the default style for paths. It fills the path with a black color.
 var DefaultStyle = Style{
-	FillColor:    Black,
-	StrokeColor:  Transparent,
+	Fill:         Paint{Color: Black},
+	Stroke:       Paint{Color: Transparent},
 	StrokeWidth:  1.0,
 	StrokeCapper: ButtCap,
 	StrokeJoiner: MiterJoin,
@@ -53,6 +70,24 @@ var DefaultStyle = Style{
 	FillRule:     NonZero,
 }
 
+// HasFill returns true if the style fills the path, either with a color or a gradient.
+func (style Style) HasFill() bool {
+	return style.Fill.IsVisible()
+}
+
+// HasStroke returns true if the style strokes the path, either with a color or a gradient.
+func (style Style) HasStroke() bool {
+	return style.Stroke.IsVisible() && (0.0 < style.StrokeWidth || style.StrokeProfile != nil)
+}
+
+// StrokeOutline returns the outline of the stroke of path, which renderers fill when they cannot stroke the path themselves. It uses the stroke profile for strokes of variable width, or the stroke width otherwise. Dashes are not applied.
+func (style Style) StrokeOutline(path *Path) *Path {
+	if style.StrokeProfile != nil {
+		return path.StrokeVariable(style.StrokeProfile, style.StrokeCapper, style.StrokeJoiner)
+	}
+	return path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+}
+
 // Renderer is an interface that renderers implement. It defines the size of the target (in mm) and functions to render paths, text objects and raster images.
 type Renderer interface {
 	Size() (float64, float64)
@@ -61,6 +96,32 @@ type Renderer interface {
 	RenderImage(img image.Image, m Matrix)
 }
 
+// Link is the target of a clickable area. It opens the URI when set, or otherwise jumps to the named destination when set, or otherwise jumps to a page.
+type Link struct {
+	URI  string  // external resource such as a web page
+	Dest string  // named destination in the document, see Context.SetDestination
+	Page int     // page number in the document starting at 1
+	Y    float64 // vertical position on the page in mm that is shown at the top, or zero to show the whole page
+}
+
+// LinkRenderer is an interface that renderers implement when they support clickable areas. RenderLink makes the area of the rectangle clickable, and RenderDestination defines a named destination at the given position on the current page that links can jump to. Both are transformed by m. Renderers that do not implement LinkRenderer ignore links and destinations.
+type LinkRenderer interface {
+	RenderLink(link Link, rect Rect, m Matrix)
+	RenderDestination(name string, pos Point, m Matrix)
+}
+
+// LayerRenderer is an interface that renderers implement when they support named layers, which viewers can show or hide independently. Everything rendered between BeginLayer and the matching EndLayer belongs to the layer, layers can be nested, and layers with the same name are the same layer. Renderers that do not implement LayerRenderer draw the content of all layers.
+type LayerRenderer interface {
+	BeginLayer(name string)
+	EndLayer()
+}
+
+// ClipRenderer is an interface that renderers implement when they support clipping paths. PushClip restricts all following drawing operations to the area filled by the path, intersected with the current clipping area. PopClip removes the last pushed clipping path. Renderers that do not implement ClipRenderer ignore clipping paths.
+type ClipRenderer interface {
+	PushClip(path *Path, fillRule FillRule, m Matrix)
+	PopClip()
+}
+
 ////////////////////////////////////////////////////////////////
 
 // Context maintains the state for the current path, path style, and view transformation matrix.
@@ -72,11 +133,13 @@ type Context struct {
 	styleStack []Style
 	view       Matrix
 	viewStack  []Matrix
+	clips      int
+	clipStack  []int
 }
 
 // NewContext returns a new Context which is a wrapper around a Renderer. Context maintains state for the current path, path style, and view transformation matrix.
 func NewContext(r Renderer) *Context {
-	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil}
+	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil, 0, nil}
 }
 
 // Width returns the width of the canvas.
@@ -95,6 +158,7 @@ func (c *Context) Height() float64 {
 func (c *Context) Push() {
 	c.viewStack = append(c.viewStack, c.view)
 	c.styleStack = append(c.styleStack, c.Style)
+	c.clipStack = append(c.clipStack, c.clips)
 }
 
 // Pop restores the last pushed draw state and uses that as the current draw state. If there are no states on the stack, this will do nothing.
@@ -106,6 +170,15 @@ func (c *Context) Pop() {
 	c.Style = c.styleStack[len(c.styleStack)-1]
 	c.viewStack = c.viewStack[:len(c.viewStack)-1]
 	c.styleStack = c.styleStack[:len(c.styleStack)-1]
+
+	clips := c.clipStack[len(c.clipStack)-1]
+	c.clipStack = c.clipStack[:len(c.clipStack)-1]
+	if clipper, ok := c.Renderer.(ClipRenderer); ok {
+		for ; clips < c.clips; c.clips-- {
+			clipper.PopClip()
+		}
+	}
+	c.clips = clips
 }
 
 // View returns the current affine transformation matrix.
@@ -183,16 +256,36 @@ func (c *Context) ShearAbout(sx, sy, x, y float64) {
 	c.view = c.view.Mul(Identity.ShearAbout(sx, sy, x, y))
 }
 
-// SetFillColor sets the color to be used for filling operations.
+// SetFill sets the paint to be used for filling operations.
+func (c *Context) SetFill(paint Paint) {
+	c.Style.Fill = paint
+}
+
+// SetFillColor sets the color to be used for filling operations. It removes the fill gradient.
 func (c *Context) SetFillColor(col color.Color) {
 	r, g, b, a := col.RGBA()
-	c.Style.FillColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.Style.Fill = Paint{Color: color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}}
+}
+
+// SetFillGradient sets the gradient to be used for filling operations. The gradient is in the coordinate system of the drawn path. The fill color is kept for renderers that do not support gradients.
+func (c *Context) SetFillGradient(gradient Gradient) {
+	c.Style.Fill.Gradient = gradient
+}
+
+// SetStroke sets the paint to be used for stroking operations.
+func (c *Context) SetStroke(paint Paint) {
+	c.Style.Stroke = paint
 }
 
-// SetStrokeColor sets the color to be used for stroking operations.
+// SetStrokeColor sets the color to be used for stroking operations. It removes the stroke gradient.
 func (c *Context) SetStrokeColor(col color.Color) {
 	r, g, b, a := col.RGBA()
-	c.Style.StrokeColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	c.Style.Stroke = Paint{Color: color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}}
+}
+
+// SetStrokeGradient sets the gradient to be used for stroking operations. The gradient is in the coordinate system of the drawn path. The stroke color is kept for renderers that do not support gradients.
+func (c *Context) SetStrokeGradient(gradient Gradient) {
+	c.Style.Stroke.Gradient = gradient
 }
 
 // SetStrokeWidth sets the width in mm for stroking operations.
@@ -200,6 +293,11 @@ func (c *Context) SetStrokeWidth(width float64) {
 	c.Style.StrokeWidth = width
 }
 
+// SetStrokeProfile sets the width of strokes along the path, which overrides the stroke width. Set it to nil to use the stroke width again.
+func (c *Context) SetStrokeProfile(profile StrokeProfile) {
+	c.Style.StrokeProfile = profile
+}
+
 // SetStrokeCapper sets the line cap function to be used for stroke endpoints.
 func (c *Context) SetStrokeCapper(capper Capper) {
 	c.Style.StrokeCapper = capper
@@ -226,6 +324,42 @@ func (c *Context) ResetStyle() {
 	c.Style = DefaultStyle
 }
 
+// ClipPath restricts all following drawing operations to the area filled by path, using the current view and fill rule. The clipping area is intersected with any previous clipping area and is removed by the Pop that matches the last Push. It is ignored if the renderer does not implement ClipRenderer.
+func (c *Context) ClipPath(path *Path) {
+	if clipper, ok := c.Renderer.(ClipRenderer); ok {
+		clipper.PushClip(path, c.Style.FillRule, c.view)
+		c.clips++
+	}
+}
+
+// BeginLayer starts the named layer, which contains everything drawn until the matching EndLayer and can be shown or hidden independently in viewers, such as for dimensions or annotations in technical drawings. It is ignored if the renderer does not implement LayerRenderer.
+func (c *Context) BeginLayer(name string) {
+	if layerer, ok := c.Renderer.(LayerRenderer); ok {
+		layerer.BeginLayer(name)
+	}
+}
+
+// EndLayer ends the layer started by the last BeginLayer.
+func (c *Context) EndLayer() {
+	if layerer, ok := c.Renderer.(LayerRenderer); ok {
+		layerer.EndLayer()
+	}
+}
+
+// DrawLink makes the rectangle at position (x,y) with width w and height h clickable using the current view, where it links to an external resource or another place in the document. It is ignored if the renderer does not implement LinkRenderer.
+func (c *Context) DrawLink(x, y, w, h float64, link Link) {
+	if linker, ok := c.Renderer.(LinkRenderer); ok {
+		linker.RenderLink(link, Rect{x, y, w, h}, c.view)
+	}
+}
+
+// SetDestination defines a named destination at position (x,y) using the current view, which links can jump to. It is ignored if the renderer does not implement LinkRenderer.
+func (c *Context) SetDestination(name string, x, y float64) {
+	if linker, ok := c.Renderer.(LinkRenderer); ok {
+		linker.RenderDestination(name, Point{x, y}, c.view)
+	}
+}
+
 // Pos returns the current position of the path, which is the end point of the last command.
 func (c *Context) Pos() (float64, float64) {
 	return c.path.Pos().X, c.path.Pos().Y
@@ -277,7 +411,7 @@ func (c *Context) Close() {
 // Fill fills the current path and resets it.
 func (c *Context) Fill() {
 	style := c.Style
-	style.StrokeColor = Transparent
+	style.Stroke = Paint{}
 	c.RenderPath(c.path, style, c.view)
 	c.path = &Path{}
 }
@@ -285,7 +419,7 @@ func (c *Context) Fill() {
 // Stroke strokes the current path and resets it.
 func (c *Context) Stroke() {
 	style := c.Style
-	style.FillColor = Transparent
+	style.Fill = Paint{}
 	c.RenderPath(c.path, style, c.view)
 	c.path = &Path{}
 }
@@ -298,7 +432,7 @@ func (c *Context) FillStroke() {
 
 // DrawPath draws a path at position (x,y) using the current draw state.
 func (c *Context) DrawPath(x, y float64, paths ...*Path) {
-	if c.Style.FillColor.A == 0 && (c.Style.StrokeColor.A == 0 || c.Style.StrokeWidth == 0.0) {
+	if !c.Style.HasFill() && !c.Style.HasStroke() {
 		return
 	}
 
@@ -341,13 +475,22 @@ func (c *Context) DrawImage(x, y float64, img image.Image, dpm float64) {
 ////////////////////////////////////////////////////////////////
 
 type layer struct {
-	// path, text OR img is set
-	path *Path
-	text *Text
-	img  image.Image
-
-	m     Matrix
-	style Style // only for path
+	// path, text, img, clip, popClip, link, dest, beginLayer OR endLayer is set
+	path       *Path
+	text       *Text
+	img        image.Image
+	clip       *Path
+	popClip    bool
+	link       *Link
+	dest       string
+	beginLayer bool
+	endLayer   bool
+
+	m         Matrix
+	style     Style  // only for path and clip
+	rect      Rect   // only for link
+	pos       Point  // only for dest
+	layerName string // only for beginLayer
 }
 
 // Canvas stores all drawing operations as layers that can be re-rendered to other renderers.
@@ -386,6 +529,38 @@ func (c *Canvas) RenderImage(img image.Image, m Matrix) {
 	c.layers = append(c.layers, layer{img: img, m: m})
 }
 
+// PushClip adds a clipping path to the canvas using a fill rule and a transformation matrix.
+func (c *Canvas) PushClip(path *Path, fillRule FillRule, m Matrix) {
+	style := DefaultStyle
+	style.FillRule = fillRule
+	c.layers = append(c.layers, layer{clip: path.Copy(), m: m, style: style})
+}
+
+// PopClip removes the last clipping path from the canvas.
+func (c *Canvas) PopClip() {
+	c.layers = append(c.layers, layer{popClip: true})
+}
+
+// RenderLink adds a clickable area to the canvas using a transformation matrix.
+func (c *Canvas) RenderLink(link Link, rect Rect, m Matrix) {
+	c.layers = append(c.layers, layer{link: &link, rect: rect, m: m})
+}
+
+// RenderDestination adds a named destination to the canvas using a transformation matrix.
+func (c *Canvas) RenderDestination(name string, pos Point, m Matrix) {
+	c.layers = append(c.layers, layer{dest: name, pos: pos, m: m})
+}
+
+// BeginLayer starts a named layer on the canvas.
+func (c *Canvas) BeginLayer(name string) {
+	c.layers = append(c.layers, layer{beginLayer: true, layerName: name})
+}
+
+// EndLayer ends the last named layer on the canvas.
+func (c *Canvas) EndLayer() {
+	c.layers = append(c.layers, layer{endLayer: true})
+}
+
 // Empty return true if the canvas is empty.
 func (c *Canvas) Empty() bool {
 	return len(c.layers) == 0
@@ -405,12 +580,15 @@ func (c *Canvas) Fit(margin float64) {
 	}
 
 	rect := Rect{}
+	first := true
 	// TODO: slow when we have many paths (see Graph example)
-	for i, l := range c.layers {
+	for _, l := range c.layers {
 		bounds := Rect{}
-		if l.path != nil {
+		if l.clip != nil || l.popClip || l.link != nil || l.dest != "" || l.beginLayer || l.endLayer {
+			continue
+		} else if l.path != nil {
 			bounds = l.path.Bounds()
-			if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
+			if l.style.HasStroke() {
 				bounds.X -= l.style.StrokeWidth / 2.0
 				bounds.Y -= l.style.StrokeWidth / 2.0
 				bounds.W += l.style.StrokeWidth
@@ -423,8 +601,9 @@ func (c *Canvas) Fit(margin float64) {
 			bounds = Rect{0.0, 0.0, float64(size.X), float64(size.Y)}
 		}
 		bounds = bounds.Transform(l.m)
-		if i == 0 {
+		if first {
 			rect = bounds
+			first = false
 		} else {
 			rect = rect.Add(bounds)
 		}
@@ -442,6 +621,9 @@ func (c *Canvas) Render(r Renderer) {
 	if viewer, ok := r.(interface{ View() Matrix }); ok {
 		view = viewer.View()
 	}
+	clipper, _ := r.(ClipRenderer)
+	linker, _ := r.(LinkRenderer)
+	layerer, _ := r.(LayerRenderer)
 	for _, l := range c.layers {
 		m := view.Mul(l.m)
 		if l.path != nil {
@@ -450,6 +632,18 @@ func (c *Canvas) Render(r Renderer) {
 			r.RenderText(l.text, m)
 		} else if l.img != nil {
 			r.RenderImage(l.img, m)
+		} else if l.clip != nil && clipper != nil {
+			clipper.PushClip(l.clip, l.style.FillRule, m)
+		} else if l.popClip && clipper != nil {
+			clipper.PopClip()
+		} else if l.link != nil && linker != nil {
+			linker.RenderLink(*l.link, l.rect, m)
+		} else if l.dest != "" && linker != nil {
+			linker.RenderDestination(l.dest, l.pos, m)
+		} else if l.beginLayer && layerer != nil {
+			layerer.BeginLayer(l.layerName)
+		} else if l.endLayer && layerer != nil {
+			layerer.EndLayer()
 		}
 	}
 }
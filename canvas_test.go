@@ -65,3 +65,105 @@ func TestCanvasFit(t *testing.T) {
 	test.Float(t, c.W, 20)
 	test.Float(t, c.H, 20)
 }
+
+func TestCanvasClip(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetFillRule(EvenOdd)
+	ctx.Push()
+	ctx.ClipPath(Rectangle(10.0, 10.0))
+	ctx.DrawPath(5.0, 5.0, Rectangle(10.0, 10.0))
+	ctx.Pop()
+
+	test.T(t, len(c.layers), 3)
+	test.That(t, c.layers[0].clip != nil, "expected clip layer")
+	test.T(t, c.layers[0].style.FillRule, EvenOdd)
+	test.That(t, c.layers[1].path != nil, "expected path layer")
+	test.That(t, c.layers[2].popClip, "expected pop clip layer")
+
+	c.Fit(0.0)
+	test.Float(t, c.W, 10.0) // clip layers don't contribute to bounds
+	test.Float(t, c.H, 10.0)
+}
+
+func TestCanvasLayer(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.BeginLayer("Dimensions")
+	ctx.DrawPath(5.0, 5.0, Rectangle(10.0, 10.0))
+	ctx.EndLayer()
+
+	test.T(t, len(c.layers), 3)
+	test.That(t, c.layers[0].beginLayer)
+	test.T(t, c.layers[0].layerName, "Dimensions")
+	test.That(t, c.layers[2].endLayer)
+
+	c2 := New(100, 100)
+	c.Render(c2)
+	test.T(t, len(c2.layers), 3)
+	test.That(t, c2.layers[0].beginLayer)
+	test.T(t, c2.layers[0].layerName, "Dimensions")
+	test.That(t, c2.layers[2].endLayer)
+
+	// layers without a name are replayed as well, so that EndLayer stays balanced
+	c3 := New(100, 100)
+	c3.BeginLayer("")
+	c3.EndLayer()
+	c4 := New(100, 100)
+	c3.Render(c4)
+	test.T(t, len(c4.layers), 2)
+	test.That(t, c4.layers[0].beginLayer)
+	test.T(t, c4.layers[0].layerName, "")
+
+	c.Fit(0.0)
+	test.Float(t, c.W, 10.0) // layer markers don't contribute to bounds
+	test.Float(t, c.H, 10.0)
+}
+
+func TestContextPaint(t *testing.T) {
+	gradient := NewLinearGradient(Point{0.0, 0.0}, Point{10.0, 0.0})
+	gradient.Add(0.0, Red)
+	gradient.Add(1.0, Blue)
+
+	ctx := NewContext(New(100, 100))
+	ctx.SetFillColor(Red)
+	ctx.SetFillGradient(gradient)
+	test.That(t, ctx.Style.Fill.IsGradient())
+	test.T(t, ctx.Style.Fill.Color, Red) // kept for renderers without gradients
+	test.That(t, ctx.HasFill())
+
+	ctx.SetFillColor(Green)
+	test.That(t, !ctx.Style.Fill.IsGradient())
+	test.T(t, ctx.Style.Fill.Color, Green)
+
+	ctx.SetStroke(Paint{Gradient: gradient})
+	test.That(t, ctx.Style.Stroke.IsVisible())
+	test.That(t, ctx.HasStroke())
+
+	ctx.SetStroke(Paint{Color: Transparent})
+	test.That(t, !ctx.HasStroke())
+}
+
+func TestCanvasLink(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.DrawPath(5.0, 5.0, Rectangle(10.0, 10.0))
+	ctx.DrawLink(5.0, 5.0, 10.0, 10.0, Link{URI: "https://example.com/"})
+	ctx.SetDestination("top", 0.0, 50.0)
+
+	test.T(t, len(c.layers), 3)
+	test.T(t, *c.layers[1].link, Link{URI: "https://example.com/"})
+	test.T(t, c.layers[1].rect, Rect{5.0, 5.0, 10.0, 10.0})
+	test.T(t, c.layers[2].dest, "top")
+	test.T(t, c.layers[2].pos, Point{0.0, 50.0})
+
+	c2 := New(100, 100)
+	c.Render(c2)
+	test.T(t, len(c2.layers), 3)
+	test.T(t, c2.layers[1].link.URI, "https://example.com/")
+	test.T(t, c2.layers[2].dest, "top")
+
+	c.Fit(0.0)
+	test.Float(t, c.W, 10.0) // link layers don't contribute to bounds
+	test.Float(t, c.H, 10.0)
+}
@@ -0,0 +1,202 @@
+package eps
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dtrenin7/canvas"
+	canvasFont "github.com/dtrenin7/canvas/font"
+)
+
+// type42Tables are the SFNT tables needed by the TrueType rasterizer of PostScript interpreters, see Adobe Technical Note #5012.
+var type42Tables = []string{"cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "prep", "vhea", "vmtx"}
+
+// maxSfntsString is the maximum length of the strings in the sfnts array, which can hold at most 65535 bytes including the padding byte.
+const maxSfntsString = 65534
+
+// getFont returns the name of the embedded Type 42 font, embedding it on first use. It returns false if the font has no TrueType outlines and cannot be embedded.
+func (r *Renderer) getFont(font *canvas.Font) (string, bool) {
+	if name, ok := r.fonts[font]; ok {
+		return name, name != ""
+	}
+
+	_, b := font.Raw()
+	b, err := canvasFont.ToSFNT(b)
+	if err == nil {
+		name := psFontName(font.Name())
+		for _, other := range r.fonts {
+			if other == name {
+				name += "-" + strconv.Itoa(len(r.fonts))
+				break
+			}
+		}
+		if err = writeType42(r.w, name, b); err == nil {
+			r.fonts[font] = name
+			return name, true
+		}
+	}
+	r.fonts[font] = ""
+	return "", false
+}
+
+// psFontName returns a valid PostScript name for the font.
+func psFontName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	if name == "" {
+		name = "Font"
+	}
+	return name
+}
+
+// glyphName returns the name of the glyph in the CharStrings dictionary of the Type 42 font.
+func glyphName(index uint16) string {
+	if index == 0 {
+		return ".notdef"
+	}
+	return "g" + strconv.Itoa(int(index))
+}
+
+type sfntTable struct {
+	tag      string
+	checksum uint32
+	data     []byte
+}
+
+// writeType42 writes the TrueType font as a Type 42 font resource, see Adobe Technical Note #5012. Only the tables needed for rendering are included and glyphs are named after their index, see glyphName.
+func writeType42(w io.Writer, name string, b []byte) error {
+	if len(b) < 12 || binary.BigEndian.Uint32(b) != 0x00010000 && string(b[:4]) != "true" {
+		return fmt.Errorf("EPS: font has no TrueType outlines")
+	}
+
+	tables := map[string]sfntTable{}
+	numTables := int(binary.BigEndian.Uint16(b[4:]))
+	if len(b) < 12+16*numTables {
+		return fmt.Errorf("EPS: invalid font")
+	}
+	for i := 0; i < numTables; i++ {
+		rec := b[12+16*i:]
+		tag := string(rec[:4])
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		if uint32(len(b)) < offset || uint32(len(b))-offset < length {
+			return fmt.Errorf("EPS: invalid %s table", tag)
+		}
+		tables[tag] = sfntTable{tag, binary.BigEndian.Uint32(rec[4:]), b[offset : offset+length]}
+	}
+	for _, tag := range []string{"glyf", "head", "hhea", "hmtx", "loca", "maxp"} {
+		if _, ok := tables[tag]; !ok {
+			return fmt.Errorf("EPS: font misses %s table", tag)
+		}
+	}
+	head, maxp := tables["head"].data, tables["maxp"].data
+	if len(head) < 54 || len(maxp) < 6 {
+		return fmt.Errorf("EPS: invalid font")
+	}
+	unitsPerEm := float64(binary.BigEndian.Uint16(head[18:]))
+	xMin, yMin := float64(int16(binary.BigEndian.Uint16(head[36:]))), float64(int16(binary.BigEndian.Uint16(head[38:])))
+	xMax, yMax := float64(int16(binary.BigEndian.Uint16(head[40:]))), float64(int16(binary.BigEndian.Uint16(head[42:])))
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	// glyph offsets into the glyf table, strings can only be split at glyph boundaries
+	loca := tables["loca"].data
+	glyphOffsets := make([]uint32, 0, numGlyphs+1)
+	for i := 0; i <= numGlyphs; i++ {
+		if longLoca && 4*i+4 <= len(loca) {
+			glyphOffsets = append(glyphOffsets, binary.BigEndian.Uint32(loca[4*i:]))
+		} else if !longLoca && 2*i+2 <= len(loca) {
+			glyphOffsets = append(glyphOffsets, 2*uint32(binary.BigEndian.Uint16(loca[2*i:])))
+		}
+	}
+
+	// build the table directory of the reduced font
+	included := []sfntTable{}
+	for _, tag := range type42Tables {
+		if table, ok := tables[tag]; ok {
+			included = append(included, table)
+		}
+	}
+	sort.Slice(included, func(i, j int) bool { return included[i].tag < included[j].tag })
+
+	entrySelector := 0
+	for 2<<entrySelector <= len(included) {
+		entrySelector++
+	}
+	searchRange := 16 << entrySelector
+	dir := make([]byte, 12+16*len(included))
+	binary.BigEndian.PutUint32(dir[0:], 0x00010000)
+	binary.BigEndian.PutUint16(dir[4:], uint16(len(included)))
+	binary.BigEndian.PutUint16(dir[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(dir[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(dir[10:], uint16(16*len(included)-searchRange))
+	offset := uint32(len(dir))
+	for i, table := range included {
+		rec := dir[12+16*i:]
+		copy(rec, table.tag)
+		binary.BigEndian.PutUint32(rec[4:], table.checksum)
+		binary.BigEndian.PutUint32(rec[8:], offset)
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(table.data)))
+		offset += (uint32(len(table.data)) + 3) &^ 3
+	}
+
+	// split the font data into strings that end at table or glyph boundaries
+	sfnts := [][]byte{dir}
+	for _, table := range included {
+		data := table.data
+		if pad := (4 - len(data)%4) % 4; pad != 0 {
+			data = append(append([]byte{}, data...), make([]byte, pad)...)
+		}
+		if table.tag == "glyf" {
+			start := uint32(0)
+			for i := 1; i < len(glyphOffsets) && glyphOffsets[i] <= uint32(len(data)); i++ {
+				if maxSfntsString < glyphOffsets[i]-start && start < glyphOffsets[i-1] {
+					sfnts = append(sfnts, data[start:glyphOffsets[i-1]])
+					start = glyphOffsets[i-1]
+				}
+			}
+			data = data[start:]
+		} else if maxSfntsString < len(data) {
+			return fmt.Errorf("EPS: %s table too large", table.tag)
+		}
+		sfnts = append(sfnts, data)
+	}
+
+	fmt.Fprintf(w, "\n%%%%BeginResource: font %s\n11 dict begin\n/FontName /%s def\n/FontType 42 def\n/PaintType 0 def\n", name, name)
+	fmt.Fprintf(w, "/FontMatrix [1 0 0 1 0 0] def\n/FontBBox [%v %v %v %v] def\n", dec(xMin/unitsPerEm), dec(yMin/unitsPerEm), dec(xMax/unitsPerEm), dec(yMax/unitsPerEm))
+	fmt.Fprintf(w, "/Encoding 256 array 0 1 255 {1 index exch /.notdef put} for def\n")
+	fmt.Fprintf(w, "/CharStrings %d dict dup begin\n/.notdef 0 def", numGlyphs)
+	for i := 1; i < numGlyphs; i++ {
+		if i%8 == 1 {
+			fmt.Fprintf(w, "\n")
+		} else {
+			fmt.Fprintf(w, " ")
+		}
+		fmt.Fprintf(w, "/%s %d def", glyphName(uint16(i)), i)
+	}
+	fmt.Fprintf(w, "\nend readonly def\n/sfnts [")
+	for _, data := range sfnts {
+		// the last byte of each string is ignored, so we add a zero byte
+		fmt.Fprintf(w, "\n<")
+		for i := 0; i < len(data); i += 36 {
+			if 0 < i {
+				fmt.Fprintf(w, "\n")
+			}
+			j := i + 36
+			if len(data) < j {
+				j = len(data)
+			}
+			fmt.Fprintf(w, "%X", data[i:j])
+		}
+		fmt.Fprintf(w, "00>")
+	}
+	fmt.Fprintf(w, "\n] def\nFontName currentdict end definefont pop\n%%%%EndResource\n")
+	return nil
+}
@@ -1,6 +1,8 @@
 package eps
 
 import (
+	"bytes"
+	"compress/zlib"
 	"fmt"
 	"image"
 	"image/color"
@@ -12,6 +14,8 @@ import (
 	"github.com/dtrenin7/minify/v2"
 )
 
+const ptPerMm = 72 / 25.4
+
 var psEllipseDef = `/ellipse {
 /rot exch def
 /endangle exch def
@@ -42,59 +46,379 @@ xrad yrad scale
 savematrix setmatrix
 } def`
 
+// ImageFilter is the filter used to compress embedded images. The compressed data is always ASCII85 encoded.
+type ImageFilter int
+
+// see ImageFilter
+const (
+	FlateFilter     ImageFilter = iota // requires PostScript LanguageLevel 3
+	RunLengthFilter                    // requires PostScript LanguageLevel 2
+	NoFilter
+)
+
+type epsGraphicsState struct {
+	color      color.RGBA
+	lineWidth  float64
+	lineCap    int
+	lineJoin   int
+	miterLimit float64
+	dashes     []float64 // dashArray and dashPhase
+	font       string
+	fontMatrix canvas.Matrix
+}
+
+// Renderer is an encapsulated PostScript renderer. EPS does not support transparency, colors are composited onto a white background instead, which is exact for elements drawn on white but not for overlapping elements. Fully transparent elements are not drawn. Gradients are replaced by the solid fill and stroke colors. Text using TrueType fonts is embedded as Type 42 fonts, other fonts are converted to paths.
 type Renderer struct {
 	w             io.Writer
 	width, height float64
-	color         color.RGBA
+	imgFilter     ImageFilter
+	fonts         map[*canvas.Font]string
+
+	epsGraphicsState
+	stateStack []epsGraphicsState
 }
 
 // New creates an encapsulated PostScript renderer.
 func New(w io.Writer, width, height float64) *Renderer {
-	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width), dec(height))
+	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %d %d\n", int(math.Ceil(width*ptPerMm)), int(math.Ceil(height*ptPerMm)))
+	fmt.Fprintf(w, "%%%%HiResBoundingBox: 0 0 %v %v\n%%%%LanguageLevel: 3\n%%%%EndComments\n", dec(width*ptPerMm), dec(height*ptPerMm))
 	fmt.Fprintf(w, psEllipseDef)
+	fmt.Fprintf(w, " %v %v scale", dec(ptPerMm), dec(ptPerMm))
 	// TODO: (EPS) generate and add preview
 
+	// for defaults see the PostScript Language Reference Manual, section 4.3
 	return &Renderer{
 		w:      w,
 		width:  width,
 		height: height,
-		color:  canvas.Black,
+		fonts:  map[*canvas.Font]string{},
+		epsGraphicsState: epsGraphicsState{
+			color:      canvas.Black,
+			lineWidth:  1.0,
+			lineCap:    0,
+			lineJoin:   0,
+			miterLimit: 10.0,
+			dashes:     []float64{0.0},
+		},
 	}
 }
 
-func (r *Renderer) setColor(color color.RGBA) {
-	if color != r.color {
-		fmt.Fprintf(r.w, " %v %v %v setrgbcolor", dec(float64(color.R)/255.0), dec(float64(color.G)/255.0), dec(float64(color.B)/255.0))
-		r.color = color
-	}
+// SetImageFilter sets the filter used to compress embedded images, the default is FlateFilter.
+func (r *Renderer) SetImageFilter(filter ImageFilter) {
+	r.imgFilter = filter
 }
 
 func (r *Renderer) Size() (float64, float64) {
 	return r.width, r.height
 }
 
+func (r *Renderer) saveState() {
+	fmt.Fprintf(r.w, " gsave")
+	state := r.epsGraphicsState
+	state.dashes = append([]float64{}, r.dashes...)
+	r.stateStack = append(r.stateStack, state)
+}
+
+func (r *Renderer) restoreState() {
+	fmt.Fprintf(r.w, " grestore")
+	r.epsGraphicsState = r.stateStack[len(r.stateStack)-1]
+	r.stateStack = r.stateStack[:len(r.stateStack)-1]
+}
+
+// setColor sets the current color, the alpha channel is removed by compositing the color onto white.
+func (r *Renderer) setColor(col color.RGBA) {
+	// colors are premultiplied, adding the white background that shines through gives the composited color
+	col = color.RGBA{col.R + (255 - col.A), col.G + (255 - col.A), col.B + (255 - col.A), 255}
+	if col != r.color {
+		fmt.Fprintf(r.w, " %v %v %v setrgbcolor", dec(float64(col.R)/255.0), dec(float64(col.G)/255.0), dec(float64(col.B)/255.0))
+		r.color = col
+	}
+}
+
+func (r *Renderer) setLineWidth(lineWidth float64) {
+	if lineWidth != r.lineWidth {
+		fmt.Fprintf(r.w, " %v setlinewidth", dec(lineWidth))
+		r.lineWidth = lineWidth
+	}
+}
+
+func (r *Renderer) setLineCap(capper canvas.Capper) {
+	var lineCap int
+	if _, ok := capper.(canvas.ButtCapper); ok {
+		lineCap = 0
+	} else if _, ok := capper.(canvas.RoundCapper); ok {
+		lineCap = 1
+	} else if _, ok := capper.(canvas.SquareCapper); ok {
+		lineCap = 2
+	} else {
+		panic("EPS: line cap not support")
+	}
+	if lineCap != r.lineCap {
+		fmt.Fprintf(r.w, " %d setlinecap", lineCap)
+		r.lineCap = lineCap
+	}
+}
+
+func (r *Renderer) setLineJoin(joiner canvas.Joiner) {
+	var lineJoin int
+	var miterLimit float64
+	if _, ok := joiner.(canvas.BevelJoiner); ok {
+		lineJoin = 2
+	} else if _, ok := joiner.(canvas.RoundJoiner); ok {
+		lineJoin = 1
+	} else if miter, ok := joiner.(canvas.MiterJoiner); ok && !math.IsNaN(miter.Limit) {
+		lineJoin = 0
+		miterLimit = math.Max(1.0, miter.Limit)
+	} else {
+		panic("EPS: line join not support")
+	}
+	if lineJoin != r.lineJoin {
+		fmt.Fprintf(r.w, " %d setlinejoin", lineJoin)
+		r.lineJoin = lineJoin
+	}
+	if lineJoin == 0 && miterLimit != r.miterLimit {
+		fmt.Fprintf(r.w, " %v setmiterlimit", dec(miterLimit))
+		r.miterLimit = miterLimit
+	}
+}
+
+func (r *Renderer) setDashes(dashPhase float64, dashArray []float64) {
+	if len(dashArray)%2 == 1 {
+		dashArray = append(dashArray, dashArray...)
+	}
+
+	// use a positive dash phase
+	if dashPhase < 0.0 {
+		totalLength := 0.0
+		for _, dash := range dashArray {
+			totalLength += dash
+		}
+		if 0.0 < totalLength {
+			dashPhase = math.Mod(dashPhase, totalLength) + totalLength
+		}
+	}
+
+	dashes := append(append([]float64{}, dashArray...), dashPhase)
+	if !float64sEqual(dashes, r.dashes) {
+		if len(dashes) == 1 {
+			fmt.Fprintf(r.w, " [] 0 setdash")
+			dashes[0] = 0.0
+		} else {
+			fmt.Fprintf(r.w, " [%v", dec(dashes[0]))
+			for _, dash := range dashes[1 : len(dashes)-1] {
+				fmt.Fprintf(r.w, " %v", dec(dash))
+			}
+			fmt.Fprintf(r.w, "] %v setdash", dec(dashes[len(dashes)-1]))
+		}
+		r.dashes = dashes
+	}
+}
+
+// setFont selects the font scaled and transformed by the matrix, the translation component is ignored.
+func (r *Renderer) setFont(name string, m canvas.Matrix) {
+	m[0][2], m[1][2] = 0.0, 0.0
+	if name != r.font || !m.Equals(r.fontMatrix) {
+		fmt.Fprintf(r.w, " /%s findfont [%v %v %v %v 0 0] makefont setfont", name, dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]))
+		r.font = name
+		r.fontMatrix = m
+	}
+}
+
 func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	// TODO: (EPS) test ellipse, rotations etc
-	// TODO: (EPS) add drawState support
-	// TODO: (EPS) use dither to fake transparency
-	r.setColor(style.FillColor)
-	r.w.Write([]byte(" "))
-	r.w.Write([]byte(path.Transform(m).ToPS()))
-	r.w.Write([]byte(" fill"))
+	// gradients are not supported, the solid colors are used instead
+	fill := style.Fill.Color.A != 0
+	stroke := style.Stroke.Color.A != 0 && (0.0 < style.StrokeWidth || style.StrokeProfile != nil)
+
+	// EPS doesn't support variable stroke widths, the arcs joiner, miter joiner (not clipped), miter joiner (clipped) with non-bevel fallback, or custom cappers and joiners
+	strokeUnsupported := style.StrokeProfile != nil
+	switch style.StrokeCapper.(type) {
+	case canvas.ButtCapper, canvas.RoundCapper, canvas.SquareCapper:
+	default:
+		strokeUnsupported = true
+	}
+	switch joiner := style.StrokeJoiner.(type) {
+	case canvas.BevelJoiner, canvas.RoundJoiner:
+	case canvas.MiterJoiner:
+		if math.IsNaN(joiner.Limit) {
+			strokeUnsupported = true
+		} else if _, ok := joiner.GapJoiner.(canvas.BevelJoiner); !ok {
+			strokeUnsupported = true
+		}
+	default:
+		strokeUnsupported = true
+	}
+
+	path = path.Transform(m)
+	data := path.ToPS()
+	if data == "" {
+		return
+	}
+
+	fillOp := " fill"
+	if style.FillRule == canvas.EvenOdd {
+		fillOp = " eofill"
+	}
+	if fill && stroke && !strokeUnsupported {
+		// the path is preserved after filling by saving and restoring the graphics state
+		r.setColor(style.Fill.Color)
+		fmt.Fprintf(r.w, " %s gsave%s grestore", data, fillOp)
+		r.setColor(style.Stroke.Color)
+		r.setLineWidth(style.StrokeWidth)
+		r.setLineCap(style.StrokeCapper)
+		r.setLineJoin(style.StrokeJoiner)
+		r.setDashes(style.DashOffset, style.Dashes)
+		fmt.Fprintf(r.w, " stroke")
+		return
+	}
+
+	if fill {
+		r.setColor(style.Fill.Color)
+		fmt.Fprintf(r.w, " %s%s", data, fillOp)
+	}
+	if stroke {
+		if !strokeUnsupported {
+			r.setColor(style.Stroke.Color)
+			r.setLineWidth(style.StrokeWidth)
+			r.setLineCap(style.StrokeCapper)
+			r.setLineJoin(style.StrokeJoiner)
+			r.setDashes(style.DashOffset, style.Dashes)
+			fmt.Fprintf(r.w, " %s stroke", data)
+		} else {
+			// stroke settings unsupported by EPS, draw stroke explicitly
+			if 0 < len(style.Dashes) {
+				path = path.Dash(style.DashOffset, style.Dashes...)
+			}
+			path = style.StrokeOutline(path)
+
+			r.setColor(style.Stroke.Color)
+			fmt.Fprintf(r.w, " %s fill", path.ToPS())
+		}
+	}
 }
 
 func (r *Renderer) RenderText(text *canvas.Text, m canvas.Matrix) {
-	// TODO: (EPS) write text natively
-	paths, colors := text.ToPaths()
-	for i, path := range paths {
-		style := canvas.DefaultStyle
-		style.FillColor = colors[i]
-		r.RenderPath(path, style, m)
+	if text.OnPath() {
+		text.RenderAsPath(r, m)
+		return
 	}
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		name, ok := r.getFont(span.Face.Font)
+		if !ok || 0.0 < span.Face.FauxBold {
+			// draw the span as a path, faux bold is drawn by stroking the glyph outlines
+			p, _, col := span.ToPath(0.0)
+			style := canvas.DefaultStyle
+			style.Fill = canvas.Paint{Color: col}
+			if 0.0 < span.Face.FauxBold {
+				style.Stroke = canvas.Paint{Color: col}
+				style.StrokeWidth = span.Face.FauxBold * 2.0
+			}
+			r.RenderPath(p, style, m.Translate(dx, y))
+			return
+		} else if span.Face.Color.A == 0 {
+			return
+		}
+
+		size := span.Face.Size * span.Face.Scale
+		fm := m.Translate(dx, y+span.Face.Voffset).Shear(span.Face.FauxItalic, 0.0)
+		origin := fm.Dot(canvas.Point{X: 0.0, Y: 0.0})
+		r.setColor(span.Face.Color)
+		r.setFont(name, fm.Scale(size, size))
+		fmt.Fprintf(r.w, " %v %v moveto", dec(origin.X), dec(origin.Y))
+
+		// glyphshow advances by the glyph width, glyph positioning and spacing are added explicitly
+		var advance canvas.Point
+		rmoveto := func() {
+			if advance.X != 0.0 || advance.Y != 0.0 {
+				d := fm.Dot(advance).Sub(origin)
+				fmt.Fprintf(r.w, " %v %v rmoveto", dec(d.X), dec(d.Y))
+				advance = canvas.Point{}
+			}
+		}
+
+		for _, glyph := range span.Glyphs() {
+			advance = advance.Add(canvas.Point{X: glyph.XOffset, Y: glyph.YOffset})
+			rmoveto()
+			fmt.Fprintf(r.w, " /%s glyphshow", glyphName(glyph.ID))
+			advance.X += glyph.XAdvance - glyph.XOffset - span.Face.Font.GlyphAdvance(glyph.ID, size)
+			advance.Y -= glyph.YOffset
+		}
+	})
+
+	text.RenderDecoration(r, m)
 }
 
 func (r *Renderer) RenderImage(img image.Image, m canvas.Matrix) {
-	// TODO: (EPS) write image
+	size := img.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return
+	}
+
+	// composite the premultiplied colors onto white
+	sp := img.Bounds().Min // starting point
+	b := make([]byte, size.X*size.Y*3)
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			i := (y*size.X + x) * 3
+			R, G, B, A := img.At(sp.X+x, sp.Y+y).RGBA()
+			b[i+0] = byte((R + 0xffff - A) >> 8)
+			b[i+1] = byte((G + 0xffff - A) >> 8)
+			b[i+2] = byte((B + 0xffff - A) >> 8)
+		}
+	}
+
+	filter := ""
+	switch r.imgFilter {
+	case FlateFilter:
+		buf := &bytes.Buffer{}
+		zw := zlib.NewWriter(buf)
+		zw.Write(b)
+		zw.Close()
+		b = buf.Bytes()
+		filter = " /FlateDecode filter"
+	case RunLengthFilter:
+		b = runLengthEncode(b)
+		filter = " /RunLengthDecode filter"
+	}
+
+	m = m.Scale(float64(size.X), float64(size.Y))
+	fmt.Fprintf(r.w, " gsave [%v %v %v %v %v %v] concat", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	fmt.Fprintf(r.w, " %d %d 8 [%d 0 0 %d 0 %d] currentfile /ASCII85Decode filter%s false 3 colorimage\n", size.X, size.Y, size.X, -size.Y, size.Y, filter)
+	writeASCII85(r.w, b)
+	fmt.Fprintf(r.w, "\n grestore")
+}
+
+// PushClip restricts drawing to the area filled by the path, intersected with the current clipping area.
+func (r *Renderer) PushClip(path *canvas.Path, fillRule canvas.FillRule, m canvas.Matrix) {
+	r.saveState()
+	data := path.Transform(m).ToPS()
+	if data == "" {
+		fmt.Fprintf(r.w, " 0 0 0 0 rectclip")
+	} else if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(r.w, " %s eoclip newpath", data)
+	} else {
+		fmt.Fprintf(r.w, " %s clip newpath", data)
+	}
+}
+
+// PopClip removes the last clipping path.
+func (r *Renderer) PopClip() {
+	if 0 < len(r.stateStack) {
+		r.restoreState()
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+func float64sEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, f := range a {
+		if f != b[i] {
+			return false
+		}
+	}
+	return true
 }
 
 type dec float64
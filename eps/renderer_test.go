@@ -2,14 +2,110 @@ package eps
 
 import (
 	"bytes"
+	"image"
+	"image/color"
+	"strings"
 	"testing"
 
 	"github.com/dtrenin7/canvas"
+	"github.com/dtrenin7/test"
 )
 
 func TestEPS(t *testing.T) {
 	w := &bytes.Buffer{}
 	eps := New(w, 100, 80)
 	eps.setColor(canvas.Red)
-	//test.String(t, string(w.Bytes()), "")
+	test.That(t, strings.HasPrefix(w.String(), "%!PS-Adobe-3.0 EPSF-3.0\n%%BoundingBox: 0 0 284 227\n"))
+	test.That(t, strings.HasSuffix(w.String(), " 2.8346457 2.8346457 scale 1 0 0 setrgbcolor"))
+
+	// transparent colors are composited onto white
+	w.Reset()
+	eps.setColor(color.RGBA{0, 0, 128, 128})
+	test.String(t, w.String(), " .49803922 .49803922 1 setrgbcolor")
+}
+
+func TestEPSPath(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	w.Reset()
+
+	style := canvas.DefaultStyle
+	style.Fill.Color = canvas.Red
+	style.Stroke.Color = canvas.Blue
+	style.StrokeWidth = 2.0
+	style.StrokeCapper = canvas.RoundCap
+	style.StrokeJoiner = canvas.BevelJoin
+	style.Dashes = []float64{1.0, 2.0, 3.0}
+	style.DashOffset = -1.0
+	style.FillRule = canvas.EvenOdd
+	eps.RenderPath(canvas.MustParseSVG("L10 0L10 10z"), style, canvas.Identity)
+	test.String(t, w.String(), " 1 0 0 setrgbcolor 0 0 moveto 10 0 lineto 10 10 lineto closepath gsave eofill grestore 0 0 1 setrgbcolor 2 setlinewidth 1 setlinecap 2 setlinejoin [1 2 3 1 2 3] 11 setdash stroke")
+
+	// arcs joiner is stroked as a filled outline
+	w.Reset()
+	style.Fill.Color = canvas.Transparent
+	style.StrokeCapper = canvas.ButtCap
+	style.StrokeJoiner = canvas.ArcsJoiner{GapJoiner: canvas.BevelJoin, Limit: 4.0}
+	style.Dashes = nil
+	eps.RenderPath(canvas.MustParseSVG("L10 0"), style, canvas.Identity)
+	test.String(t, w.String(), " 0 -1 moveto 10 -1 lineto 10 1 lineto 0 1 lineto closepath fill")
+}
+
+func TestEPSClip(t *testing.T) {
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	w.Reset()
+
+	eps.PushClip(canvas.Rectangle(10.0, 10.0), canvas.NonZero, canvas.Identity)
+	eps.setColor(canvas.Red)
+	eps.PopClip()
+	eps.setColor(canvas.Red)
+	test.String(t, w.String(), " gsave 0 0 moveto 10 0 lineto 10 10 lineto 0 10 lineto closepath clip newpath 1 0 0 setrgbcolor grestore 1 0 0 setrgbcolor")
+}
+
+func TestEPSImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, canvas.Red)
+
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	eps.SetImageFilter(RunLengthFilter)
+	w.Reset()
+	eps.RenderImage(img, canvas.Identity)
+	test.String(t, w.String(), " gsave [2 0 0 2 0 0] concat 2 2 8 [2 0 0 -2 0 2] currentfile /ASCII85Decode filter /RunLengthDecode filter false 3 colorimage\n!<<'!q#>j~>\n grestore")
+
+	w.Reset()
+	eps.SetImageFilter(NoFilter)
+	eps.RenderImage(img, canvas.Identity)
+	test.String(t, w.String(), " gsave [2 0 0 2 0 0] concat 2 2 8 [2 0 0 -2 0 2] currentfile /ASCII85Decode filter false 3 colorimage\nrr<'!s8W-!s8W-!~>\n grestore")
+}
+
+func TestEPSText(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		test.Error(t, err)
+	}
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	text := canvas.NewTextLine(face, "AV", canvas.Left)
+
+	w := &bytes.Buffer{}
+	eps := New(w, 100, 80)
+	eps.RenderText(text, canvas.Identity)
+	s := w.String()
+	test.That(t, strings.Contains(s, "%%BeginResource: font dejavu-serif\n11 dict begin\n/FontName /dejavu-serif def\n/FontType 42 def\n"))
+	test.That(t, strings.Contains(s, "/g36 36 def"))
+	test.That(t, strings.Contains(s, "/dejavu-serif findfont [4.2333333 0 0 4.2333333 0 0] makefont setfont"))
+	test.That(t, strings.Contains(s, " /g36 glyphshow"))
+	test.That(t, strings.HasSuffix(s, " /g57 glyphshow"))
+
+	// the font is embedded only once
+	eps.RenderText(text, canvas.Identity)
+	test.T(t, strings.Count(w.String(), "%%BeginResource"), 1)
+}
+
+func TestRunLengthEncode(t *testing.T) {
+	test.Bytes(t, runLengthEncode([]byte{}), []byte{128})
+	test.Bytes(t, runLengthEncode([]byte{1, 2, 3}), []byte{2, 1, 2, 3, 128})
+	test.Bytes(t, runLengthEncode([]byte{1, 1, 1, 2, 3, 3}), []byte{254, 1, 0, 2, 255, 3, 128})
+	test.Bytes(t, runLengthEncode(bytes.Repeat([]byte{7}, 130)), []byte{129, 7, 255, 7, 128})
 }
@@ -0,0 +1,59 @@
+package eps
+
+import (
+	"encoding/ascii85"
+	"io"
+)
+
+// runLengthEncode encodes the data for the RunLengthDecode filter. A length byte n below 128 is followed by n+1 literal bytes, a length byte n above 128 is followed by a single byte that is repeated 257-n times, and 128 marks the end of the data.
+func runLengthEncode(b []byte) []byte {
+	out := make([]byte, 0, len(b)+len(b)/128+2)
+	for i := 0; i < len(b); {
+		// count repeated bytes
+		j := i + 1
+		for j < len(b) && j-i < 128 && b[j] == b[i] {
+			j++
+		}
+		if 1 < j-i {
+			out = append(out, byte(257-(j-i)), b[i])
+			i = j
+			continue
+		}
+
+		// collect literal bytes until a run of at least two bytes starts
+		j = i + 1
+		for j < len(b) && j-i < 128 && (j+1 == len(b) || b[j] != b[j+1]) {
+			j++
+		}
+		out = append(out, byte(j-i-1))
+		out = append(out, b[i:j]...)
+		i = j
+	}
+	return append(out, 128)
+}
+
+// writeASCII85 writes the data ASCII85 encoded, including the end-of-data marker. Lines are broken at 75 characters and never start with a percent sign, to avoid confusion with comments.
+func writeASCII85(w io.Writer, b []byte) {
+	buf := make([]byte, ascii85.MaxEncodedLen(len(b)))
+	buf = buf[:ascii85.Encode(buf, b)]
+
+	const lineLength = 75
+	line := make([]byte, 0, lineLength+2)
+	for 0 < len(buf) {
+		n := lineLength
+		if len(buf) < n {
+			n = len(buf)
+		}
+		line = line[:0]
+		if buf[0] == '%' {
+			line = append(line, ' ')
+		}
+		line = append(line, buf[:n]...)
+		buf = buf[n:]
+		if 0 < len(buf) {
+			line = append(line, '\n')
+		}
+		w.Write(line)
+	}
+	w.Write([]byte("~>"))
+}
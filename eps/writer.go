@@ -7,7 +7,7 @@ import (
 )
 
 // Writer writes the canvas as an EPS file.
-// Be aware that EPS does not support transparency of colors.
+// Be aware that EPS does not support transparency of colors, transparent colors are composited onto a white background instead.
 func Writer(w io.Writer, c *canvas.Canvas) error {
 	eps := New(w, c.W, c.H)
 	c.Render(eps)
@@ -138,10 +138,10 @@ func draw(c *canvas.Context) {
 	polyline.Add(0.0, 30.0)
 	polyline.Add(0.0, 0.0)
 	c.SetFillColor(canvas.Seagreen)
-	c.FillColor.R = byte(float64(c.FillColor.R) * 0.25)
-	c.FillColor.G = byte(float64(c.FillColor.G) * 0.25)
-	c.FillColor.B = byte(float64(c.FillColor.B) * 0.25)
-	c.FillColor.A = byte(float64(c.FillColor.A) * 0.25)
+	c.Style.Fill.Color.R = byte(float64(c.Style.Fill.Color.R) * 0.25)
+	c.Style.Fill.Color.G = byte(float64(c.Style.Fill.Color.G) * 0.25)
+	c.Style.Fill.Color.B = byte(float64(c.Style.Fill.Color.B) * 0.25)
+	c.Style.Fill.Color.A = byte(float64(c.Style.Fill.Color.A) * 0.25)
 	c.SetStrokeColor(canvas.Seagreen)
 	c.DrawPath(155, 35, polyline.Smoothen())
 
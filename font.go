@@ -20,15 +20,19 @@ const (
 	CommonLigatures
 	DiscretionaryLigatures
 	HistoricalLigatures
+	OldstyleFigures
+	Fractions
 )
 
 // Font defines a font of type TTF or OTF which which a FontFace can be generated for use in text drawing operations.
 type Font struct {
-	// TODO: extend to fully read in sfnt data and read liga tables, generate Raw font data (base on used glyphs), etc
 	name     string
 	mimetype string
 	raw      []byte
 	sfnt     *sfnt.Font
+	layout   *canvasFont.Layout // OpenType layout tables, nil if they could not be parsed
+	features []string           // OpenType features enabled by the typographic options
+	frac     bool
 
 	// TODO: use sub/superscript Unicode transformations in ToPath etc. if they exist
 	typography  bool
@@ -43,16 +47,22 @@ func parseFont(name string, b []byte) (*Font, error) {
 		return nil, err
 	}
 
-	sfntFont, err := canvasFont.ParseFont(b)
+	sfntBytes, err := canvasFont.ToSFNT(b)
 	if err != nil {
 		return nil, err
 	}
+	sfntFont, err := canvasFont.ParseSFNT(sfntBytes)
+	if err != nil {
+		return nil, err
+	}
+	layout, _ := canvasFont.ParseLayout(sfntBytes) // fonts with invalid layout tables are used without them
 
 	f := &Font{
 		name:     name,
 		mimetype: mimetype,
 		raw:      b,
 		sfnt:     (*sfnt.Font)(sfntFont),
+		layout:   layout,
 	}
 	f.superscript = f.supportedSubstitutions(superscriptSubstitutes)
 	f.subscript = f.supportedSubstitutions(subscriptSubstitutes)
@@ -89,6 +99,12 @@ func (f *Font) Kerning(left, right rune, ppem float64) (float64, error) {
 		return 0, err
 	}
 
+	if f.layout.HasPositioning("kern") {
+		glyphs := []canvasFont.Glyph{{ID: uint16(iLeft)}, {ID: uint16(iRight), Cluster: 1}}
+		f.layout.Position(glyphs, scriptTag(string([]rune{left, right})), []string{"kern"})
+		return fromI26_6(scaleUnits(glyphs[0].XAdvance, toI26_6(ppem), f.sfnt.UnitsPerEm())), nil
+	}
+
 	kern, err := f.sfnt.Kern(&sfntBuffer, iLeft, iRight, toI26_6(ppem), font.HintingNone)
 	if err != nil {
 		return 0, err
@@ -157,6 +173,21 @@ func (f *Font) Widths(ppem float64) []float64 {
 	return widths
 }
 
+// GlyphAdvance returns the advance of the glyph, without adjustments such as kerning.
+func (f *Font) GlyphAdvance(glyphID uint16, ppem float64) float64 {
+	advance, err := f.sfnt.GlyphAdvance(nil, sfnt.GlyphIndex(glyphID), toI26_6(ppem), font.HintingNone)
+	if err != nil {
+		return 0.0
+	}
+	return fromI26_6(advance)
+}
+
+// HasGlyph returns true if the font has a glyph for the rune in its character map.
+func (f *Font) HasGlyph(r rune) bool {
+	index, err := f.sfnt.GlyphIndex(&sfnt.Buffer{}, r)
+	return err == nil && index != 0
+}
+
 func (f *Font) IndicesOf(s string) []uint16 {
 	buffer := &sfnt.Buffer{}
 	runes := []rune(s)
@@ -170,12 +201,232 @@ func (f *Font) IndicesOf(s string) []uint16 {
 	return indices
 }
 
+// shape returns the glyphs of the string, transformed by the OpenType features of the font and the extra features. Right-to-left text has its mirrored characters replaced and its glyphs returned in visual order. Advances and offsets are in font units.
+func (f *Font) shape(s string, rtl bool, features ...string) []canvasFont.Glyph {
+	buffer := &sfnt.Buffer{}
+	glyphs := make([]canvasFont.Glyph, 0, len(s))
+	for i, r := range s {
+		if rtl {
+			if mirror, ok := bidiMirror(r); ok {
+				r = mirror
+			}
+		}
+		index, _ := f.sfnt.GlyphIndex(buffer, r)
+		glyphs = append(glyphs, canvasFont.Glyph{ID: uint16(index), Cluster: i})
+	}
+
+	script := scriptTag(s)
+	if script == "arab" {
+		// substitute the contextual forms of joining letters
+		for i, form := range arabicForms(s) {
+			if form != "" {
+				glyphs[i] = f.layout.Substitute(glyphs[i:i+1:i+1], script, []string{form})[0]
+			}
+		}
+	}
+	features = append(features, f.features...)
+	glyphs = f.layout.Substitute(glyphs, script, features)
+	if f.frac {
+		// fractions are only applied to numbers with a slash, as the feature would otherwise change all digits
+		for _, fraction := range findFractions(s) {
+			i := 0
+			for i < len(glyphs) && glyphs[i].Cluster < fraction[0] {
+				i++
+			}
+			j := i
+			for j < len(glyphs) && glyphs[j].Cluster < fraction[1] {
+				j++
+			}
+			sub := f.layout.Substitute(append([]canvasFont.Glyph{}, glyphs[i:j]...), script, []string{"frac"})
+			glyphs = append(glyphs[:i], append(sub, glyphs[j:]...)...)
+		}
+	}
+
+	ppem := toI26_6(f.UnitsPerEm())
+	for i, glyph := range glyphs {
+		glyphs[i].XAdvance = f.unitsAdvance(buffer, glyph.ID)
+	}
+	f.layout.Position(glyphs, script, features)
+	if !f.layout.HasPositioning("kern") {
+		// use the legacy kern table
+		for i := 1; i < len(glyphs); i++ {
+			kern, err := f.sfnt.Kern(buffer, sfnt.GlyphIndex(glyphs[i-1].ID), sfnt.GlyphIndex(glyphs[i].ID), ppem, font.HintingNone)
+			if err == nil {
+				glyphs[i-1].XAdvance += int32(kern.Round())
+			}
+		}
+	}
+
+	if rtl {
+		// marks are attached relative to the pen position after their base, in visual order the pen is before the base
+		advance := int32(0)
+		for i := range glyphs {
+			if glyphs[i].XAdvance == 0 && f.layout.IsMark(glyphs[i].ID) {
+				glyphs[i].XOffset += advance
+			} else {
+				advance = 0
+			}
+			advance += glyphs[i].XAdvance
+		}
+		for i, j := 0, len(glyphs)-1; i < j; i, j = i+1, j-1 {
+			glyphs[i], glyphs[j] = glyphs[j], glyphs[i]
+		}
+	}
+	return glyphs
+}
+
+// arabicForms returns the OpenType feature of the positional form (isol, init, medi or fina) for each character of an Arabic string, or an empty string for characters that do not join.
+func arabicForms(s string) []string {
+	runes := []rune(s)
+	joining := make([]byte, len(runes))
+	for i, r := range runes {
+		joining[i] = arabicJoining(r)
+	}
+
+	// joins returns whether the character at i joins with the next or previous non-transparent character
+	joins := func(i, step int) bool {
+		for i += step; 0 <= i && i < len(runes); i += step {
+			if joining[i] != 'T' {
+				if step < 0 {
+					return joining[i] == 'D' || joining[i] == 'C' || joining[i] == 'L'
+				}
+				return joining[i] == 'D' || joining[i] == 'C' || joining[i] == 'R'
+			}
+		}
+		return false
+	}
+
+	forms := make([]string, len(runes))
+	for i := range runes {
+		prev, next := false, false
+		switch joining[i] {
+		case 'D':
+			prev, next = joins(i, -1), joins(i, 1)
+		case 'R':
+			prev = joins(i, -1)
+		case 'L':
+			next = joins(i, 1)
+		default:
+			continue
+		}
+		if prev && next {
+			forms[i] = "medi"
+		} else if prev {
+			forms[i] = "fina"
+		} else if next {
+			forms[i] = "init"
+		} else {
+			forms[i] = "isol"
+		}
+	}
+	return forms
+}
+
+// arabicJoining returns the joining type of the character: dual-joining (D), right-joining (R), left-joining (L), join-causing (C), transparent (T) or non-joining (U).
+func arabicJoining(r rune) byte {
+	switch {
+	case r == '\u0640' || r == '\u200D':
+		return 'C'
+	case r == '\u0622' || r == '\u0623' || r == '\u0624' || r == '\u0625' || r == '\u0627' || r == '\u0629' || '\u062F' <= r && r <= '\u0632' || r == '\u0648' || '\u0671' <= r && r <= '\u0673' || '\u0675' <= r && r <= '\u0677' || '\u0688' <= r && r <= '\u0699' || r == '\u06C0' || '\u06C3' <= r && r <= '\u06CB' || r == '\u06CD' || r == '\u06CF' || r == '\u06D2' || r == '\u06D3' || r == '\u06D5' || r == '\u06EE' || r == '\u06EF':
+		return 'R'
+	case r == '\u0620' || r == '\u0626' || r == '\u0628' || '\u062A' <= r && r <= '\u062E' || '\u0633' <= r && r <= '\u063F' || '\u0641' <= r && r <= '\u0647' || r == '\u0649' || r == '\u064A' || r == '\u066E' || r == '\u066F' || '\u0678' <= r && r <= '\u0687' || '\u069A' <= r && r <= '\u06BF' || r == '\u06C1' || r == '\u06C2' || r == '\u06CC' || r == '\u06CE' || r == '\u06D0' || r == '\u06D1' || '\u06FA' <= r && r <= '\u06FC' || r == '\u06FF':
+		return 'D'
+	case unicode.In(r, unicode.Mn, unicode.Me) || unicode.Is(unicode.Cf, r) && r != '\u200C':
+		return 'T'
+	}
+	return 'U'
+}
+
+// unitsAdvance returns the advance of the glyph in font units.
+func (f *Font) unitsAdvance(buffer *sfnt.Buffer, glyphID uint16) int32 {
+	advance, err := f.sfnt.GlyphAdvance(buffer, sfnt.GlyphIndex(glyphID), toI26_6(f.UnitsPerEm()), font.HintingNone)
+	if err != nil {
+		return 0
+	}
+	return int32(advance.Round())
+}
+
+// variantFeatures returns the OpenType features that implement the font variant and that are supported by the font.
+func (f *Font) variantFeatures(variant FontVariant) []string {
+	features := []string{}
+	if variant&FontSmallcaps != 0 && f.layout.HasSubstitution("smcp") {
+		features = append(features, "smcp")
+	}
+	if variant&FontSuperscript != 0 && f.layout.HasSubstitution("sups") {
+		features = append(features, "sups")
+	}
+	if variant&FontSubscript != 0 && f.layout.HasSubstitution("subs") {
+		features = append(features, "subs")
+	}
+	return features
+}
+
+// synthesizesPosition returns true if subscripts or superscripts of the font variant are synthesized by scaling and shifting the regular glyphs, as the font has no glyphs for them.
+func (f *Font) synthesizesPosition(variant FontVariant) bool {
+	if variant&FontSuperscript != 0 {
+		return !f.layout.HasSubstitution("sups")
+	} else if variant&FontSubscript != 0 {
+		return !f.layout.HasSubstitution("subs")
+	}
+	return false
+}
+
+// findFractions returns the byte ranges of numbers with a slash, such as 1/2.
+func findFractions(s string) [][2]int {
+	fractions := [][2]int{}
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	for i := 0; i < len(s); i++ {
+		if s[i] != '/' || i == 0 || !isDigit(s[i-1]) || i+1 == len(s) || !isDigit(s[i+1]) {
+			continue
+		}
+		start, end := i-1, i+1
+		for 0 < start && isDigit(s[start-1]) {
+			start--
+		}
+		for end < len(s) && isDigit(s[end]) {
+			end++
+		}
+		fractions = append(fractions, [2]int{start, end})
+		i = end
+	}
+	return fractions
+}
+
+// scriptTag returns the OpenType script tag of the first character in the string that belongs to a script, or DFLT.
+func scriptTag(s string) string {
+	for _, r := range s {
+		for _, script := range openTypeScripts {
+			if unicode.Is(script.table, r) {
+				return script.tag
+			}
+		}
+	}
+	return "DFLT"
+}
+
+var openTypeScripts = []struct {
+	tag   string
+	table *unicode.RangeTable
+}{
+	{"latn", unicode.Latin},
+	{"grek", unicode.Greek},
+	{"cyrl", unicode.Cyrillic},
+	{"arab", unicode.Arabic},
+	{"hebr", unicode.Hebrew},
+	{"deva", unicode.Devanagari},
+	{"thai", unicode.Thai},
+	{"hani", unicode.Han},
+	{"kana", unicode.Hiragana},
+	{"kana", unicode.Katakana},
+	{"hang", unicode.Hangul},
+}
+
 type textSubstitution struct {
 	src string
 	dst rune
 }
 
-// TODO: read from liga tables in OpenType (clig, dlig, hlig) with rlig default enabled
+// commonLigatures are the common ligatures that have a Unicode code point, see substituteLigatures.
 var commonLigatures = []textSubstitution{
 	{"ffi", '\uFB03'},
 	{"ffl", '\uFB04'},
@@ -288,12 +539,30 @@ func (f *Font) supportedSubstitutions(substitutions []textSubstitution) []textSu
 	return supported
 }
 
-// Use enables typographic options on the font such as ligatures.
+// Use enables typographic options on the font such as ligatures. Ligatures, old-style figures and fractions use the OpenType features of the font.
 func (f *Font) Use(options TypographicOptions) {
 	if options&NoTypography == 0 {
 		f.typography = true
 	}
 
+	f.features = []string{"ccmp", "locl", "kern", "mark", "mkmk"}
+	if options&NoRequiredLigatures == 0 {
+		f.features = append(f.features, "rlig")
+	}
+	if options&CommonLigatures != 0 {
+		f.features = append(f.features, "liga", "clig")
+	}
+	if options&DiscretionaryLigatures != 0 {
+		f.features = append(f.features, "dlig")
+	}
+	if options&HistoricalLigatures != 0 {
+		f.features = append(f.features, "hlig")
+	}
+	if options&OldstyleFigures != 0 {
+		f.features = append(f.features, "onum")
+	}
+	f.frac = options&Fractions != 0
+
 	f.ligatures = []textSubstitution{}
 	if options&CommonLigatures != 0 {
 		f.ligatures = append(f.ligatures, f.supportedSubstitutions(commonLigatures)...)
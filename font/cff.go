@@ -0,0 +1,697 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Specification:
+// https://adobe-type-tools.github.io/font-tech-notes/pdfs/5176.CFF.pdf
+
+// CFF DICT operators, two-byte operators are 1200 plus the second byte.
+const (
+	cffCharset      = 15
+	cffEncoding     = 16
+	cffCharStrings  = 17
+	cffPrivate      = 18
+	cffSubrs        = 19
+	cffROS          = 1230
+	cffFDArray      = 1236
+	cffFDSelect     = 1237
+	cffCharsetISO   = 0 // predefined ISOAdobe charset
+	cffMaxDictValue = 1<<31 - 1
+)
+
+type cffDictEntry struct {
+	op       int
+	operands [][]byte // encoded operands
+}
+
+type cffDict []cffDictEntry
+
+type cffPrivateData struct {
+	dict     []byte
+	subrs    [][]byte // local subroutines
+	hasSubrs bool
+}
+
+// parseCFFDict parses a DICT, keeping the encoded operands.
+func parseCFFDict(b []byte) (cffDict, error) {
+	dict := cffDict{}
+	operands := [][]byte{}
+	for i := 0; i < len(b); {
+		b0 := b[i]
+		n := 0
+		switch {
+		case b0 <= 21:
+			op := int(b0)
+			i++
+			if b0 == 12 {
+				if len(b) <= i {
+					return nil, ErrInvalidFontData
+				}
+				op = 1200 + int(b[i])
+				i++
+			}
+			dict = append(dict, cffDictEntry{op, operands})
+			operands = [][]byte{}
+			continue
+		case b0 == 28:
+			n = 3
+		case b0 == 29:
+			n = 5
+		case b0 == 30:
+			// real number, ends with a nibble of 0xf
+			n = 1
+			for i+n < len(b) && b[i+n]&0x0f != 0x0f && b[i+n]&0xf0 != 0xf0 {
+				n++
+			}
+			n++
+		case 32 <= b0 && b0 <= 246:
+			n = 1
+		case 247 <= b0 && b0 <= 254:
+			n = 2
+		default:
+			return nil, ErrInvalidFontData
+		}
+		if len(b) < i+n {
+			return nil, ErrInvalidFontData
+		}
+		operands = append(operands, b[i:i+n])
+		i += n
+	}
+	return dict, nil
+}
+
+// Get returns the integer operands of the operator.
+func (dict cffDict) Get(op int) ([]int, bool) {
+	for _, entry := range dict {
+		if entry.op == op {
+			values := make([]int, 0, len(entry.operands))
+			for _, operand := range entry.operands {
+				value, ok := cffOperandInt(operand)
+				if !ok {
+					return nil, false
+				}
+				values = append(values, value)
+			}
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the operands of the operator to the given integers, which are encoded in five bytes so that the size of the DICT does not depend on their values.
+func (dict cffDict) Set(op int, values ...int) cffDict {
+	operands := [][]byte{}
+	for _, value := range values {
+		operand := make([]byte, 5)
+		operand[0] = 29
+		binary.BigEndian.PutUint32(operand[1:], uint32(int32(value)))
+		operands = append(operands, operand)
+	}
+	for i, entry := range dict {
+		if entry.op == op {
+			dict[i].operands = operands
+			return dict
+		}
+	}
+	return append(dict, cffDictEntry{op, operands})
+}
+
+// Remove removes the operator from the DICT.
+func (dict cffDict) Remove(op int) cffDict {
+	for i, entry := range dict {
+		if entry.op == op {
+			return append(dict[:i:i], dict[i+1:]...)
+		}
+	}
+	return dict
+}
+
+// Bytes returns the encoded DICT. The ROS operator must be first.
+func (dict cffDict) Bytes() []byte {
+	w := newBinaryWriter([]byte{})
+	for _, entry := range dict {
+		if entry.op == cffROS {
+			dict.writeEntry(w, entry)
+		}
+	}
+	for _, entry := range dict {
+		if entry.op != cffROS {
+			dict.writeEntry(w, entry)
+		}
+	}
+	return w.Bytes()
+}
+
+func (dict cffDict) writeEntry(w *binaryWriter, entry cffDictEntry) {
+	for _, operand := range entry.operands {
+		w.WriteBytes(operand)
+	}
+	if 1200 <= entry.op {
+		w.WriteByte(12)
+		w.WriteByte(byte(entry.op - 1200))
+	} else {
+		w.WriteByte(byte(entry.op))
+	}
+}
+
+func cffOperandInt(b []byte) (int, bool) {
+	switch b0 := b[0]; {
+	case b0 == 28:
+		return int(int16(binary.BigEndian.Uint16(b[1:]))), true
+	case b0 == 29:
+		return int(int32(binary.BigEndian.Uint32(b[1:]))), true
+	case 32 <= b0 && b0 <= 246:
+		return int(b0) - 139, true
+	case 247 <= b0 && b0 <= 250:
+		return (int(b0)-247)*256 + int(b[1]) + 108, true
+	case 251 <= b0 && b0 <= 254:
+		return -(int(b0)-251)*256 - int(b[1]) - 108, true
+	}
+	return 0, false
+}
+
+// readCFFIndex reads an INDEX and returns its items and the encoded INDEX.
+func readCFFIndex(r *binaryReader) ([][]byte, []byte, error) {
+	start := r.Pos()
+	count := uint32(r.ReadUint16())
+	if r.EOF() {
+		return nil, nil, ErrInvalidFontData
+	} else if count == 0 {
+		return [][]byte{}, r.buf[start:r.Pos()], nil
+	}
+
+	offSize := r.ReadByte()
+	if offSize < 1 || 4 < offSize || r.Len()/uint32(offSize) < count+1 {
+		return nil, nil, ErrInvalidFontData
+	}
+	offsets := make([]uint32, count+1)
+	for i := range offsets {
+		for _, b := range r.ReadBytes(uint32(offSize)) {
+			offsets[i] = offsets[i]<<8 | uint32(b)
+		}
+	}
+	base := r.Pos() - 1
+	items := make([][]byte, count)
+	for i := range items {
+		if offsets[i] < 1 || offsets[i+1] < offsets[i] || uint32(len(r.buf))-base < offsets[i+1] {
+			return nil, nil, ErrInvalidFontData
+		}
+		items[i] = r.buf[base+offsets[i] : base+offsets[i+1]]
+	}
+	r.Seek(base + offsets[count])
+	return items, r.buf[start:r.Pos()], nil
+}
+
+func writeCFFIndex(items [][]byte) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(uint16(len(items)))
+	if len(items) == 0 {
+		return w.Bytes()
+	}
+
+	size := uint32(1)
+	for _, item := range items {
+		size += uint32(len(item))
+	}
+	offSize := 1
+	for 1<<(8*offSize) <= size {
+		offSize++
+	}
+	w.WriteByte(byte(offSize))
+	offset := uint32(1)
+	writeOffset := func(offset uint32) {
+		for i := offSize - 1; 0 <= i; i-- {
+			w.WriteByte(byte(offset >> (8 * i)))
+		}
+	}
+	writeOffset(offset)
+	for _, item := range items {
+		offset += uint32(len(item))
+		writeOffset(offset)
+	}
+	for _, item := range items {
+		w.WriteBytes(item)
+	}
+	return w.Bytes()
+}
+
+// subsetCFF rebuilds the CFF table for the subset glyphs. The CharStrings are rebuilt and subroutines that are not used by the subset glyphs are emptied. CID-keyed fonts get a charset that maps glyph i to CID i.
+func (s *subsetter) subsetCFF(b []byte, numGlyphs uint16) ([]byte, error) {
+	r := newBinaryReader(b)
+	_ = r.ReadUint16() // major and minor version
+	hdrSize := r.ReadByte()
+	r.Seek(uint32(hdrSize))
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+
+	_, nameIndex, err := readCFFIndex(r)
+	if err != nil {
+		return nil, err
+	}
+	topDicts, _, err := readCFFIndex(r)
+	if err != nil {
+		return nil, err
+	} else if len(topDicts) != 1 {
+		return nil, fmt.Errorf("font sets are unsupported")
+	}
+	_, stringIndex, err := readCFFIndex(r)
+	if err != nil {
+		return nil, err
+	}
+	globalSubrs, _, err := readCFFIndex(r)
+	if err != nil {
+		return nil, err
+	}
+
+	topDict, err := parseCFFDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+	offset, ok := topDict.Get(cffCharStrings)
+	if !ok || len(offset) != 1 || offset[0] < 0 {
+		return nil, ErrInvalidFontData
+	}
+	r.Seek(uint32(offset[0]))
+	charStrings, _, err := readCFFIndex(r)
+	if err != nil {
+		return nil, err
+	} else if len(charStrings) != int(numGlyphs) {
+		return nil, fmt.Errorf("number of glyphs must match maxp table")
+	}
+
+	// private DICTs of CID-keyed fonts are in the FDArray
+	_, cid := topDict.Get(cffROS)
+	var privates []cffPrivateData
+	var fontDicts []cffDict
+	var fdSelect []byte
+	if cid {
+		offset, ok := topDict.Get(cffFDArray)
+		if !ok || len(offset) != 1 || offset[0] < 0 {
+			return nil, ErrInvalidFontData
+		}
+		r.Seek(uint32(offset[0]))
+		items, _, err := readCFFIndex(r)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			fontDict, err := parseCFFDict(item)
+			if err != nil {
+				return nil, err
+			}
+			private, err := readCFFPrivate(b, fontDict)
+			if err != nil {
+				return nil, err
+			}
+			fontDicts = append(fontDicts, fontDict)
+			privates = append(privates, private)
+		}
+
+		if offset, ok = topDict.Get(cffFDSelect); !ok || len(offset) != 1 || offset[0] < 0 {
+			return nil, ErrInvalidFontData
+		}
+		if fdSelect, err = readCFFFDSelect(b, uint32(offset[0]), numGlyphs); err != nil {
+			return nil, err
+		}
+	} else {
+		private, err := readCFFPrivate(b, topDict)
+		if err != nil {
+			return nil, err
+		}
+		privates = append(privates, private)
+	}
+
+	// build charset, FDSelect and CharStrings
+	wCharset := newBinaryWriter([]byte{})
+	var wFDSelect *binaryWriter
+	if cid {
+		// map glyph i to CID i
+		wCharset.WriteByte(2) // format
+		if 1 < len(s.glyphs) {
+			wCharset.WriteUint16(1)
+			wCharset.WriteUint16(uint16(len(s.glyphs) - 2))
+		}
+
+		wFDSelect = newBinaryWriter([]byte{})
+		wFDSelect.WriteByte(0) // format
+		for _, glyphID := range s.glyphs {
+			if len(fontDicts) <= int(fdSelect[glyphID]) {
+				return nil, ErrInvalidFontData
+			}
+			wFDSelect.WriteByte(fdSelect[glyphID])
+		}
+	} else {
+		sids, err := readCFFCharset(b, topDict, numGlyphs)
+		if err != nil {
+			return nil, err
+		}
+		wCharset.WriteByte(0) // format
+		for _, glyphID := range s.glyphs[1:] {
+			wCharset.WriteUint16(sids[glyphID])
+		}
+	}
+	subsetCharStrings := make([][]byte, len(s.glyphs))
+	for i, glyphID := range s.glyphs {
+		if s.empty[glyphID] {
+			subsetCharStrings[i] = []byte{14} // endchar
+		} else {
+			subsetCharStrings[i] = charStrings[glyphID]
+		}
+	}
+	charStringsIndex := writeCFFIndex(subsetCharStrings)
+
+	// replace unused subroutines by an empty subroutine, keeping the subroutine numbers intact
+	usedGlobalSubrs := make([]bool, len(globalSubrs))
+	usedLocalSubrs := make([][]bool, len(privates))
+	for i := range privates {
+		usedLocalSubrs[i] = make([]bool, len(privates[i].subrs))
+	}
+	pruneSubrs := true
+	for i, charString := range subsetCharStrings {
+		fd := 0
+		if cid {
+			fd = int(fdSelect[s.glyphs[i]])
+		}
+		scanner := cffCharStringScanner{
+			globalSubrs:     globalSubrs,
+			localSubrs:      privates[fd].subrs,
+			usedGlobalSubrs: usedGlobalSubrs,
+			usedLocalSubrs:  usedLocalSubrs[fd],
+		}
+		if !scanner.scan(charString, 0) {
+			pruneSubrs = false
+			break
+		}
+	}
+	globalSubrsIndex := writeCFFIndex(pruneCFFSubrs(globalSubrs, usedGlobalSubrs, pruneSubrs))
+	localSubrsIndices := make([][]byte, len(privates))
+	for i, private := range privates {
+		if private.hasSubrs {
+			localSubrsIndices[i] = writeCFFIndex(pruneCFFSubrs(private.subrs, usedLocalSubrs[i], pruneSubrs))
+		}
+	}
+
+	// layout: header, Name INDEX, Top DICT INDEX, String INDEX, Global Subr INDEX, charset, FDSelect, CharStrings INDEX, FDArray INDEX, private DICTs and local subroutines
+	topDict = topDict.Remove(cffEncoding)
+	topDict = topDict.Set(cffCharset, 0)
+	topDict = topDict.Set(cffCharStrings, 0)
+	if cid {
+		topDict = topDict.Set(cffFDSelect, 0)
+		topDict = topDict.Set(cffFDArray, 0)
+	} else {
+		topDict = topDict.Set(cffPrivate, 0, 0)
+	}
+	topDictIndexLength := len(writeCFFIndex([][]byte{topDict.Bytes()}))
+
+	pos := 4 + len(nameIndex) + topDictIndexLength + len(stringIndex) + len(globalSubrsIndex)
+	charsetOffset := pos
+	pos += int(wCharset.Len())
+	fdSelectOffset := pos
+	if cid {
+		pos += int(wFDSelect.Len())
+	}
+	charStringsOffset := pos
+	pos += len(charStringsIndex)
+	fdArrayOffset := pos
+	var fdArrayIndex []byte
+	if cid {
+		// the Private DICT offsets in the Font DICTs are five-byte integers, so the size of the FDArray does not depend on them
+		items := [][]byte{}
+		for _, fontDict := range fontDicts {
+			items = append(items, fontDict.Set(cffPrivate, 0, 0).Bytes())
+		}
+		pos += len(writeCFFIndex(items))
+
+		items = items[:0]
+		for i, fontDict := range fontDicts {
+			items = append(items, fontDict.Set(cffPrivate, len(privates[i].dict), pos).Bytes())
+			pos += len(privates[i].dict) + len(localSubrsIndices[i])
+		}
+		fdArrayIndex = writeCFFIndex(items)
+	} else {
+		topDict = topDict.Set(cffPrivate, len(privates[0].dict), pos)
+		pos += len(privates[0].dict) + len(localSubrsIndices[0])
+	}
+	if cffMaxDictValue < pos {
+		return nil, ErrExceedsMemory
+	}
+
+	topDict = topDict.Set(cffCharset, charsetOffset)
+	topDict = topDict.Set(cffCharStrings, charStringsOffset)
+	if cid {
+		topDict = topDict.Set(cffFDSelect, fdSelectOffset)
+		topDict = topDict.Set(cffFDArray, fdArrayOffset)
+	}
+
+	w := newBinaryWriter([]byte{})
+	w.WriteBytes([]byte{1, 0, 4, 4}) // major, minor, hdrSize, offSize
+	w.WriteBytes(nameIndex)
+	w.WriteBytes(writeCFFIndex([][]byte{topDict.Bytes()}))
+	w.WriteBytes(stringIndex)
+	w.WriteBytes(globalSubrsIndex)
+	w.WriteBytes(wCharset.Bytes())
+	if cid {
+		w.WriteBytes(wFDSelect.Bytes())
+	}
+	w.WriteBytes(charStringsIndex)
+	w.WriteBytes(fdArrayIndex)
+	for i, private := range privates {
+		w.WriteBytes(private.dict)
+		w.WriteBytes(localSubrsIndices[i])
+	}
+	return w.Bytes(), nil
+}
+
+// readCFFPrivate returns the Private DICT referenced by the Top DICT or Font DICT and its local subroutines. The Subrs operator is set to point directly after the Private DICT.
+func readCFFPrivate(b []byte, dict cffDict) (cffPrivateData, error) {
+	private, ok := dict.Get(cffPrivate)
+	if !ok {
+		return cffPrivateData{}, nil
+	} else if len(private) != 2 || private[0] < 0 || private[1] < 0 || len(b) < private[1]+private[0] {
+		return cffPrivateData{}, ErrInvalidFontData
+	}
+	privateDict, err := parseCFFDict(b[private[1] : private[1]+private[0]])
+	if err != nil {
+		return cffPrivateData{}, err
+	}
+
+	var subrsItems [][]byte
+	hasSubrs := false
+	if subrs, ok := privateDict.Get(cffSubrs); ok {
+		if len(subrs) != 1 || subrs[0] < 0 {
+			return cffPrivateData{}, ErrInvalidFontData
+		}
+		r := newBinaryReader(b)
+		r.Seek(uint32(private[1] + subrs[0]))
+		if subrsItems, _, err = readCFFIndex(r); err != nil {
+			return cffPrivateData{}, err
+		}
+		privateDict = privateDict.Set(cffSubrs, 0)
+		privateDict = privateDict.Set(cffSubrs, len(privateDict.Bytes()))
+		hasSubrs = true
+	}
+	return cffPrivateData{privateDict.Bytes(), subrsItems, hasSubrs}, nil
+}
+
+// readCFFCharset returns the string IDs of the glyph names of a font that is not CID-keyed.
+func readCFFCharset(b []byte, topDict cffDict, numGlyphs uint16) ([]uint16, error) {
+	sids := make([]uint16, numGlyphs)
+	offset, ok := topDict.Get(cffCharset)
+	if !ok || len(offset) == 1 && offset[0] == cffCharsetISO {
+		for i := range sids {
+			sids[i] = uint16(i)
+		}
+		return sids, nil
+	} else if len(offset) != 1 || offset[0] < 3 {
+		return nil, fmt.Errorf("unsupported charset")
+	}
+
+	r := newBinaryReader(b)
+	r.Seek(uint32(offset[0]))
+	format := r.ReadByte()
+	for i := 1; i < int(numGlyphs); {
+		if format == 0 {
+			sids[i] = r.ReadUint16()
+			i++
+		} else if format == 1 || format == 2 {
+			first := r.ReadUint16()
+			nLeft := uint16(r.ReadByte())
+			if format == 2 {
+				nLeft = nLeft<<8 | uint16(r.ReadByte())
+			}
+			for j := 0; j <= int(nLeft) && i < int(numGlyphs); j++ {
+				sids[i] = first + uint16(j)
+				i++
+			}
+		} else {
+			return nil, fmt.Errorf("unsupported charset format")
+		}
+		if r.EOF() {
+			return nil, ErrInvalidFontData
+		}
+	}
+	return sids, nil
+}
+
+// readCFFFDSelect returns the Font DICT index for each glyph.
+func readCFFFDSelect(b []byte, offset uint32, numGlyphs uint16) ([]byte, error) {
+	fds := make([]byte, numGlyphs)
+	r := newBinaryReader(b)
+	r.Seek(offset)
+	switch format := r.ReadByte(); format {
+	case 0:
+		copy(fds, r.ReadBytes(uint32(numGlyphs)))
+	case 3:
+		nRanges := r.ReadUint16()
+		first := r.ReadUint16()
+		for i := 0; i < int(nRanges); i++ {
+			fd := r.ReadByte()
+			next := r.ReadUint16()
+			if next < first || numGlyphs < next {
+				return nil, ErrInvalidFontData
+			}
+			for j := first; j < next; j++ {
+				fds[j] = fd
+			}
+			first = next
+		}
+	default:
+		return nil, fmt.Errorf("unsupported FDSelect format")
+	}
+	if r.EOF() {
+		return nil, ErrInvalidFontData
+	}
+	return fds, nil
+}
+
+// pruneCFFSubrs replaces the unused subroutines by a subroutine that only returns.
+func pruneCFFSubrs(subrs [][]byte, used []bool, prune bool) [][]byte {
+	if !prune {
+		return subrs
+	}
+	pruned := make([][]byte, len(subrs))
+	for i, subr := range subrs {
+		if used[i] {
+			pruned[i] = subr
+		} else {
+			pruned[i] = []byte{11} // return
+		}
+	}
+	return pruned
+}
+
+// cffSubrBias returns the bias that is added to subroutine numbers in Type 2 charstrings.
+func cffSubrBias(n int) int {
+	if n < 1240 {
+		return 107
+	} else if n < 33900 {
+		return 1131
+	}
+	return 32768
+}
+
+// cffCharStringScanner finds the subroutines used by Type 2 charstrings, see Adobe Technical Note #5177.
+type cffCharStringScanner struct {
+	globalSubrs, localSubrs         [][]byte
+	usedGlobalSubrs, usedLocalSubrs []bool
+
+	stack  []int
+	nStems int
+	ended  bool
+}
+
+// scan scans a charstring or subroutine and marks the called subroutines as used. It returns false if the subroutine numbers cannot be determined, in which case all subroutines must be kept.
+func (s *cffCharStringScanner) scan(b []byte, depth int) bool {
+	if 10 < depth {
+		return false // maximum subroutine nesting depth
+	}
+	for i := 0; i < len(b) && !s.ended; {
+		b0 := b[i]
+		switch {
+		case b0 == 28:
+			if len(b) < i+3 {
+				return false
+			}
+			s.stack = append(s.stack, int(int16(binary.BigEndian.Uint16(b[i+1:]))))
+			i += 3
+			continue
+		case 32 <= b0 && b0 <= 246:
+			s.stack = append(s.stack, int(b0)-139)
+			i++
+			continue
+		case 247 <= b0 && b0 <= 250:
+			if len(b) < i+2 {
+				return false
+			}
+			s.stack = append(s.stack, (int(b0)-247)*256+int(b[i+1])+108)
+			i += 2
+			continue
+		case 251 <= b0 && b0 <= 254:
+			if len(b) < i+2 {
+				return false
+			}
+			s.stack = append(s.stack, -(int(b0)-251)*256-int(b[i+1])-108)
+			i += 2
+			continue
+		case b0 == 255:
+			if len(b) < i+5 {
+				return false
+			}
+			s.stack = append(s.stack, int(int32(binary.BigEndian.Uint32(b[i+1:])))>>16)
+			i += 5
+			continue
+		}
+
+		// operators
+		i++
+		switch b0 {
+		case 1, 3, 18, 23: // hstem, vstem, hstemhm, vstemhm
+			s.nStems += len(s.stack) / 2
+			s.stack = s.stack[:0]
+		case 19, 20: // hintmask, cntrmask
+			s.nStems += len(s.stack) / 2 // implicit vstem
+			s.stack = s.stack[:0]
+			i += (s.nStems + 7) / 8
+		case 10, 29: // callsubr, callgsubr
+			if len(s.stack) == 0 {
+				return false
+			}
+			subrs, used := s.localSubrs, s.usedLocalSubrs
+			if b0 == 29 {
+				subrs, used = s.globalSubrs, s.usedGlobalSubrs
+			}
+			n := s.stack[len(s.stack)-1] + cffSubrBias(len(subrs))
+			s.stack = s.stack[:len(s.stack)-1]
+			if n < 0 || len(subrs) <= n {
+				return false
+			}
+			used[n] = true
+			if !s.scan(subrs[n], depth+1) {
+				return false
+			}
+		case 11: // return
+			return true
+		case 14: // endchar
+			s.ended = true
+		case 12: // escape, arithmetic operators would make the stack unpredictable
+			if len(b) <= i {
+				return false
+			}
+			if b[i] < 34 || 37 < b[i] { // only flex operators are allowed
+				return false
+			}
+			i++
+			s.stack = s.stack[:0]
+		default:
+			if 31 < b0 || b0 == 0 || b0 == 2 || b0 == 9 || b0 == 13 || 15 <= b0 && b0 <= 17 {
+				return false // reserved
+			}
+			s.stack = s.stack[:0]
+		}
+	}
+	return true
+}
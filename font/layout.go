@@ -0,0 +1,786 @@
+package font
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Specification:
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gdef
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gsub
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gpos
+
+// Glyph is a glyph of a glyph run that is transformed by the GSUB and GPOS tables. Advances and offsets are in font units.
+type Glyph struct {
+	ID       uint16
+	Cluster  int // index of the first character in the text that maps to the glyph
+	XAdvance int32
+	XOffset  int32
+	YOffset  int32
+}
+
+// lookup flags
+const (
+	lookupIgnoreBaseGlyphs    = 0x0002
+	lookupIgnoreLigatures     = 0x0004
+	lookupIgnoreMarks         = 0x0008
+	lookupUseMarkFilteringSet = 0x0010
+	lookupMarkAttachmentType  = 0xFF00
+)
+
+// glyph classes of the GDEF table
+const (
+	glyphClassBase     = 1
+	glyphClassLigature = 2
+	glyphClassMark     = 3
+)
+
+// maxLayoutDepth is the maximum nesting depth of contextual lookups.
+const maxLayoutDepth = 8
+
+// layoutData is a table or subtable of the OpenType layout tables, reading out of bounds returns zero.
+type layoutData []byte
+
+func (b layoutData) u16(i int) uint16 {
+	if i < 0 || len(b) < i+2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(b[i:])
+}
+
+func (b layoutData) i16(i int) int16 {
+	return int16(b.u16(i))
+}
+
+func (b layoutData) u32(i int) uint32 {
+	if i < 0 || len(b) < i+4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b[i:])
+}
+
+func (b layoutData) tag(i int) string {
+	if i < 0 || len(b) < i+4 {
+		return ""
+	}
+	return string(b[i : i+4])
+}
+
+// sub returns the subtable at the given offset, a zero offset is a NULL offset.
+func (b layoutData) sub(offset int) layoutData {
+	if offset <= 0 || len(b) <= offset {
+		return nil
+	}
+	return b[offset:]
+}
+
+// coverage returns the coverage index of the glyph for a Coverage table, or -1 if the glyph is not covered.
+func (b layoutData) coverage(glyphID uint16) int {
+	switch b.u16(0) {
+	case 1:
+		n := int(b.u16(2))
+		i := sort.Search(n, func(i int) bool { return glyphID <= b.u16(4+2*i) })
+		if i < n && b.u16(4+2*i) == glyphID {
+			return i
+		}
+	case 2:
+		n := int(b.u16(2))
+		i := sort.Search(n, func(i int) bool { return glyphID <= b.u16(4+6*i+2) })
+		if i < n && b.u16(4+6*i) <= glyphID {
+			return int(b.u16(4+6*i+4)) + int(glyphID-b.u16(4+6*i))
+		}
+	}
+	return -1
+}
+
+// class returns the class of the glyph for a Class Definition table.
+func (b layoutData) class(glyphID uint16) uint16 {
+	switch b.u16(0) {
+	case 1:
+		start := b.u16(2)
+		if start <= glyphID && int(glyphID-start) < int(b.u16(4)) {
+			return b.u16(6 + 2*int(glyphID-start))
+		}
+	case 2:
+		n := int(b.u16(2))
+		i := sort.Search(n, func(i int) bool { return glyphID <= b.u16(4+6*i+2) })
+		if i < n && b.u16(4+6*i) <= glyphID {
+			return b.u16(4 + 6*i + 4)
+		}
+	}
+	return 0
+}
+
+type layoutLangSys struct {
+	required uint16 // feature index, 0xFFFF if there is none
+	features []uint16
+}
+
+type layoutFeature struct {
+	tag     string
+	lookups []uint16
+}
+
+type layoutLookup struct {
+	kind             uint16
+	flag             uint16
+	markFilteringSet uint16
+	subtables        []layoutData
+}
+
+type layoutTable struct {
+	scripts  map[string]layoutLangSys
+	features []layoutFeature
+	lookups  []layoutLookup
+}
+
+// parseLayoutTable parses the GSUB or GPOS table, extensionKind is the lookup type of extension subtables.
+func parseLayoutTable(b []byte, extensionKind uint16) (*layoutTable, error) {
+	d := layoutData(b)
+	if len(d) < 10 || d.u16(0) != 1 {
+		return nil, ErrInvalidFontData
+	}
+	scriptList, featureList, lookupList := d.sub(int(d.u16(4))), d.sub(int(d.u16(6))), d.sub(int(d.u16(8)))
+
+	t := &layoutTable{
+		scripts: map[string]layoutLangSys{},
+	}
+	for i := 0; i < int(scriptList.u16(0)); i++ {
+		script := scriptList.sub(int(scriptList.u16(2 + 6*i + 4)))
+		langSys := script.sub(int(script.u16(0))) // default language system
+		if langSys == nil && 0 < script.u16(2) {
+			langSys = script.sub(int(script.u16(4 + 4)))
+		}
+		if langSys == nil {
+			continue
+		}
+		features := make([]uint16, langSys.u16(4))
+		for j := range features {
+			features[j] = langSys.u16(6 + 2*j)
+		}
+		t.scripts[scriptList.tag(2+6*i)] = layoutLangSys{langSys.u16(2), features}
+	}
+
+	t.features = make([]layoutFeature, featureList.u16(0))
+	for i := range t.features {
+		feature := featureList.sub(int(featureList.u16(2 + 6*i + 4)))
+		lookups := make([]uint16, feature.u16(2))
+		for j := range lookups {
+			lookups[j] = feature.u16(4 + 2*j)
+		}
+		t.features[i] = layoutFeature{featureList.tag(2 + 6*i), lookups}
+	}
+
+	t.lookups = make([]layoutLookup, lookupList.u16(0))
+	for i := range t.lookups {
+		lookup := lookupList.sub(int(lookupList.u16(2 + 2*i)))
+		kind, flag, n := lookup.u16(0), lookup.u16(2), int(lookup.u16(4))
+		extension := kind == extensionKind
+		subtables := make([]layoutData, 0, n)
+		for j := 0; j < n; j++ {
+			subtable := lookup.sub(int(lookup.u16(6 + 2*j)))
+			if extension {
+				if subtable.u16(0) != 1 {
+					return nil, ErrInvalidFontData
+				}
+				kind = subtable.u16(2)
+				subtable = subtable.sub(int(subtable.u32(4)))
+			}
+			subtables = append(subtables, subtable)
+		}
+		t.lookups[i] = layoutLookup{
+			kind:             kind,
+			flag:             flag,
+			markFilteringSet: lookup.u16(6 + 2*n),
+			subtables:        subtables,
+		}
+	}
+	return t, nil
+}
+
+// lookupIndices returns the lookups for the features of the script in the order they must be applied. The DFLT and latn scripts are used if the font does not support the script.
+func (t *layoutTable) lookupIndices(script string, features []string) []uint16 {
+	langSys, ok := t.scripts[script]
+	if !ok {
+		if langSys, ok = t.scripts["DFLT"]; !ok {
+			if langSys, ok = t.scripts["latn"]; !ok {
+				return nil
+			}
+		}
+	}
+
+	used := map[uint16]bool{}
+	add := func(index uint16) {
+		if int(index) < len(t.features) {
+			for _, lookup := range t.features[index].lookups {
+				if int(lookup) < len(t.lookups) {
+					used[lookup] = true
+				}
+			}
+		}
+	}
+	if langSys.required != 0xFFFF {
+		add(langSys.required)
+	}
+	for _, index := range langSys.features {
+		if int(index) < len(t.features) {
+			for _, feature := range features {
+				if t.features[index].tag == feature {
+					add(index)
+					break
+				}
+			}
+		}
+	}
+
+	lookups := make([]uint16, 0, len(used))
+	for lookup := range used {
+		lookups = append(lookups, lookup)
+	}
+	sort.Slice(lookups, func(i, j int) bool { return lookups[i] < lookups[j] })
+	return lookups
+}
+
+func (t *layoutTable) hasFeature(tag string) bool {
+	for _, feature := range t.features {
+		if feature.tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////
+
+// Layout holds the OpenType layout tables GDEF, GSUB and GPOS of a font, which are used to substitute and position glyphs.
+type Layout struct {
+	glyphClasses      layoutData
+	markAttachClasses layoutData
+	markGlyphSets     layoutData
+	gsub, gpos        *layoutTable
+}
+
+// ParseLayout parses the GDEF, GSUB and GPOS tables of an SFNT font. Missing tables are not an error.
+func ParseLayout(b []byte) (*Layout, error) {
+	_, tables, err := readSFNT(b)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Layout{}
+	if gdef := layoutData(tables["GDEF"]); gdef != nil {
+		l.glyphClasses = gdef.sub(int(gdef.u16(4)))
+		l.markAttachClasses = gdef.sub(int(gdef.u16(10)))
+		if 2 <= gdef.u16(2) {
+			l.markGlyphSets = gdef.sub(int(gdef.u16(12)))
+		}
+	}
+	if gsub, ok := tables["GSUB"]; ok {
+		if l.gsub, err = parseLayoutTable(gsub, 7); err != nil {
+			return nil, err
+		}
+	}
+	if gpos, ok := tables["GPOS"]; ok {
+		if l.gpos, err = parseLayoutTable(gpos, 9); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// HasSubstitution returns true if the font has the GSUB feature.
+func (l *Layout) HasSubstitution(feature string) bool {
+	return l != nil && l.gsub != nil && l.gsub.hasFeature(feature)
+}
+
+// HasPositioning returns true if the font has the GPOS feature.
+func (l *Layout) HasPositioning(feature string) bool {
+	return l != nil && l.gpos != nil && l.gpos.hasFeature(feature)
+}
+
+// IsMark returns true if the glyph is a mark as defined by the GDEF table.
+func (l *Layout) IsMark(glyphID uint16) bool {
+	return l != nil && l.glyphClass(glyphID) == glyphClassMark
+}
+
+func (l *Layout) glyphClass(glyphID uint16) uint16 {
+	return l.glyphClasses.class(glyphID)
+}
+
+// ignore returns true if the glyph is skipped by the lookup, as set by the lookup flags.
+func (l *Layout) ignore(glyphID uint16, lookup *layoutLookup) bool {
+	switch l.glyphClass(glyphID) {
+	case glyphClassBase:
+		return lookup.flag&lookupIgnoreBaseGlyphs != 0
+	case glyphClassLigature:
+		return lookup.flag&lookupIgnoreLigatures != 0
+	case glyphClassMark:
+		if lookup.flag&lookupIgnoreMarks != 0 {
+			return true
+		} else if lookup.flag&lookupUseMarkFilteringSet != 0 {
+			set := l.markGlyphSets.sub(int(l.markGlyphSets.u32(4 + 4*int(lookup.markFilteringSet))))
+			return set.coverage(glyphID) < 0
+		} else if lookup.flag&lookupMarkAttachmentType != 0 {
+			return l.markAttachClasses.class(glyphID) != lookup.flag>>8
+		}
+	}
+	return false
+}
+
+// next returns the index of the next glyph after i that is not ignored by the lookup, or -1.
+func (l *Layout) next(glyphs []Glyph, i int, lookup *layoutLookup) int {
+	for i++; i < len(glyphs); i++ {
+		if !l.ignore(glyphs[i].ID, lookup) {
+			return i
+		}
+	}
+	return -1
+}
+
+// prev returns the index of the previous glyph before i that is not ignored by the lookup, or -1.
+func (l *Layout) prev(glyphs []Glyph, i int, lookup *layoutLookup) int {
+	for i--; 0 <= i; i-- {
+		if !l.ignore(glyphs[i].ID, lookup) {
+			return i
+		}
+	}
+	return -1
+}
+
+// matchInput returns the positions of n glyphs starting at i, where the glyphs after the first must satisfy match(k, glyphID) for k the index in the sequence starting at 1.
+func (l *Layout) matchInput(glyphs []Glyph, i, n int, lookup *layoutLookup, match func(int, uint16) bool) ([]int, bool) {
+	positions := make([]int, n)
+	positions[0] = i
+	for k := 1; k < n; k++ {
+		if i = l.next(glyphs, i, lookup); i < 0 || !match(k, glyphs[i].ID) {
+			return nil, false
+		}
+		positions[k] = i
+	}
+	return positions, true
+}
+
+// matchBacktrack returns true if the n glyphs before i satisfy match(k, glyphID), with k=0 the closest glyph.
+func (l *Layout) matchBacktrack(glyphs []Glyph, i, n int, lookup *layoutLookup, match func(int, uint16) bool) bool {
+	for k := 0; k < n; k++ {
+		if i = l.prev(glyphs, i, lookup); i < 0 || !match(k, glyphs[i].ID) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLookahead returns true if the n glyphs after i satisfy match(k, glyphID), with k=0 the closest glyph.
+func (l *Layout) matchLookahead(glyphs []Glyph, i, n int, lookup *layoutLookup, match func(int, uint16) bool) bool {
+	for k := 0; k < n; k++ {
+		if i = l.next(glyphs, i, lookup); i < 0 || !match(k, glyphs[i].ID) {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////
+
+// Substitute applies the GSUB lookups of the features to the glyph run, lookups of the required feature are always applied. Single, multiple, alternate, ligature and (chained) contextual substitutions are supported, alternate substitutions choose the first alternate.
+func (l *Layout) Substitute(glyphs []Glyph, script string, features []string) []Glyph {
+	if l == nil || l.gsub == nil {
+		return glyphs
+	}
+	for _, index := range l.gsub.lookupIndices(script, features) {
+		lookup := &l.gsub.lookups[index]
+		for i := 0; i < len(glyphs); {
+			if l.ignore(glyphs[i].ID, lookup) {
+				i++
+				continue
+			}
+			var ok bool
+			var next int
+			if glyphs, next, ok = l.substitute(glyphs, i, index, 0); ok {
+				i = next
+			} else {
+				i++
+			}
+		}
+	}
+	return glyphs
+}
+
+// substitute applies the lookup at position i, it returns the new glyph run, the position to continue and whether a substitution took place.
+func (l *Layout) substitute(glyphs []Glyph, i int, lookupIndex uint16, depth int) ([]Glyph, int, bool) {
+	if len(l.gsub.lookups) <= int(lookupIndex) || maxLayoutDepth < depth {
+		return glyphs, i, false
+	}
+	lookup := &l.gsub.lookups[lookupIndex]
+	glyphID := glyphs[i].ID
+	for _, sub := range lookup.subtables {
+		format := sub.u16(0)
+		switch lookup.kind {
+		case 1: // single
+			index := sub.sub(int(sub.u16(2))).coverage(glyphID)
+			if index < 0 {
+				continue
+			}
+			if format == 1 {
+				glyphs[i].ID = glyphID + sub.u16(4)
+			} else if format == 2 && index < int(sub.u16(4)) {
+				glyphs[i].ID = sub.u16(6 + 2*index)
+			} else {
+				continue
+			}
+			return glyphs, i + 1, true
+		case 2, 3: // multiple, alternate
+			index := sub.sub(int(sub.u16(2))).coverage(glyphID)
+			if format != 1 || index < 0 || int(sub.u16(4)) <= index {
+				continue
+			}
+			seq := sub.sub(int(sub.u16(6 + 2*index)))
+			n := int(seq.u16(0))
+			if lookup.kind == 3 {
+				if n == 0 {
+					continue
+				}
+				glyphs[i].ID = seq.u16(2)
+				return glyphs, i + 1, true
+			}
+			replacement := make([]Glyph, n)
+			for k := range replacement {
+				replacement[k] = Glyph{ID: seq.u16(2 + 2*k), Cluster: glyphs[i].Cluster}
+			}
+			glyphs = append(glyphs[:i], append(replacement, glyphs[i+1:]...)...)
+			return glyphs, i + n, true
+		case 4: // ligature
+			index := sub.sub(int(sub.u16(2))).coverage(glyphID)
+			if format != 1 || index < 0 || int(sub.u16(4)) <= index {
+				continue
+			}
+			ligSet := sub.sub(int(sub.u16(6 + 2*index)))
+			for k := 0; k < int(ligSet.u16(0)); k++ {
+				lig := ligSet.sub(int(ligSet.u16(2 + 2*k)))
+				n := int(lig.u16(2))
+				if n == 0 {
+					continue
+				}
+				positions, ok := l.matchInput(glyphs, i, n, lookup, func(k int, glyphID uint16) bool {
+					return lig.u16(4+2*(k-1)) == glyphID
+				})
+				if !ok {
+					continue
+				}
+
+				// replace the components by the ligature, skipped glyphs such as marks are moved after the ligature
+				last := positions[n-1]
+				ligature := Glyph{ID: lig.u16(0), Cluster: glyphs[i].Cluster}
+				replacement := []Glyph{ligature}
+				for j, k := i+1, 1; j < last; j++ {
+					if k < n && positions[k] == j {
+						k++
+					} else {
+						replacement = append(replacement, glyphs[j])
+					}
+				}
+				glyphs = append(glyphs[:i], append(replacement, glyphs[last+1:]...)...)
+				return glyphs, i + 1, true
+			}
+		case 5, 6: // contextual, chained contextual
+			positions, count, records, ok := l.matchContext(glyphs, i, lookup, sub)
+			if !ok {
+				continue
+			}
+			return l.applyContext(glyphs, positions, count, records, depth)
+		}
+	}
+	return glyphs, i, false
+}
+
+// matchContext matches a contextual or chained contextual subtable at position i, it returns the positions of the input sequence and the number of lookup records and the records.
+func (l *Layout) matchContext(glyphs []Glyph, i int, lookup *layoutLookup, sub layoutData) ([]int, int, layoutData, bool) {
+	glyphID := glyphs[i].ID
+	format := sub.u16(0)
+	if lookup.kind == 5 {
+		switch format {
+		case 1, 2:
+			var ruleSet layoutData
+			if index := sub.sub(int(sub.u16(2))).coverage(glyphID); index < 0 {
+				return nil, 0, nil, false
+			} else if format == 1 && index < int(sub.u16(4)) {
+				ruleSet = sub.sub(int(sub.u16(6 + 2*index)))
+			} else if class := int(sub.sub(int(sub.u16(4))).class(glyphID)); format == 2 && class < int(sub.u16(6)) {
+				ruleSet = sub.sub(int(sub.u16(8 + 2*class)))
+			}
+			classDef := sub.sub(int(sub.u16(4)))
+			for k := 0; k < int(ruleSet.u16(0)); k++ {
+				rule := ruleSet.sub(int(ruleSet.u16(2 + 2*k)))
+				n := int(rule.u16(0))
+				if n == 0 {
+					continue
+				}
+				positions, ok := l.matchInput(glyphs, i, n, lookup, func(k int, glyphID uint16) bool {
+					if format == 1 {
+						return rule.u16(4+2*(k-1)) == glyphID
+					}
+					return rule.u16(4+2*(k-1)) == classDef.class(glyphID)
+				})
+				if ok {
+					return positions, int(rule.u16(2)), rule.sub(4 + 2*(n-1)), true
+				}
+			}
+		case 3:
+			n := int(sub.u16(2))
+			if n == 0 || sub.sub(int(sub.u16(6))).coverage(glyphID) < 0 {
+				return nil, 0, nil, false
+			}
+			positions, ok := l.matchInput(glyphs, i, n, lookup, func(k int, glyphID uint16) bool {
+				return 0 <= sub.sub(int(sub.u16(6+2*k))).coverage(glyphID)
+			})
+			if ok {
+				return positions, int(sub.u16(4)), sub.sub(6 + 2*n), true
+			}
+		}
+		return nil, 0, nil, false
+	}
+
+	// chained contextual
+	switch format {
+	case 1, 2:
+		var ruleSet layoutData
+		if index := sub.sub(int(sub.u16(2))).coverage(glyphID); index < 0 {
+			return nil, 0, nil, false
+		} else if format == 1 && index < int(sub.u16(4)) {
+			ruleSet = sub.sub(int(sub.u16(6 + 2*index)))
+		} else if class := int(sub.sub(int(sub.u16(6))).class(glyphID)); format == 2 && class < int(sub.u16(10)) {
+			ruleSet = sub.sub(int(sub.u16(12 + 2*class)))
+		}
+		backtrackClassDef, inputClassDef, lookaheadClassDef := sub.sub(int(sub.u16(4))), sub.sub(int(sub.u16(6))), sub.sub(int(sub.u16(8)))
+		for k := 0; k < int(ruleSet.u16(0)); k++ {
+			rule := ruleSet.sub(int(ruleSet.u16(2 + 2*k)))
+			nBacktrack := int(rule.u16(0))
+			nInput := int(rule.u16(2 + 2*nBacktrack))
+			nLookahead := int(rule.u16(4 + 2*nBacktrack + 2*(nInput-1)))
+			if nInput == 0 {
+				continue
+			}
+			input := 4 + 2*nBacktrack
+			lookahead := input + 2*(nInput-1) + 2
+			records := lookahead + 2*nLookahead
+
+			matchSequence := func(offset int, classDef layoutData) func(int, uint16) bool {
+				return func(k int, glyphID uint16) bool {
+					if format == 1 {
+						return rule.u16(offset+2*k) == glyphID
+					}
+					return rule.u16(offset+2*k) == classDef.class(glyphID)
+				}
+			}
+			positions, ok := l.matchInput(glyphs, i, nInput, lookup, func(k int, glyphID uint16) bool {
+				return matchSequence(input, inputClassDef)(k-1, glyphID)
+			})
+			if !ok || !l.matchBacktrack(glyphs, i, nBacktrack, lookup, matchSequence(2, backtrackClassDef)) || !l.matchLookahead(glyphs, positions[nInput-1], nLookahead, lookup, matchSequence(lookahead, lookaheadClassDef)) {
+				continue
+			}
+			return positions, int(rule.u16(records)), rule.sub(records + 2), true
+		}
+	case 3:
+		nBacktrack := int(sub.u16(2))
+		input := 4 + 2*nBacktrack
+		nInput := int(sub.u16(input))
+		lookahead := input + 2 + 2*nInput
+		nLookahead := int(sub.u16(lookahead))
+		records := lookahead + 2 + 2*nLookahead
+		if nInput == 0 {
+			return nil, 0, nil, false
+		}
+
+		matchCoverage := func(offset int) func(int, uint16) bool {
+			return func(k int, glyphID uint16) bool {
+				return 0 <= sub.sub(int(sub.u16(offset+2*k))).coverage(glyphID)
+			}
+		}
+		if !matchCoverage(input+2)(0, glyphID) {
+			return nil, 0, nil, false
+		}
+		positions, ok := l.matchInput(glyphs, i, nInput, lookup, matchCoverage(input+2))
+		if !ok || !l.matchBacktrack(glyphs, i, nBacktrack, lookup, matchCoverage(4)) || !l.matchLookahead(glyphs, positions[nInput-1], nLookahead, lookup, matchCoverage(lookahead+2)) {
+			return nil, 0, nil, false
+		}
+		return positions, int(sub.u16(records)), sub.sub(records + 2), true
+	}
+	return nil, 0, nil, false
+}
+
+// applyContext applies the lookup records of a matched contextual substitution.
+func (l *Layout) applyContext(glyphs []Glyph, positions []int, count int, records layoutData, depth int) ([]Glyph, int, bool) {
+	for k := 0; k < count; k++ {
+		seqIndex, lookupIndex := int(records.u16(4*k)), records.u16(4*k+2)
+		if len(positions) <= seqIndex || len(glyphs) <= positions[seqIndex] {
+			continue
+		}
+		n := len(glyphs)
+		glyphs, _, _ = l.substitute(glyphs, positions[seqIndex], lookupIndex, depth+1)
+		if diff := len(glyphs) - n; diff != 0 {
+			for j := seqIndex + 1; j < len(positions); j++ {
+				positions[j] += diff
+			}
+		}
+	}
+	next := positions[len(positions)-1] + 1
+	if len(glyphs) < next {
+		next = len(glyphs)
+	}
+	return glyphs, next, true
+}
+
+////////////////////////////////////////////////////////////////
+
+// Position applies the GPOS lookups of the features to the glyph run, the advances of the glyphs must be set. Single adjustment, pair adjustment, mark-to-base and mark-to-mark attachment are supported.
+func (l *Layout) Position(glyphs []Glyph, script string, features []string) {
+	if l == nil || l.gpos == nil {
+		return
+	}
+	for _, index := range l.gpos.lookupIndices(script, features) {
+		lookup := &l.gpos.lookups[index]
+		for i := 0; i < len(glyphs); {
+			if l.ignore(glyphs[i].ID, lookup) {
+				i++
+				continue
+			}
+			i = l.position(glyphs, i, lookup)
+		}
+	}
+}
+
+// position applies the lookup at position i and returns the position to continue.
+func (l *Layout) position(glyphs []Glyph, i int, lookup *layoutLookup) int {
+	glyphID := glyphs[i].ID
+	for _, sub := range lookup.subtables {
+		format := sub.u16(0)
+		switch lookup.kind {
+		case 1: // single adjustment
+			index := sub.sub(int(sub.u16(2))).coverage(glyphID)
+			valueFormat := sub.u16(4)
+			if index < 0 {
+				continue
+			} else if format == 1 {
+				applyValueRecord(&glyphs[i], sub, 6, valueFormat)
+			} else if format == 2 && index < int(sub.u16(6)) {
+				applyValueRecord(&glyphs[i], sub, 8+index*valueRecordSize(valueFormat), valueFormat)
+			} else {
+				continue
+			}
+			return i + 1
+		case 2: // pair adjustment
+			index := sub.sub(int(sub.u16(2))).coverage(glyphID)
+			j := l.next(glyphs, i, lookup)
+			if index < 0 || j < 0 {
+				continue
+			}
+			valueFormat1, valueFormat2 := sub.u16(4), sub.u16(6)
+			size1, size2 := valueRecordSize(valueFormat1), valueRecordSize(valueFormat2)
+			var record int
+			var data layoutData
+			if format == 1 {
+				if int(sub.u16(8)) <= index {
+					continue
+				}
+				pairSet := sub.sub(int(sub.u16(10 + 2*index)))
+				n, size := int(pairSet.u16(0)), 2+size1+size2
+				k := sort.Search(n, func(k int) bool { return glyphs[j].ID <= pairSet.u16(2+k*size) })
+				if n <= k || pairSet.u16(2+k*size) != glyphs[j].ID {
+					continue
+				}
+				data, record = pairSet, 2+k*size+2
+			} else if format == 2 {
+				class1 := int(sub.sub(int(sub.u16(8))).class(glyphID))
+				class2 := int(sub.sub(int(sub.u16(10))).class(glyphs[j].ID))
+				count1, count2 := int(sub.u16(12)), int(sub.u16(14))
+				if count1 <= class1 || count2 <= class2 {
+					continue
+				}
+				data, record = sub, 16+(class1*count2+class2)*(size1+size2)
+			} else {
+				continue
+			}
+			applyValueRecord(&glyphs[i], data, record, valueFormat1)
+			applyValueRecord(&glyphs[j], data, record+size1, valueFormat2)
+			if valueFormat2 != 0 {
+				return j + 1
+			}
+			return j
+		case 4, 6: // mark-to-base, mark-to-mark attachment
+			if format != 1 {
+				continue
+			}
+			markIndex := sub.sub(int(sub.u16(2))).coverage(glyphID)
+			if markIndex < 0 {
+				continue
+			}
+
+			// find the glyph to attach to
+			j := i - 1
+			if lookup.kind == 4 {
+				for 0 <= j && (l.glyphClass(glyphs[j].ID) == glyphClassMark || l.glyphClasses == nil && 0 <= sub.sub(int(sub.u16(2))).coverage(glyphs[j].ID)) {
+					j--
+				}
+			} else {
+				j = l.prev(glyphs, i, lookup)
+			}
+			if j < 0 {
+				continue
+			}
+			baseIndex := sub.sub(int(sub.u16(4))).coverage(glyphs[j].ID)
+			if baseIndex < 0 {
+				continue
+			}
+
+			classCount := int(sub.u16(6))
+			markArray, baseArray := sub.sub(int(sub.u16(8))), sub.sub(int(sub.u16(10)))
+			if int(markArray.u16(0)) <= markIndex || int(baseArray.u16(0)) <= baseIndex {
+				continue
+			}
+			class := int(markArray.u16(2 + 4*markIndex))
+			if classCount <= class {
+				continue
+			}
+			markAnchor := markArray.sub(int(markArray.u16(2 + 4*markIndex + 2)))
+			baseAnchor := baseArray.sub(int(baseArray.u16(2 + 2*(baseIndex*classCount+class))))
+			if markAnchor == nil || baseAnchor == nil {
+				continue
+			}
+
+			// place the mark anchor on the base anchor, the pen is after the advances of the base glyph up to the mark
+			glyphs[i].XAdvance = 0
+			x := int32(baseAnchor.i16(2)) - int32(markAnchor.i16(2)) + glyphs[j].XOffset
+			for k := j; k < i; k++ {
+				x -= glyphs[k].XAdvance
+			}
+			glyphs[i].XOffset = x
+			glyphs[i].YOffset = int32(baseAnchor.i16(4)) - int32(markAnchor.i16(4)) + glyphs[j].YOffset
+			return i + 1
+		}
+	}
+	return i + 1
+}
+
+func valueRecordSize(valueFormat uint16) int {
+	n := 0
+	for v := valueFormat & 0xFF; v != 0; v >>= 1 {
+		n += int(v & 1)
+	}
+	return 2 * n
+}
+
+// applyValueRecord adds the placement and advance adjustments of the value record, device tables are ignored.
+func applyValueRecord(glyph *Glyph, b layoutData, i int, valueFormat uint16) {
+	if valueFormat&0x0001 != 0 {
+		glyph.XOffset += int32(b.i16(i))
+		i += 2
+	}
+	if valueFormat&0x0002 != 0 {
+		glyph.YOffset += int32(b.i16(i))
+		i += 2
+	}
+	if valueFormat&0x0004 != 0 {
+		glyph.XAdvance += int32(b.i16(i))
+	}
+}
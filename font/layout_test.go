@@ -0,0 +1,81 @@
+package font
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/dtrenin7/test"
+	"golang.org/x/image/font/sfnt"
+)
+
+func layoutGlyphs(t *testing.T, font *sfnt.Font, s string) []Glyph {
+	buf := &sfnt.Buffer{}
+	glyphs := []Glyph{}
+	for i, r := range s {
+		glyphID, err := font.GlyphIndex(buf, r)
+		test.Error(t, err)
+		glyphs = append(glyphs, Glyph{ID: uint16(glyphID), Cluster: i})
+	}
+	return glyphs
+}
+
+func TestLayoutSubstitute(t *testing.T) {
+	var tts = []struct {
+		filename string
+		s        string
+		features []string
+		n        int
+	}{
+		{"DejaVuSerif.ttf", "fi", []string{"liga"}, 1},
+		{"DejaVuSerif.ttf", "fi", []string{}, 2},
+		{"EBGaramond12-Regular.otf", "ffi", []string{"liga"}, 3}, // contextual ligature glyphs
+		{"EBGaramond12-Regular.otf", "1/2", []string{"frac"}, 3},
+		{"EBGaramond12-Regular.otf", "abc", []string{"smcp"}, 3},
+		{"EBGaramond12-Regular.otf", "123", []string{"lnum"}, 3},
+		{"EBGaramond12-Regular.otf", "123", []string{"sups"}, 3},
+	}
+	for _, tt := range tts {
+		t.Run(tt.filename+" "+tt.s, func(t *testing.T) {
+			b, err := ioutil.ReadFile(tt.filename)
+			test.Error(t, err)
+			font, err := sfnt.Parse(b)
+			test.Error(t, err)
+			layout, err := ParseLayout(b)
+			test.Error(t, err)
+
+			glyphs := layoutGlyphs(t, font, tt.s)
+			substituted := layout.Substitute(append([]Glyph{}, glyphs...), "latn", tt.features)
+			test.T(t, len(substituted), tt.n)
+			if 0 < len(tt.features) && tt.n == len(glyphs) {
+				test.That(t, substituted[0].ID != glyphs[0].ID, "glyph must be substituted")
+			}
+			test.T(t, substituted[0].Cluster, 0)
+		})
+	}
+}
+
+func TestLayoutPosition(t *testing.T) {
+	b, err := ioutil.ReadFile("DejaVuSerif.ttf")
+	test.Error(t, err)
+	font, err := sfnt.Parse(b)
+	test.Error(t, err)
+	layout, err := ParseLayout(b)
+	test.Error(t, err)
+	test.That(t, layout.HasPositioning("kern"), "must have kerning")
+	test.That(t, !layout.HasPositioning("abcd"), "must not have feature")
+
+	glyphs := layoutGlyphs(t, font, "AV")
+	layout.Position(glyphs, "latn", []string{"kern"})
+	test.T(t, glyphs[0].XAdvance, int32(-102))
+	test.T(t, glyphs[1].XAdvance, int32(0))
+
+	glyphs = layoutGlyphs(t, font, "á")
+	glyphs[0].XAdvance = 1000
+	glyphs[1].XAdvance = 1000
+	layout.Position(glyphs, "latn", []string{"mark"})
+	test.T(t, glyphs[1].XAdvance, int32(0))
+	test.That(t, glyphs[1].XOffset != 0 || glyphs[1].YOffset != 0, "mark must be attached")
+
+	var nilLayout *Layout
+	test.T(t, len(nilLayout.Substitute(glyphs, "latn", []string{"liga"})), 2)
+}
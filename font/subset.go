@@ -0,0 +1,539 @@
+package font
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Specification:
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otff
+// https://docs.microsoft.com/en-us/typography/opentype/spec/glyf
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cmap
+
+// subsetTables are the tables that are copied to the subset, all other tables except for those that are rebuilt are dropped. Tables that depend on glyph indices (such as GSUB, GPOS, kern, or vmtx) are not included.
+var subsetTables = []string{"cvt ", "fpgm", "gasp", "name", "OS/2", "prep"}
+
+// layoutTables are the tables that are copied additionally by SubsetKeepIndices, they depend on glyph indices and can only be copied when the indices are kept.
+var layoutTables = []string{"GDEF", "GPOS", "GSUB", "kern"}
+
+// Subset returns a subset of the TrueType or CFF-based OpenType font that contains only the given glyphs. The subset starts with the .notdef glyph followed by the given glyphs in order, skipping duplicates and the .notdef glyph itself, so that glyph i of the subset is the i-th glyph of that sequence. Glyphs used by composite glyphs are appended at the end. The cmap table of the subset maps all characters of the original font for which the glyph is included, the glyf, loca, hmtx and CFF tables are rebuilt, and tables that depend on glyph indices, such as GSUB, GPOS and kern, are dropped, see SubsetKeepIndices to keep them.
+func Subset(b []byte, glyphIDs []uint16) ([]byte, error) {
+	return subset(b, glyphIDs, false)
+}
+
+// SubsetKeepIndices returns a subset of the TrueType or CFF-based OpenType font that keeps the glyph indices of the original font, so that the GSUB, GPOS, GDEF and kern tables can be copied unchanged. All glyphs other than the given glyphs, the .notdef glyph and the components of composite glyphs keep their metrics but have empty outlines. It is used for text that is shaped by the viewer, such as in SVG, so that kerning, ligatures and other OpenType features keep working.
+func SubsetKeepIndices(b []byte, glyphIDs []uint16) ([]byte, error) {
+	return subset(b, glyphIDs, true)
+}
+
+func subset(b []byte, glyphIDs []uint16, keepIndices bool) ([]byte, error) {
+	flavor, tables, err := readSFNT(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("%s: missing table", tag)
+		}
+	}
+	if len(tables["head"]) < 54 || len(tables["hhea"]) < 36 || len(tables["maxp"]) < 6 {
+		return nil, ErrInvalidFontData
+	}
+	numGlyphs := binary.BigEndian.Uint16(tables["maxp"][4:])
+
+	s := &subsetter{
+		indices: map[uint16]uint16{},
+	}
+	s.add(0)
+	for _, glyphID := range glyphIDs {
+		if numGlyphs <= glyphID {
+			return nil, fmt.Errorf("glyph %d: out of range", glyphID)
+		}
+		s.add(glyphID)
+	}
+
+	if keepIndices {
+		if _, ok := tables["CFF "]; !ok {
+			if _, ok := tables["glyf"]; ok {
+				// add the components of composite glyphs
+				if _, _, err = s.subsetGlyf(tables["glyf"], tables["loca"], tables["head"], numGlyphs); err != nil {
+					return nil, fmt.Errorf("glyf: %w", err)
+				}
+			}
+		}
+
+		// include all glyphs in their original order, emptying those that are not used
+		used := s.indices
+		s = &subsetter{
+			indices: map[uint16]uint16{},
+			empty:   map[uint16]bool{},
+		}
+		for glyphID := uint16(0); glyphID < numGlyphs; glyphID++ {
+			s.add(glyphID)
+			if _, ok := used[glyphID]; !ok {
+				s.empty[glyphID] = true
+			}
+		}
+	}
+
+	subset := map[string][]byte{}
+	if _, ok := tables["CFF "]; ok {
+		if subset["CFF "], err = s.subsetCFF(tables["CFF "], numGlyphs); err != nil {
+			return nil, fmt.Errorf("CFF: %w", err)
+		}
+	} else if _, ok := tables["glyf"]; ok {
+		if subset["glyf"], subset["loca"], err = s.subsetGlyf(tables["glyf"], tables["loca"], tables["head"], numGlyphs); err != nil {
+			return nil, fmt.Errorf("glyf: %w", err)
+		}
+	} else {
+		return nil, fmt.Errorf("unsupported glyph outlines")
+	}
+
+	if subset["hmtx"], err = s.subsetHmtx(tables["hmtx"], tables["hhea"], numGlyphs); err != nil {
+		return nil, fmt.Errorf("hmtx: %w", err)
+	}
+	if cmap, ok := tables["cmap"]; ok {
+		runes, err := parseCmap(cmap)
+		if err != nil {
+			return nil, fmt.Errorf("cmap: %w", err)
+		}
+		subsetRunes := map[rune]uint16{}
+		for r, glyphID := range runes {
+			if index, ok := s.indices[glyphID]; ok && glyphID != 0 && !s.empty[glyphID] {
+				subsetRunes[r] = index
+			}
+		}
+		subset["cmap"] = writeCmap(subsetRunes)
+	}
+
+	n := uint16(len(s.glyphs))
+	head := append([]byte{}, tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0) // checkSumAdjustment
+	if _, ok := subset["loca"]; ok {
+		binary.BigEndian.PutUint16(head[50:], 1) // indexToLocFormat
+	}
+	subset["head"] = head
+	hhea := append([]byte{}, tables["hhea"]...)
+	binary.BigEndian.PutUint16(hhea[34:], n) // numberOfHMetrics
+	subset["hhea"] = hhea
+	maxp := append([]byte{}, tables["maxp"]...)
+	binary.BigEndian.PutUint16(maxp[4:], n) // numGlyphs
+	subset["maxp"] = maxp
+	if post, ok := tables["post"]; ok && 32 <= len(post) {
+		// version 3 has no glyph names
+		post = append([]byte{}, post[:32]...)
+		binary.BigEndian.PutUint32(post, 0x00030000)
+		subset["post"] = post
+	}
+	for _, tag := range subsetTables {
+		if table, ok := tables[tag]; ok {
+			subset[tag] = table
+		}
+	}
+	if keepIndices {
+		for _, tag := range layoutTables {
+			if table, ok := tables[tag]; ok {
+				subset[tag] = table
+			}
+		}
+	}
+	return writeSFNT(flavor, subset), nil
+}
+
+type subsetter struct {
+	glyphs  []uint16          // original glyph IDs of the subset
+	indices map[uint16]uint16 // original glyph ID to glyph ID in the subset
+	empty   map[uint16]bool   // original glyph IDs that are written without outline, see SubsetKeepIndices
+}
+
+func (s *subsetter) add(glyphID uint16) uint16 {
+	if index, ok := s.indices[glyphID]; ok {
+		return index
+	}
+	index := uint16(len(s.glyphs))
+	s.indices[glyphID] = index
+	s.glyphs = append(s.glyphs, glyphID)
+	return index
+}
+
+// subsetGlyf rebuilds the glyf and loca tables, using the long loca format. Components of composite glyphs are added to the subset and their glyph indices are rewritten.
+func (s *subsetter) subsetGlyf(glyf, loca, head []byte, numGlyphs uint16) ([]byte, []byte, error) {
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	if !longLoca && len(loca) < 2*(int(numGlyphs)+1) || longLoca && len(loca) < 4*(int(numGlyphs)+1) {
+		return nil, nil, ErrInvalidFontData
+	}
+	glyphData := func(glyphID uint16) ([]byte, error) {
+		var start, end uint32
+		if i := uint32(glyphID); longLoca {
+			start = binary.BigEndian.Uint32(loca[4*i:])
+			end = binary.BigEndian.Uint32(loca[4*i+4:])
+		} else {
+			start = 2 * uint32(binary.BigEndian.Uint16(loca[2*i:]))
+			end = 2 * uint32(binary.BigEndian.Uint16(loca[2*i+2:]))
+		}
+		if end < start || uint32(len(glyf)) < end {
+			return nil, ErrInvalidFontData
+		}
+		return glyf[start:end], nil
+	}
+
+	w := newBinaryWriter([]byte{})
+	offsets := []uint32{}
+	for i := 0; i < len(s.glyphs); i++ {
+		data, err := glyphData(s.glyphs[i])
+		if err != nil {
+			return nil, nil, err
+		} else if s.empty[s.glyphs[i]] {
+			data = nil
+		}
+		if 10 <= len(data) && int16(binary.BigEndian.Uint16(data)) < 0 {
+			// composite glyph
+			data = append([]byte{}, data...)
+			r := newBinaryReader(data)
+			r.Seek(10)
+			for {
+				flags := r.ReadUint16()
+				pos := r.Pos()
+				glyphID := r.ReadUint16()
+				if r.EOF() || numGlyphs <= glyphID {
+					return nil, nil, ErrInvalidFontData
+				}
+				binary.BigEndian.PutUint16(data[pos:], s.add(glyphID))
+
+				n := uint32(2) // arguments
+				if flags&0x0001 != 0 {
+					n = 4 // ARG_1_AND_2_ARE_WORDS
+				}
+				if flags&0x0008 != 0 {
+					n += 2 // WE_HAVE_A_SCALE
+				} else if flags&0x0040 != 0 {
+					n += 4 // WE_HAVE_AN_X_AND_Y_SCALE
+				} else if flags&0x0080 != 0 {
+					n += 8 // WE_HAVE_A_TWO_BY_TWO
+				}
+				r.ReadBytes(n)
+				if r.EOF() {
+					return nil, nil, ErrInvalidFontData
+				} else if flags&0x0020 == 0 {
+					break // MORE_COMPONENTS
+				}
+			}
+		}
+		offsets = append(offsets, w.Len())
+		w.WriteBytes(data)
+		for w.Len()%4 != 0 {
+			w.WriteByte(0)
+		}
+	}
+	offsets = append(offsets, w.Len())
+
+	wLoca := newBinaryWriter([]byte{})
+	for _, offset := range offsets {
+		wLoca.WriteUint32(offset)
+	}
+	return w.Bytes(), wLoca.Bytes(), nil
+}
+
+// subsetHmtx rebuilds the hmtx table using only long horizontal metrics.
+func (s *subsetter) subsetHmtx(hmtx, hhea []byte, numGlyphs uint16) ([]byte, error) {
+	numberOfHMetrics := uint32(binary.BigEndian.Uint16(hhea[34:]))
+	if numberOfHMetrics == 0 || uint32(numGlyphs) < numberOfHMetrics || uint32(len(hmtx)) < 4*numberOfHMetrics+2*(uint32(numGlyphs)-numberOfHMetrics) {
+		return nil, ErrInvalidFontData
+	}
+
+	w := newBinaryWriter([]byte{})
+	for _, glyphID := range s.glyphs {
+		if i := uint32(glyphID); i < numberOfHMetrics {
+			w.WriteBytes(hmtx[4*i : 4*i+4])
+		} else {
+			lsbPos := 4*numberOfHMetrics + 2*(i-numberOfHMetrics)
+			w.WriteBytes(hmtx[4*numberOfHMetrics-4 : 4*numberOfHMetrics-2]) // advanceWidth
+			w.WriteBytes(hmtx[lsbPos : lsbPos+2])
+		}
+	}
+	return w.Bytes(), nil
+}
+
+////////////////////////////////////////////////////////////////
+
+// readSFNT returns the flavor and tables of an SFNT font.
+func readSFNT(b []byte) (uint32, map[string][]byte, error) {
+	r := newBinaryReader(b)
+	flavor := r.ReadUint32()
+	numTables := r.ReadUint16()
+	_ = r.ReadBytes(6) // searchRange, entrySelector, rangeShift
+	if r.EOF() {
+		return 0, nil, ErrInvalidFontData
+	} else if uint32ToString(flavor) != "OTTO" && uint32ToString(flavor) != "true" && flavor != 0x00010000 {
+		return 0, nil, fmt.Errorf("unsupported font format")
+	}
+
+	tables := map[string][]byte{}
+	for i := 0; i < int(numTables); i++ {
+		tag := r.ReadString(4)
+		_ = r.ReadUint32() // checksum
+		offset := r.ReadUint32()
+		length := r.ReadUint32()
+		if r.EOF() || uint32(len(b)) < offset || uint32(len(b))-offset < length {
+			return 0, nil, ErrInvalidFontData
+		}
+		tables[tag] = b[offset : offset+length : offset+length]
+	}
+	return flavor, tables, nil
+}
+
+// writeSFNT writes an SFNT font with the given tables, and calculates the table checksums and the checksum adjustment in the head table.
+func writeSFNT(flavor uint32, tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := uint16(len(tags))
+	var searchRange uint16 = 1
+	var entrySelector uint16
+	for searchRange*2 <= numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 16
+
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(flavor)
+	w.WriteUint16(numTables)
+	w.WriteUint16(searchRange)
+	w.WriteUint16(entrySelector)
+	w.WriteUint16(numTables*16 - searchRange)
+
+	offset := 12 + 16*uint32(numTables)
+	for _, tag := range tags {
+		data := tables[tag]
+		padded := append(append([]byte{}, data...), make([]byte, (4-len(data)&3)&3)...)
+		w.WriteString(tag)
+		w.WriteUint32(calcChecksum(padded))
+		w.WriteUint32(offset)
+		w.WriteUint32(uint32(len(data)))
+		offset += uint32(len(padded))
+	}
+
+	headPos := uint32(0)
+	for _, tag := range tags {
+		if tag == "head" {
+			headPos = w.Len()
+		}
+		w.WriteBytes(tables[tag])
+		for w.Len()%4 != 0 {
+			w.WriteByte(0)
+		}
+	}
+
+	b := w.Bytes()
+	if headPos != 0 {
+		binary.BigEndian.PutUint32(b[headPos+8:], 0xB1B0AFBA-calcChecksum(b))
+	}
+	return b
+}
+
+// parseCmap returns the character to glyph index mapping of the Unicode subtable of the cmap table. It supports the subtable formats 4 and 12.
+func parseCmap(b []byte) (map[rune]uint16, error) {
+	r := newBinaryReader(b)
+	_ = r.ReadUint16() // version
+	numTables := r.ReadUint16()
+
+	// prefer the full Unicode repertoire over the Basic Multilingual Plane
+	var offset uint32
+	format := uint16(0)
+	for i := 0; i < int(numTables); i++ {
+		platformID := r.ReadUint16()
+		encodingID := r.ReadUint16()
+		subtableOffset := r.ReadUint32()
+		if r.EOF() || uint32(len(b)) < subtableOffset+2 {
+			return nil, ErrInvalidFontData
+		}
+		if platformID == 0 || platformID == 3 && (encodingID == 1 || encodingID == 10) {
+			subtableFormat := binary.BigEndian.Uint16(b[subtableOffset:])
+			if subtableFormat == 12 || subtableFormat == 4 && format != 12 {
+				offset = subtableOffset
+				format = subtableFormat
+			}
+		}
+	}
+
+	runes := map[rune]uint16{}
+	r.Seek(offset)
+	if format == 4 {
+		_ = r.ReadUint16() // format
+		_ = r.ReadUint16() // length
+		_ = r.ReadUint16() // language
+		segCount := uint32(r.ReadUint16() / 2)
+		_ = r.ReadBytes(6) // searchRange, entrySelector, rangeShift
+		endCodes := r.ReadBytes(2 * segCount)
+		_ = r.ReadUint16() // reservedPad
+		startCodes := r.ReadBytes(2 * segCount)
+		idDeltas := r.ReadBytes(2 * segCount)
+		idRangeOffsetsPos := r.Pos()
+		idRangeOffsets := r.ReadBytes(2 * segCount)
+		if r.EOF() {
+			return nil, ErrInvalidFontData
+		}
+		for i := uint32(0); i < segCount; i++ {
+			startCode := uint32(binary.BigEndian.Uint16(startCodes[2*i:]))
+			endCode := uint32(binary.BigEndian.Uint16(endCodes[2*i:]))
+			idDelta := binary.BigEndian.Uint16(idDeltas[2*i:])
+			idRangeOffset := uint32(binary.BigEndian.Uint16(idRangeOffsets[2*i:]))
+			for c := startCode; c <= endCode && c != 0xFFFF; c++ {
+				glyphID := uint16(c) + idDelta
+				if idRangeOffset != 0 {
+					pos := idRangeOffsetsPos + 2*i + idRangeOffset + 2*(c-startCode)
+					if uint32(len(b)) < pos+2 {
+						return nil, ErrInvalidFontData
+					}
+					glyphID = binary.BigEndian.Uint16(b[pos:])
+					if glyphID != 0 {
+						glyphID += idDelta
+					}
+				}
+				if glyphID != 0 {
+					runes[rune(c)] = glyphID
+				}
+			}
+		}
+	} else if format == 12 {
+		_ = r.ReadUint16() // format
+		_ = r.ReadUint16() // reserved
+		_ = r.ReadUint32() // length
+		_ = r.ReadUint32() // language
+		numGroups := r.ReadUint32()
+		if r.EOF() || r.Len()/12 < numGroups {
+			return nil, ErrInvalidFontData
+		}
+		for i := uint32(0); i < numGroups; i++ {
+			startCharCode := r.ReadUint32()
+			endCharCode := r.ReadUint32()
+			startGlyphID := r.ReadUint32()
+			if endCharCode < startCharCode || 0x10FFFF < endCharCode {
+				return nil, ErrInvalidFontData
+			}
+			for c := startCharCode; c <= endCharCode; c++ {
+				if glyphID := uint16(startGlyphID + c - startCharCode); glyphID != 0 {
+					runes[rune(c)] = glyphID
+				}
+			}
+		}
+	}
+	return runes, nil
+}
+
+// writeCmap returns a cmap table with a format 4 subtable for the Basic Multilingual Plane, and a format 12 subtable if there are characters outside of it.
+func writeCmap(runes map[rune]uint16) []byte {
+	type cmapGroup struct {
+		start, end rune
+		glyphID    uint16 // of start
+	}
+
+	chars := make([]rune, 0, len(runes))
+	for r := range runes {
+		chars = append(chars, r)
+	}
+	sort.Slice(chars, func(i, j int) bool { return chars[i] < chars[j] })
+
+	// groups of consecutive characters with consecutive glyph indices
+	groups := []cmapGroup{}
+	for _, r := range chars {
+		glyphID := runes[r]
+		if 0 < len(groups) {
+			last := &groups[len(groups)-1]
+			if last.end+1 == r && uint16(rune(last.glyphID)+r-last.start) == glyphID && (r <= 0xFFFF || 0xFFFF < last.start) {
+				last.end = r
+				continue
+			}
+		}
+		groups = append(groups, cmapGroup{r, r, glyphID})
+	}
+
+	// format 4
+	segments := []cmapGroup{}
+	for _, group := range groups {
+		if group.start < 0xFFFF {
+			if 0xFFFF <= group.end {
+				group.end = 0xFFFE
+			}
+			segments = append(segments, group)
+		}
+	}
+	segments = append(segments, cmapGroup{0xFFFF, 0xFFFF, 1})
+	if 0xFFFF < 16+8*len(segments) {
+		// too many segments for format 4, only write format 12
+		segments = segments[len(segments)-1:]
+	}
+	segCount := uint16(len(segments))
+	var searchRange uint16 = 1
+	var entrySelector uint16
+	for searchRange*2 <= segCount {
+		searchRange *= 2
+		entrySelector++
+	}
+	searchRange *= 2
+
+	w4 := newBinaryWriter([]byte{})
+	w4.WriteUint16(4)
+	w4.WriteUint16(16 + 8*segCount) // length
+	w4.WriteUint16(0)               // language
+	w4.WriteUint16(2 * segCount)
+	w4.WriteUint16(searchRange)
+	w4.WriteUint16(entrySelector)
+	w4.WriteUint16(2*segCount - searchRange)
+	for _, segment := range segments {
+		w4.WriteUint16(uint16(segment.end))
+	}
+	w4.WriteUint16(0) // reservedPad
+	for _, segment := range segments {
+		w4.WriteUint16(uint16(segment.start))
+	}
+	for _, segment := range segments {
+		w4.WriteUint16(segment.glyphID - uint16(segment.start)) // idDelta
+	}
+	for range segments {
+		w4.WriteUint16(0) // idRangeOffset
+	}
+
+	// format 12
+	var w12 *binaryWriter
+	if 0 < len(chars) && 0xFFFF < chars[len(chars)-1] || len(segments) == 1 && 0 < len(groups) {
+		w12 = newBinaryWriter([]byte{})
+		w12.WriteUint16(12)
+		w12.WriteUint16(0) // reserved
+		w12.WriteUint32(16 + 12*uint32(len(groups)))
+		w12.WriteUint32(0) // language
+		w12.WriteUint32(uint32(len(groups)))
+		for _, group := range groups {
+			w12.WriteUint32(uint32(group.start))
+			w12.WriteUint32(uint32(group.end))
+			w12.WriteUint32(uint32(group.glyphID))
+		}
+	}
+
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(0) // version
+	if w12 == nil {
+		w.WriteUint16(1)
+		w.WriteUint16(3) // platformID
+		w.WriteUint16(1) // encodingID
+		w.WriteUint32(12)
+		w.WriteBytes(w4.Bytes())
+	} else {
+		w.WriteUint16(2)
+		w.WriteUint16(3) // platformID
+		w.WriteUint16(1) // encodingID
+		w.WriteUint32(20)
+		w.WriteUint16(3)  // platformID
+		w.WriteUint16(10) // encodingID
+		w.WriteUint32(20 + w4.Len())
+		w.WriteBytes(w4.Bytes())
+		w.WriteBytes(w12.Bytes())
+	}
+	return w.Bytes()
+}
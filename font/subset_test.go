@@ -0,0 +1,127 @@
+package font
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/dtrenin7/test"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestSubset(t *testing.T) {
+	var tts = []struct {
+		filename string
+	}{
+		{"DejaVuSerif.ttf"},
+		{"EBGaramond12-Regular.otf"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.filename, func(t *testing.T) {
+			b, err := ioutil.ReadFile(tt.filename)
+			test.Error(t, err)
+			orig, err := sfnt.Parse(b)
+			test.Error(t, err)
+
+			buf := &sfnt.Buffer{}
+			runes := []rune{'a', 'Z', 'é', 'a'}
+			glyphIDs := []uint16{}
+			for _, r := range runes {
+				glyphID, err := orig.GlyphIndex(buf, r)
+				test.Error(t, err)
+				glyphIDs = append(glyphIDs, uint16(glyphID))
+			}
+
+			subset, err := Subset(b, glyphIDs)
+			test.Error(t, err)
+			test.That(t, len(subset) < len(b)/10, "subset must be smaller")
+			font, err := sfnt.Parse(subset)
+			test.Error(t, err)
+			test.That(t, len(runes)-1 <= font.NumGlyphs(), "subset must contain the glyphs")
+
+			for i, r := range runes[:3] {
+				glyphID, err := font.GlyphIndex(buf, r)
+				test.Error(t, err)
+				test.T(t, glyphID, sfnt.GlyphIndex(i+1), "glyph index of "+string(r))
+
+				origSegments, err := orig.LoadGlyph(buf, sfnt.GlyphIndex(glyphIDs[i]), fixed.I(1000), nil)
+				test.Error(t, err)
+				origSegments = append([]sfnt.Segment{}, origSegments...)
+				segments, err := font.LoadGlyph(buf, glyphID, fixed.I(1000), nil)
+				test.Error(t, err)
+				test.T(t, segments, origSegments, "outline of "+string(r))
+
+				origAdvance, err := orig.GlyphAdvance(buf, sfnt.GlyphIndex(glyphIDs[i]), fixed.I(1000), 0)
+				test.Error(t, err)
+				advance, err := font.GlyphAdvance(buf, glyphID, fixed.I(1000), 0)
+				test.Error(t, err)
+				test.T(t, advance, origAdvance, "advance of "+string(r))
+			}
+
+			glyphID, err := font.GlyphIndex(buf, 'b')
+			test.Error(t, err)
+			test.T(t, glyphID, sfnt.GlyphIndex(0), "glyph not in subset")
+		})
+	}
+
+	_, err := Subset([]byte("wOFF"), nil)
+	test.That(t, err != nil)
+}
+
+func TestSubsetKeepIndices(t *testing.T) {
+	var tts = []struct {
+		filename string
+	}{
+		{"DejaVuSerif.ttf"},
+		{"EBGaramond12-Regular.otf"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.filename, func(t *testing.T) {
+			b, err := ioutil.ReadFile(tt.filename)
+			test.Error(t, err)
+			orig, err := sfnt.Parse(b)
+			test.Error(t, err)
+
+			buf := &sfnt.Buffer{}
+			a, err := orig.GlyphIndex(buf, 'a')
+			test.Error(t, err)
+			z, err := orig.GlyphIndex(buf, 'z')
+			test.Error(t, err)
+
+			subset, err := SubsetKeepIndices(b, []uint16{uint16(a)})
+			test.Error(t, err)
+			test.That(t, len(subset) < len(b), "subset must be smaller")
+			font, err := sfnt.Parse(subset)
+			test.Error(t, err)
+			test.T(t, font.NumGlyphs(), orig.NumGlyphs(), "glyph indices must be kept")
+
+			glyphID, err := font.GlyphIndex(buf, 'a')
+			test.Error(t, err)
+			test.T(t, glyphID, a, "glyph index of a")
+			origSegments, err := orig.LoadGlyph(buf, a, fixed.I(1000), nil)
+			test.Error(t, err)
+			origSegments = append([]sfnt.Segment{}, origSegments...)
+			segments, err := font.LoadGlyph(buf, a, fixed.I(1000), nil)
+			test.Error(t, err)
+			test.T(t, segments, origSegments, "outline of a")
+
+			segments, err = font.LoadGlyph(buf, z, fixed.I(1000), nil)
+			test.Error(t, err)
+			test.T(t, len(segments), 0, "outline of glyph not in subset")
+			origAdvance, err := orig.GlyphAdvance(buf, z, fixed.I(1000), 0)
+			test.Error(t, err)
+			advance, err := font.GlyphAdvance(buf, z, fixed.I(1000), 0)
+			test.Error(t, err)
+			test.T(t, advance, origAdvance, "advance of glyph not in subset")
+
+			_, origTables, err := readSFNT(b)
+			test.Error(t, err)
+			_, tables, err := readSFNT(subset)
+			test.Error(t, err)
+			for _, tag := range layoutTables {
+				test.T(t, tables[tag], origTables[tag], tag+" table")
+			}
+			test.That(t, tables["GSUB"] != nil && tables["GPOS"] != nil, "layout tables")
+		})
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"math"
 	"os/exec"
 	"reflect"
+	"unicode"
 
-	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
 )
 
@@ -40,11 +40,12 @@ const (
 	FontSmallcaps
 )
 
-// FontFamily contains a family of fonts (bold, italic, ...). Selecting an italic style will pick the native italic font or use faux italic if not present.
+// FontFamily contains a family of fonts (bold, italic, ...). Selecting an italic style will pick the native italic font or use faux italic if not present. Characters that are missing in the fonts are taken from the fallback font families.
 type FontFamily struct {
-	name    string
-	fonts   map[FontStyle]*Font
-	options TypographicOptions
+	name      string
+	fonts     map[FontStyle]*Font
+	options   TypographicOptions
+	fallbacks []*FontFamily
 }
 
 // NewFontFamily returns a new FontFamily.
@@ -113,6 +114,11 @@ func (family *FontFamily) Use(options TypographicOptions) {
 	}
 }
 
+// SetFallbacks sets the ordered list of font families that are used for characters that are missing in the fonts of this family, such as emoji, CJK or math symbols. The first fallback family that has the character is used.
+func (family *FontFamily) SetFallbacks(fallbacks ...*FontFamily) {
+	family.fallbacks = fallbacks
+}
+
 // Face gets the font face given by the font size (in pt).
 func (family *FontFamily) Face(size float64, col color.Color, style FontStyle, variant FontVariant, deco ...FontDecorator) FontFace {
 	size *= mmPerPt
@@ -151,7 +157,7 @@ func (family *FontFamily) Face(size float64, col color.Color, style FontStyle, v
 	}
 
 	// TODO: use subscript/superscript size info from SFNT OS/2 table
-	if variant&FontSubscript != 0 || variant&FontSuperscript != 0 {
+	if font.synthesizesPosition(variant) {
 		scale = 0.583
 		fauxBold += 0.02
 		if variant&FontSubscript != 0 {
@@ -219,28 +225,49 @@ func (ff FontFace) Kerning(rPrev, rNext rune) float64 {
 	return k
 }
 
-// TextWidth returns the width of a given string in mm.
-func (ff FontFace) TextWidth(s string) float64 {
+// Glyph is a glyph of shaped text. Cluster is the byte index into the text of the first character that the glyph represents, XAdvance is the advance in mm including adjustments such as kerning, and XOffset and YOffset displace the glyph in mm.
+type Glyph struct {
+	ID       uint16
+	Cluster  int
+	XAdvance float64
+	XOffset  float64
+	YOffset  float64
+}
+
+// Glyphs shapes the string into glyphs using the OpenType features of the font, such as ligatures, kerning and mark positioning, and those of the font variant such as small capitals.
+func (ff FontFace) Glyphs(s string) []Glyph {
+	return ff.glyphs(s, false)
+}
+
+// glyphs shapes the string into glyphs, right-to-left text is returned in visual order.
+func (ff FontFace) glyphs(s string, rtl bool) []Glyph {
+	ppem := toI26_6(ff.Size * ff.Scale)
+	unitsPerEm := ff.Font.sfnt.UnitsPerEm()
+	scale := func(x int32) float64 {
+		return fromI26_6(scaleUnits(x, ppem, unitsPerEm))
+	}
+
 	buffer := &sfnt.Buffer{}
-	w := 0.0
-	var prevIndex sfnt.GlyphIndex
-	for i, r := range s {
-		index, err := ff.Font.sfnt.GlyphIndex(buffer, r)
-		if err != nil {
-			continue
+	shaped := ff.Font.shape(s, rtl, ff.Font.variantFeatures(ff.Variant)...)
+	glyphs := make([]Glyph, len(shaped))
+	for i, glyph := range shaped {
+		advance := ff.Font.unitsAdvance(buffer, glyph.ID)
+		glyphs[i] = Glyph{
+			ID:       glyph.ID,
+			Cluster:  glyph.Cluster,
+			XAdvance: scale(advance) + scale(glyph.XAdvance-advance),
+			XOffset:  scale(glyph.XOffset),
+			YOffset:  scale(glyph.YOffset),
 		}
+	}
+	return glyphs
+}
 
-		if i != 0 {
-			kern, err := ff.Font.sfnt.Kern(buffer, prevIndex, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
-			if err == nil {
-				w += fromI26_6(kern)
-			}
-		}
-		advance, err := ff.Font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
-		if err == nil {
-			w += fromI26_6(advance)
-		}
-		prevIndex = index
+// TextWidth returns the width of a given string in mm.
+func (ff FontFace) TextWidth(s string) float64 {
+	w := 0.0
+	for _, glyph := range ff.Glyphs(s) {
+		w += glyph.XAdvance
 	}
 	return w
 }
@@ -256,74 +283,114 @@ func (ff FontFace) Decorate(width float64) *Path {
 	return p
 }
 
-// ToPath converts a string to a path and also returns its advance in mm.
+// ToPath converts a string to a path and also returns its advance in mm. Characters missing in the font use the fallback fonts of the font family.
 func (ff FontFace) ToPath(s string) (*Path, float64) {
-	buffer := &sfnt.Buffer{}
 	p := &Path{}
 	x := 0.0
-	var prevIndex sfnt.GlyphIndex
-	for i, r := range s {
-		index, err := ff.Font.sfnt.GlyphIndex(buffer, r)
-		if err != nil {
-			return p, 0.0
+	faces, starts := ff.faceRuns(s)
+	for k, face := range faces {
+		end := len(s)
+		if k+1 < len(starts) {
+			end = starts[k+1]
 		}
-
-		segments, err := ff.Font.sfnt.LoadGlyph(buffer, index, toI26_6(ff.Size*ff.Scale), nil)
-		if err != nil {
-			return p, 0.0
+		for _, glyph := range face.Glyphs(s[starts[k]:end]) {
+			p = p.Append(face.GlyphPath(glyph.ID).Translate(x+glyph.XOffset, glyph.YOffset))
+			x += glyph.XAdvance
 		}
+	}
+	return p, x
+}
+
+// faceRuns splits the string into runs of characters that use the same font face, where characters missing in the font use the font face of the first fallback family that has them. It returns the font faces and the byte positions where the runs start.
+func (ff FontFace) faceRuns(s string) ([]FontFace, []int) {
+	if ff.family == nil || len(ff.family.fallbacks) == 0 || s == "" {
+		return []FontFace{ff}, []int{0}
+	}
 
-		var start0, end Point
-		for i, segment := range segments {
-			switch segment.Op {
-			case sfnt.SegmentOpMoveTo:
-				if i != 0 && start0.Equals(end) {
-					p.Close()
+	faces, starts := []FontFace{}, []int{}
+	fallbacks := make([]*FontFace, len(ff.family.fallbacks)) // created when needed
+	cur := -2                                                // index of the fallback face of the current run, -1 is ff
+	for i, r := range s {
+		k := cur
+		if cur == -2 || !isFallbackNeutral(r) {
+			k = -1
+			if !ff.Font.HasGlyph(r) {
+				for j, family := range ff.family.fallbacks {
+					if fallbacks[j] == nil {
+						face := family.Face(ff.Size*ptPerMm, ff.Color, ff.Style, ff.Variant, ff.deco...)
+						fallbacks[j] = &face
+					}
+					if fallbacks[j].Font.HasGlyph(r) {
+						k = j
+						break
+					}
 				}
-				end = fromP26_6(segment.Args[0])
-				end.X += ff.FauxItalic * -end.Y
-				p.MoveTo(x+end.X, ff.Voffset-end.Y)
-				start0 = end
-			case sfnt.SegmentOpLineTo:
-				end = fromP26_6(segment.Args[0])
-				end.X += ff.FauxItalic * -end.Y
-				p.LineTo(x+end.X, ff.Voffset-end.Y)
-			case sfnt.SegmentOpQuadTo:
-				cp := fromP26_6(segment.Args[0])
-				end = fromP26_6(segment.Args[1])
-				cp.X += ff.FauxItalic * -cp.Y
-				end.X += ff.FauxItalic * -end.Y
-				p.QuadTo(x+cp.X, ff.Voffset-cp.Y, x+end.X, ff.Voffset-end.Y)
-			case sfnt.SegmentOpCubeTo:
-				cp1 := fromP26_6(segment.Args[0])
-				cp2 := fromP26_6(segment.Args[1])
-				end = fromP26_6(segment.Args[2])
-				cp1.X += ff.FauxItalic * -cp1.Y
-				cp2.X += ff.FauxItalic * -cp2.Y
-				end.X += ff.FauxItalic * -end.Y
-				p.CubeTo(x+cp1.X, ff.Voffset-cp1.Y, x+cp2.X, ff.Voffset-cp2.Y, x+end.X, ff.Voffset-end.Y)
 			}
 		}
-		if !p.Empty() && start0.Equals(end) {
-			p.Close()
-		}
-		if ff.FauxBold != 0.0 {
-			p = p.Offset(ff.FauxBold, NonZero)
+		if k != cur {
+			if k == -1 {
+				faces = append(faces, ff)
+			} else {
+				faces = append(faces, *fallbacks[k])
+			}
+			starts = append(starts, i)
+			cur = k
 		}
+	}
+	return faces, starts
+}
 
-		if i != 0 {
-			kern, err := ff.Font.sfnt.Kern(buffer, prevIndex, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
-			if err == nil {
-				x += fromI26_6(kern)
+// isFallbackNeutral returns true for characters that stay in the font of the preceding character, such as spaces, combining marks, joiners and variation selectors.
+func isFallbackNeutral(r rune) bool {
+	return isWhitespace(r) || isNewline(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) || 0x200B <= r && r <= 0x200D || 0xFE00 <= r && r <= 0xFE0F || 0xE0100 <= r && r <= 0xE01EF
+}
+
+// GlyphPath returns the outline of the glyph with its origin at (0,0), the font's faux styles and vertical offset are applied.
+func (ff FontFace) GlyphPath(glyphID uint16) *Path {
+	p := &Path{}
+	segments, err := ff.Font.sfnt.LoadGlyph(nil, sfnt.GlyphIndex(glyphID), toI26_6(ff.Size*ff.Scale), nil)
+	if err != nil {
+		return p
+	}
+
+	var start0, end Point
+	for i, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i != 0 && start0.Equals(end) {
+				p.Close()
 			}
+			end = fromP26_6(segment.Args[0])
+			end.X += ff.FauxItalic * -end.Y
+			p.MoveTo(end.X, ff.Voffset-end.Y)
+			start0 = end
+		case sfnt.SegmentOpLineTo:
+			end = fromP26_6(segment.Args[0])
+			end.X += ff.FauxItalic * -end.Y
+			p.LineTo(end.X, ff.Voffset-end.Y)
+		case sfnt.SegmentOpQuadTo:
+			cp := fromP26_6(segment.Args[0])
+			end = fromP26_6(segment.Args[1])
+			cp.X += ff.FauxItalic * -cp.Y
+			end.X += ff.FauxItalic * -end.Y
+			p.QuadTo(cp.X, ff.Voffset-cp.Y, end.X, ff.Voffset-end.Y)
+		case sfnt.SegmentOpCubeTo:
+			cp1 := fromP26_6(segment.Args[0])
+			cp2 := fromP26_6(segment.Args[1])
+			end = fromP26_6(segment.Args[2])
+			cp1.X += ff.FauxItalic * -cp1.Y
+			cp2.X += ff.FauxItalic * -cp2.Y
+			end.X += ff.FauxItalic * -end.Y
+			p.CubeTo(cp1.X, ff.Voffset-cp1.Y, cp2.X, ff.Voffset-cp2.Y, end.X, ff.Voffset-end.Y)
 		}
-		advance, err := ff.Font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
-		if err == nil {
-			x += fromI26_6(advance)
-		}
-		prevIndex = index
 	}
-	return p, x
+	if !p.Empty() && start0.Equals(end) {
+		p.Close()
+	}
+	if ff.FauxBold != 0.0 {
+		p = p.Offset(ff.FauxBold, NonZero)
+	}
+	return p
 }
 
 func (ff FontFace) Boldness() int {
@@ -345,7 +412,7 @@ func (ff FontFace) Boldness() int {
 	} else if ff.Style&FontExtraBlack == FontExtraBlack {
 		boldness = 900
 	}
-	if ff.Variant&FontSubscript != 0 || ff.Variant&FontSuperscript != 0 {
+	if ff.Font.synthesizesPosition(ff.Variant) {
 		boldness += 300
 		if 1000 < boldness {
 			boldness = 1000
@@ -50,6 +50,64 @@ func TestFontFace(t *testing.T) {
 	test.Float(t, width, 18.515625)
 }
 
+func TestFontFaceGlyphs(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.T(t, len(face.Glyphs("fi")), 2)
+
+	family.Use(CommonLigatures)
+	face = family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	glyphs := face.Glyphs("fi")
+	test.T(t, len(glyphs), 1)
+	test.Float(t, face.TextWidth("fi"), glyphs[0].XAdvance)
+
+	glyphs = face.Glyphs("AV")
+	test.Float(t, glyphs[0].XAdvance, face.Font.GlyphAdvance(glyphs[0].ID, 12.0)+face.Kerning('A', 'V'))
+
+	ebGaramond := NewFontFamily("eb-garamond")
+	ebGaramond.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular)
+	ebGaramond.Use(Fractions)
+	face = ebGaramond.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	glyphs = face.Glyphs("1/2 1")
+	test.That(t, glyphs[0].ID != face.Font.IndicesOf("1")[0], "fraction must be substituted")
+	test.T(t, glyphs[4].ID, face.Font.IndicesOf("1")[0])
+
+	smallcaps := ebGaramond.Face(12.0*ptPerMm, Black, FontRegular, FontSmallcaps)
+	test.That(t, smallcaps.Glyphs("a")[0].ID != face.Glyphs("a")[0].ID, "small capitals must be substituted")
+
+	superscript := ebGaramond.Face(12.0*ptPerMm, Black, FontRegular, FontSuperscript)
+	test.Float(t, superscript.Voffset, 0.0)
+	test.Float(t, superscript.Size, 12.0)
+	test.That(t, superscript.Glyphs("2")[0].ID != face.Glyphs("2")[0].ID, "superscript must be substituted")
+}
+
+func TestFontFaceFallback(t *testing.T) {
+	fallback := NewFontFamily("eb-garamond")
+	fallback.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular)
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	test.That(t, !face.Font.HasGlyph('\u211E'), "prescription sign must be missing")
+	test.That(t, fallback.fonts[FontRegular].HasGlyph('\u211E'), "prescription sign must be in fallback")
+
+	faces, starts := face.faceRuns("5 \u2113 water \u211E")
+	test.T(t, len(faces), 1)
+	test.T(t, starts, []int{0})
+
+	family.SetFallbacks(fallback)
+	faces, starts = face.faceRuns("5 \u2113 water \u211E")
+	test.T(t, len(faces), 4)
+	test.T(t, starts, []int{0, 2, 6, 12})
+	test.T(t, faces[1].Font, fallback.fonts[FontRegular])
+	test.Float(t, faces[1].Size, face.Size)
+
+	p, advance := face.ToPath("\u211E")
+	test.That(t, !p.Empty(), "path must not be empty")
+	test.Float(t, advance, faces[3].TextWidth("\u211E"))
+}
+
 func TestFontDecoration(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
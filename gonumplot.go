@@ -137,7 +137,7 @@ func (r *GonumPlot) Fill(path vg.Path) {
 // location using the given font.
 // If the font size is zero, the text is not drawn.
 func (r *GonumPlot) FillString(f vg.Font, pt vg.Point, text string) {
-	face := r.font.Face(float64(f.Size), r.ctx.FillColor, FontRegular, FontNormal)
+	face := r.font.Face(float64(f.Size), r.ctx.Style.Fill.Color, FontRegular, FontNormal)
 	r.ctx.DrawText(float64(pt.X*mmPerPt), float64(pt.Y*mmPerPt), NewTextLine(face, text, Left))
 }
 
@@ -0,0 +1,317 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Spread is the spread mode of a gradient, which determines the color outside of the gradient's range.
+type Spread int
+
+// see Spread
+const (
+	PadSpread     Spread = iota // use the color of the first or last stop
+	ReflectSpread               // mirror the gradient back and forth
+	RepeatSpread                // repeat the gradient
+)
+
+// apply maps the gradient parameter t to the range [0,1] following the spread mode.
+func (spread Spread) apply(t float64) float64 {
+	switch spread {
+	case ReflectSpread:
+		t = math.Mod(math.Abs(t), 2.0)
+		if 1.0 < t {
+			t = 2.0 - t
+		}
+	case RepeatSpread:
+		t -= math.Floor(t)
+	default:
+		t = math.Max(0.0, math.Min(1.0, t))
+	}
+	return t
+}
+
+// Stop is a color stop of a gradient at an offset between 0 and 1.
+type Stop struct {
+	Offset float64
+	Color  color.RGBA
+}
+
+// Stops is a list of color stops ordered by offset.
+type Stops []Stop
+
+// Add adds a color stop at the given offset, which is clamped to [0,1]. Stops at equal offsets are kept in the order they were added, which allows for sharp color transitions.
+func (stops *Stops) Add(offset float64, col color.Color) {
+	r, g, b, a := col.RGBA()
+	offset = math.Max(0.0, math.Min(1.0, offset))
+	stop := Stop{offset, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}}
+	i := sort.Search(len(*stops), func(i int) bool { return offset < (*stops)[i].Offset })
+	*stops = append(*stops, Stop{})
+	copy((*stops)[i+1:], (*stops)[i:])
+	(*stops)[i] = stop
+}
+
+// At returns the color at offset t, interpolating linearly between the (premultiplied) colors of the neighbouring stops. Outside of the stops the color of the first or last stop is returned.
+func (stops Stops) At(t float64) color.RGBA {
+	if len(stops) == 0 {
+		return Transparent
+	} else if t <= stops[0].Offset {
+		return stops[0].Color
+	}
+	for i, stop := range stops[1:] {
+		if t < stop.Offset {
+			prev := stops[i]
+			return interpolateColor(prev.Color, stop.Color, (t-prev.Offset)/(stop.Offset-prev.Offset))
+		}
+	}
+	return stops[len(stops)-1].Color
+}
+
+// Expand returns the color stops for the range [t0,t1] of the gradient parameter, repeating or reflecting the stops following the spread mode. The first and last stops are at t0 and t1 respectively. This allows renderers that only support the pad spread mode to render the other spread modes as well.
+func (stops Stops) Expand(spread Spread, t0, t1 float64) Stops {
+	if len(stops) == 0 || t1 <= t0 {
+		return Stops{}
+	}
+
+	expanded := Stops{}
+	expanded = append(expanded, Stop{t0, stops.At(spread.apply(t0))})
+	if spread == PadSpread {
+		for _, stop := range stops {
+			if t0 < stop.Offset && stop.Offset < t1 {
+				expanded = append(expanded, stop)
+			}
+		}
+	} else {
+		for k := math.Floor(t0); k < t1; k++ {
+			reflect := spread == ReflectSpread && math.Mod(math.Abs(k), 2.0) == 1.0
+			for i := range stops {
+				stop := stops[i]
+				if reflect {
+					stop = stops[len(stops)-1-i]
+					stop.Offset = 1.0 - stop.Offset
+				}
+				stop.Offset += k
+				if t0 < stop.Offset && stop.Offset < t1 && stop != expanded[len(expanded)-1] {
+					expanded = append(expanded, stop)
+				}
+			}
+		}
+	}
+	end := spread.apply(t1)
+	if spread == RepeatSpread && end == 0.0 {
+		end = 1.0 // color at the end of the period
+	}
+	expanded = append(expanded, Stop{t1, stops.At(end)})
+	return expanded
+}
+
+func interpolateColor(a, b color.RGBA, t float64) color.RGBA {
+	return color.RGBA{
+		uint8(float64(a.R) + t*(float64(b.R)-float64(a.R)) + 0.5),
+		uint8(float64(a.G) + t*(float64(b.G)-float64(a.G)) + 0.5),
+		uint8(float64(a.B) + t*(float64(b.B)-float64(a.B)) + 0.5),
+		uint8(float64(a.A) + t*(float64(b.A)-float64(a.A)) + 0.5),
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// Gradient is a paint that varies in color over the plane, it is used to fill or stroke paths through the Gradient of Style.Fill and Style.Stroke. Its coordinates are in the same coordinate system as the path it paints. LinearGradient and RadialGradient are rendered natively by the PDF, SVG and HTML canvas renderers, other implementations are only supported by the rasterizer. Renderers that do not support gradients use the color of the Paint instead.
+type Gradient interface {
+	// At returns the color at (x,y).
+	At(x, y float64) color.RGBA
+}
+
+// LinearGradient is a gradient along the line from Start to End, where the color is constant perpendicular to that line.
+type LinearGradient struct {
+	Start, End Point
+	Stops
+	Spread
+}
+
+// NewLinearGradient returns a new linear gradient from start to end. Add color stops to the gradient using Add.
+func NewLinearGradient(start, end Point) *LinearGradient {
+	return &LinearGradient{
+		Start: start,
+		End:   end,
+	}
+}
+
+// Param returns the gradient parameter at (x,y), which is 0 at Start and 1 at End.
+func (g *LinearGradient) Param(x, y float64) float64 {
+	d := g.End.Sub(g.Start)
+	dd := d.Dot(d)
+	if dd == 0.0 {
+		return 0.0
+	}
+	return Point{x, y}.Sub(g.Start).Dot(d) / dd
+}
+
+// At returns the color at (x,y).
+func (g *LinearGradient) At(x, y float64) color.RGBA {
+	return g.Stops.At(g.Spread.apply(g.Param(x, y)))
+}
+
+// ParamRange returns the range of the gradient parameter over the rectangle.
+func (g *LinearGradient) ParamRange(rect Rect) (float64, float64) {
+	t0, t1 := math.Inf(1), math.Inf(-1)
+	for _, p := range rectCorners(rect) {
+		t := g.Param(p.X, p.Y)
+		t0 = math.Min(t0, t)
+		t1 = math.Max(t1, t)
+	}
+	return t0, t1
+}
+
+// RadialGradient is a gradient between the circle at C0 with radius R0 and the circle at C1 with radius R1, as in PDF, SVG and HTML canvas. The gradient parameter is 0 at the first circle and 1 at the second, the circles in between are interpolated linearly. Setting C0 equal to C1 and R0 to zero gives a circular gradient, while a different C0 gives a focal point.
+type RadialGradient struct {
+	C0 Point
+	R0 float64
+	C1 Point
+	R1 float64
+	Stops
+	Spread
+}
+
+// NewRadialGradient returns a new radial gradient between the circle at c0 with radius r0 and the circle at c1 with radius r1. Add color stops to the gradient using Add.
+func NewRadialGradient(c0 Point, r0 float64, c1 Point, r1 float64) *RadialGradient {
+	return &RadialGradient{
+		C0: c0,
+		R0: r0,
+		C1: c1,
+		R1: r1,
+	}
+}
+
+// Param returns the gradient parameter at (x,y), which is the largest t for which (x,y) lies on the interpolated circle with a non-negative radius. It returns false if there is no such circle.
+func (g *RadialGradient) Param(x, y float64) (float64, bool) {
+	cd := g.C1.Sub(g.C0)
+	dr := g.R1 - g.R0
+	pd := Point{x, y}.Sub(g.C0)
+
+	// solve |pd - t*cd| = r0 + t*dr
+	a := cd.Dot(cd) - dr*dr
+	b := pd.Dot(cd) + g.R0*dr
+	c := pd.Dot(pd) - g.R0*g.R0
+	if math.Abs(a) < 1e-12 {
+		if b == 0.0 {
+			return 0.0, false
+		}
+		t := c / (2.0 * b)
+		return t, 0.0 <= g.R0+t*dr
+	}
+
+	discriminant := b*b - a*c
+	if discriminant < 0.0 {
+		return 0.0, false
+	}
+	sqrt := math.Sqrt(discriminant)
+	t0, t1 := (b-sqrt)/a, (b+sqrt)/a
+	if t1 < t0 {
+		t0, t1 = t1, t0
+	}
+	if 0.0 <= g.R0+t1*dr {
+		return t1, true
+	} else if 0.0 <= g.R0+t0*dr {
+		return t0, true
+	}
+	return 0.0, false
+}
+
+// At returns the color at (x,y).
+func (g *RadialGradient) At(x, y float64) color.RGBA {
+	t, ok := g.Param(x, y)
+	if !ok {
+		return Transparent
+	}
+	return g.Stops.At(g.Spread.apply(t))
+}
+
+// ParamRange returns the range of the gradient parameter over the rectangle. It always includes [0,1] and does not extend to circles with a negative radius. Since the extremes need not be at the corners, it also considers the points where an edge touches an interpolated circle or crosses the boundary of the cone, and the point where the radius is zero.
+func (g *RadialGradient) ParamRange(rect Rect) (float64, float64) {
+	t0, t1 := 0.0, 1.0
+	add := func(t float64) {
+		t0 = math.Min(t0, t)
+		t1 = math.Max(t1, t)
+	}
+
+	cd := g.C1.Sub(g.C0)
+	dr := g.R1 - g.R0
+	a := cd.Dot(cd) - dr*dr
+	corners := rectCorners(rect)
+	edges := [4][2]Point{{corners[0], corners[1]}, {corners[1], corners[3]}, {corners[3], corners[2]}, {corners[2], corners[0]}}
+	for _, edge := range edges {
+		p0 := edge[0]
+		if t, ok := g.Param(p0.X, p0.Y); ok {
+			add(t)
+		}
+
+		length := edge[1].Sub(p0).Length()
+		if length == 0.0 {
+			continue
+		}
+		e := edge[1].Sub(p0).Div(length)
+		n := e.Rot90CW()
+
+		// the parameter is extreme where the edge touches a circle, ie. the distance from its center to the edge equals its radius
+		k := p0.Sub(g.C0).Dot(n)
+		for _, sign := range []float64{-1.0, 1.0} {
+			if denom := cd.Dot(n) + sign*dr; denom != 0.0 {
+				t := (k - sign*g.R0) / denom
+				if s := g.C0.Add(cd.Mul(t)).Sub(p0).Dot(e); 0.0 <= s && s <= length {
+					p := p0.Add(e.Mul(s))
+					if t, ok := g.Param(p.X, p.Y); ok {
+						add(t)
+					}
+				}
+			}
+		}
+
+		// the parameter is extreme where the edge crosses the boundary of the cone, ie. where the discriminant of Param is zero
+		if a != 0.0 {
+			pd0 := p0.Sub(g.C0)
+			b0 := pd0.Dot(cd) + g.R0*dr
+			c0 := pd0.Dot(pd0) - g.R0*g.R0
+			eb := e.Dot(cd)
+			s1, s2 := solveQuadraticFormula(eb*eb-a, 2.0*(b0*eb-a*pd0.Dot(e)), b0*b0-a*c0)
+			for _, s := range []float64{s1, s2} {
+				if 0.0 <= s && s <= length {
+					if t := (b0 + s*eb) / a; 0.0 <= g.R0+t*dr {
+						add(t)
+					}
+				}
+			}
+		}
+	}
+
+	// inside the rectangle the parameter is only extreme where the radius is zero
+	if dr != 0.0 {
+		t := -g.R0 / dr
+		if c := g.C0.Add(cd.Mul(t)); rect.X <= c.X && c.X <= rect.X+rect.W && rect.Y <= c.Y && c.Y <= rect.Y+rect.H {
+			add(t)
+		}
+	}
+
+	// the radius must be non-negative
+	if 0.0 < dr {
+		t0 = math.Max(t0, -g.R0/dr)
+	} else if dr < 0.0 {
+		t1 = math.Min(t1, -g.R0/dr)
+	}
+	return t0, t1
+}
+
+// Circle returns the center and radius of the interpolated circle at t.
+func (g *RadialGradient) Circle(t float64) (Point, float64) {
+	return g.C0.Interpolate(g.C1, t), g.R0 + t*(g.R1-g.R0)
+}
+
+func rectCorners(rect Rect) [4]Point {
+	return [4]Point{
+		{rect.X, rect.Y},
+		{rect.X + rect.W, rect.Y},
+		{rect.X, rect.Y + rect.H},
+		{rect.X + rect.W, rect.Y + rect.H},
+	}
+}
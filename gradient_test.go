@@ -0,0 +1,118 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestStops(t *testing.T) {
+	stops := Stops{}
+	stops.Add(1.0, Blue)
+	stops.Add(0.0, Red)
+	stops.Add(0.5, White)
+	stops.Add(0.5, Black)
+	stops.Add(2.0, Green)
+	test.T(t, len(stops), 5)
+	test.T(t, stops[0], Stop{0.0, Red})
+	test.T(t, stops[1], Stop{0.5, White})
+	test.T(t, stops[2], Stop{0.5, Black})
+	test.T(t, stops[3], Stop{1.0, Blue})
+	test.T(t, stops[4], Stop{1.0, Green})
+
+	var tts = []struct {
+		t float64
+		c color.RGBA
+	}{
+		{-1.0, Red},
+		{0.0, Red},
+		{0.25, color.RGBA{255, 128, 128, 255}},
+		{0.5, Black},
+		{0.75, color.RGBA{0, 0, 128, 255}},
+		{1.0, Green},
+		{2.0, Green},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.t), func(t *testing.T) {
+			test.T(t, stops.At(tt.t), tt.c)
+		})
+	}
+	test.T(t, Stops{}.At(0.5), Transparent)
+}
+
+func TestStopsExpand(t *testing.T) {
+	stops := Stops{{0.0, Black}, {1.0, White}}
+	gray := color.RGBA{128, 128, 128, 255}
+
+	test.T(t, stops.Expand(PadSpread, 0.0, 1.0), Stops{{0.0, Black}, {1.0, White}})
+	test.T(t, stops.Expand(PadSpread, -1.0, 0.5), Stops{{-1.0, Black}, {0.0, Black}, {0.5, gray}})
+	test.T(t, stops.Expand(ReflectSpread, 0.5, 2.0), Stops{{0.5, gray}, {1.0, White}, {2.0, Black}})
+	test.T(t, stops.Expand(RepeatSpread, -0.5, 1.0), Stops{{-0.5, gray}, {0.0, White}, {0.0, Black}, {1.0, White}})
+	test.T(t, len(stops.Expand(RepeatSpread, 1.0, 1.0)), 0)
+}
+
+func TestLinearGradient(t *testing.T) {
+	g := NewLinearGradient(Point{1.0, 0.0}, Point{3.0, 0.0})
+	g.Add(0.0, Black)
+	g.Add(1.0, White)
+
+	test.Float(t, g.Param(2.0, 5.0), 0.5)
+	test.T(t, g.At(0.0, 0.0), Black)
+	test.T(t, g.At(2.0, 1.0), color.RGBA{128, 128, 128, 255})
+	test.T(t, g.At(4.0, 0.0), White)
+
+	g.Spread = ReflectSpread
+	test.T(t, g.At(5.0, 0.0), Black)
+	test.T(t, g.At(-1.0, 0.0), White)
+	g.Spread = RepeatSpread
+	test.T(t, g.At(4.0, 0.0), color.RGBA{128, 128, 128, 255})
+
+	t0, t1 := g.ParamRange(Rect{0.0, 0.0, 5.0, 1.0})
+	test.Float(t, t0, -0.5)
+	test.Float(t, t1, 2.0)
+}
+
+func TestRadialGradient(t *testing.T) {
+	g := NewRadialGradient(Point{0.0, 0.0}, 0.0, Point{0.0, 0.0}, 2.0)
+	g.Add(0.0, Black)
+	g.Add(1.0, White)
+
+	param, ok := g.Param(1.0, 0.0)
+	test.That(t, ok)
+	test.Float(t, param, 0.5)
+	test.T(t, g.At(0.0, 0.0), Black)
+	test.T(t, g.At(0.0, -1.0), color.RGBA{128, 128, 128, 255})
+	test.T(t, g.At(3.0, 0.0), White)
+
+	t0, t1 := g.ParamRange(Rect{-4.0, -3.0, 8.0, 6.0})
+	test.Float(t, t0, 0.0)
+	test.Float(t, t1, 2.5)
+
+	// focal point, outside of the cone there is no color
+	g = NewRadialGradient(Point{0.0, 0.0}, 1.0, Point{4.0, 0.0}, 1.0)
+	g.Add(0.0, Black)
+	g.Add(1.0, White)
+	param, ok = g.Param(2.0, 1.0)
+	test.That(t, ok)
+	test.Float(t, param, 0.5)
+	_, ok = g.Param(2.0, 2.0)
+	test.That(t, !ok)
+	test.T(t, g.At(2.0, 2.0), Transparent)
+
+	c, r := g.Circle(0.5)
+	test.T(t, c, Point{2.0, 0.0})
+	test.Float(t, r, 1.0)
+
+	// off-center focus, the extremes lie inside the edges of the rectangle
+	g = NewRadialGradient(Point{0.0, 0.0}, 0.0, Point{4.0, 0.0}, 1.0)
+	t0, t1 = g.ParamRange(Rect{3.0, -3.0, 3.0, 6.0})
+	test.Float(t, t0, 0.0)
+	test.Float(t, t1, 2.0) // circle at (8,0) with radius 2 touches the right edge
+
+	g = NewRadialGradient(Point{0.0, 0.0}, 2.0, Point{1.0, 0.0}, 4.0)
+	t0, t1 = g.ParamRange(Rect{-3.0, 0.5, 6.0, 0.5})
+	test.Float(t, t0, -0.75) // circle at (-0.75,0) with radius 0.5 touches the bottom edge
+	test.Float(t, t1, 1.1196329811802246)
+}
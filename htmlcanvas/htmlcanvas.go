@@ -15,6 +15,7 @@ type htmlCanvas struct {
 	width, height float64
 	dpm           float64
 	style         canvas.Style
+	styleStack    []canvas.Style
 }
 
 func New(c js.Value, width, height, dpm float64) *htmlCanvas {
@@ -38,11 +39,7 @@ func (r *htmlCanvas) Size() (float64, float64) {
 	return r.width / r.dpm, r.height / r.dpm
 }
 
-func (r *htmlCanvas) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	if path.Empty() {
-		return
-	}
-	path = path.Transform(m)
+func (r *htmlCanvas) writePath(path *canvas.Path) {
 	path = path.ReplaceArcs()
 
 	r.ctx.Call("beginPath")
@@ -59,14 +56,47 @@ func (r *htmlCanvas) RenderPath(path *canvas.Path, style canvas.Style, m canvas.
 	}, func(start, end canvas.Point) {
 		r.ctx.Call("closePath")
 	})
+}
+
+func (r *htmlCanvas) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if path.Empty() {
+		return
+	}
+	r.writePath(path.Transform(m))
 
-	if style.FillColor.A != 0 {
-		if style.FillColor != r.style.FillColor {
-			r.ctx.Set("fillStyle", canvas.CSSColor(style.FillColor).String())
+	fillGradient, strokeGradient, strokeOutline := false, false, false
+	if style.HasFill() {
+		if style.Fill.IsGradient() {
+			fillGradient = r.fillGradient(style.Fill.Gradient, path.Bounds(), m)
+		}
+		if !fillGradient {
+			if style.Fill.Color != r.style.Fill.Color {
+				r.ctx.Set("fillStyle", canvas.CSSColor(style.Fill.Color).String())
+			}
+			r.ctx.Call("fill")
 		}
-		r.ctx.Call("fill")
 	}
-	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+	if style.HasStroke() && (style.Stroke.IsGradient() || style.StrokeProfile != nil) {
+		// gradients are relative to the transformation at the time of drawing, which would also affect the stroke width, instead we fill the stroke outline
+		// variable stroke widths are not supported and are filled as well
+		outline := path.Transform(m)
+		if 0 < len(style.Dashes) {
+			outline = outline.Dash(style.DashOffset, style.Dashes...)
+		}
+		outline = style.StrokeOutline(outline)
+		r.writePath(outline)
+		if style.Stroke.IsGradient() {
+			strokeGradient = r.fillGradient(style.Stroke.Gradient, outline.Transform(m.Inv()).Bounds(), m)
+		}
+		if !strokeGradient && style.StrokeProfile != nil {
+			r.ctx.Set("fillStyle", canvas.CSSColor(style.Stroke.Color).String())
+			r.ctx.Call("fill")
+			strokeOutline = true
+		} else if !strokeGradient {
+			r.writePath(path.Transform(m))
+		}
+	}
+	if style.HasStroke() && !strokeGradient && !strokeOutline {
 		if style.StrokeCapper != r.style.StrokeCapper {
 			if _, ok := style.StrokeCapper.(canvas.RoundCapper); ok {
 				r.ctx.Set("lineCap", "round")
@@ -118,19 +148,69 @@ func (r *htmlCanvas) RenderPath(path *canvas.Path, style canvas.Style, m canvas.
 		if style.StrokeWidth != r.style.StrokeWidth {
 			r.ctx.Set("lineWidth", style.StrokeWidth*r.dpm)
 		}
-		if style.StrokeColor != r.style.StrokeColor {
-			r.ctx.Set("strokeStyle", canvas.CSSColor(style.StrokeColor).String())
+		if style.Stroke.Color != r.style.Stroke.Color {
+			r.ctx.Set("strokeStyle", canvas.CSSColor(style.Stroke.Color).String())
 		}
 		r.ctx.Call("stroke")
 	}
 	r.style = style
+	if fillGradient || strokeGradient || strokeOutline {
+		r.style.Fill = canvas.Paint{} // fill style has been set to a gradient or the stroke color
+	}
+}
+
+// fillGradient fills the current path with the gradient, where bounds are the bounds of the path in the coordinate system of the gradient and m transforms the gradient to the canvas coordinate system. It returns false if the gradient is not supported.
+func (r *htmlCanvas) fillGradient(gradient canvas.Gradient, bounds canvas.Rect, m canvas.Matrix) bool {
+	// HTML canvas only supports the pad spread mode, other modes are expanded over the bounds
+	var jsGradient js.Value
+	var stops canvas.Stops
+	switch g := gradient.(type) {
+	case *canvas.LinearGradient:
+		t0, t1 := 0.0, 1.0
+		if g.Spread != canvas.PadSpread {
+			if tmin, tmax := g.ParamRange(bounds); tmin < tmax {
+				t0, t1 = tmin, tmax
+			}
+		}
+		start := g.Start.Interpolate(g.End, t0)
+		end := g.Start.Interpolate(g.End, t1)
+		jsGradient = r.ctx.Call("createLinearGradient", start.X, start.Y, end.X, end.Y)
+		stops = g.Stops.Expand(g.Spread, t0, t1)
+		for i := range stops {
+			stops[i].Offset = (stops[i].Offset - t0) / (t1 - t0)
+		}
+	case *canvas.RadialGradient:
+		t0, t1 := 0.0, 1.0
+		if g.Spread != canvas.PadSpread {
+			t0, t1 = g.ParamRange(bounds)
+		}
+		c0, r0 := g.Circle(t0)
+		c1, r1 := g.Circle(t1)
+		jsGradient = r.ctx.Call("createRadialGradient", c0.X, c0.Y, r0, c1.X, c1.Y, r1)
+		stops = g.Stops.Expand(g.Spread, t0, t1)
+		for i := range stops {
+			stops[i].Offset = (stops[i].Offset - t0) / (t1 - t0)
+		}
+	default:
+		return false
+	}
+	for _, stop := range stops {
+		jsGradient.Call("addColorStop", stop.Offset, canvas.CSSColor(stop.Color).String())
+	}
+
+	m = canvas.Identity.Translate(0.0, r.height).Scale(r.dpm, -r.dpm).Mul(m)
+	r.ctx.Set("fillStyle", jsGradient)
+	r.ctx.Call("setTransform", m[0][0], m[1][0], m[0][1], m[1][1], m[0][2], m[1][2])
+	r.ctx.Call("fill")
+	r.ctx.Call("setTransform", 1.0, 0.0, 0.0, 1.0, 0.0, 0.0)
+	return true
 }
 
 func (r *htmlCanvas) RenderText(text *canvas.Text, m canvas.Matrix) {
 	paths, colors := text.ToPaths()
 	for i, path := range paths {
 		style := canvas.DefaultStyle
-		style.FillColor = colors[i]
+		style.Fill.Color = colors[i]
 		r.RenderPath(path, style, m)
 	}
 }
@@ -195,3 +275,26 @@ func (r *htmlCanvas) RenderImage(img image.Image, m canvas.Matrix) {
 	r.ctx.Call("drawImage", imageBitmap, 0, 0)
 	r.ctx.Call("setTransform", 1.0, 0.0, 0.0, 1.0, 0.0, 0.0)
 }
+
+// PushClip restricts drawing to the area filled by the path, intersected with the current clipping area.
+func (r *htmlCanvas) PushClip(path *canvas.Path, fillRule canvas.FillRule, m canvas.Matrix) {
+	r.ctx.Call("save")
+	r.styleStack = append(r.styleStack, r.style)
+
+	r.writePath(path.Transform(m))
+	if fillRule == canvas.EvenOdd {
+		r.ctx.Call("clip", "evenodd")
+	} else {
+		r.ctx.Call("clip", "nonzero")
+	}
+}
+
+// PopClip removes the last clipping path.
+func (r *htmlCanvas) PopClip() {
+	if len(r.styleStack) == 0 {
+		return
+	}
+	r.ctx.Call("restore")
+	r.style = r.styleStack[len(r.styleStack)-1]
+	r.styleStack = r.styleStack[:len(r.styleStack)-1]
+}
@@ -0,0 +1,156 @@
+package canvas
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Hyphenator finds the hyphenation points of words using Knuth-Liang hyphenation patterns, such as the TeX hyphenation patterns of a language. Hyphenation points are not placed before LeftMin or after RightMin characters of a word.
+type Hyphenator struct {
+	LeftMin, RightMin int
+
+	patterns   map[string][]uint8
+	exceptions map[string][]int
+	maxLength  int
+}
+
+// NewHyphenator returns a hyphenator for the given patterns (e.g. "hy3ph" or ".ach4") and exceptions (e.g. "ta-ble") in the TeX format.
+func NewHyphenator(patterns, exceptions []string) *Hyphenator {
+	h := &Hyphenator{
+		LeftMin:    2,
+		RightMin:   3,
+		patterns:   map[string][]uint8{},
+		exceptions: map[string][]int{},
+	}
+	for _, pattern := range patterns {
+		h.addPattern(pattern)
+	}
+	for _, exception := range exceptions {
+		h.addException(exception)
+	}
+	return h
+}
+
+// LoadHyphenator reads hyphenation patterns from a reader. It accepts whitespace separated patterns, or TeX files with \patterns{...} and \hyphenation{...} blocks. Comments start with % and continue until the end of the line.
+func LoadHyphenator(r io.Reader) (*Hyphenator, error) {
+	h := NewHyphenator(nil, nil)
+	exception := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '%'); i != -1 {
+			line = line[:i]
+		}
+		for _, field := range strings.Fields(line) {
+			if strings.HasPrefix(field, "\\patterns{") {
+				exception = false
+				field = field[len("\\patterns{"):]
+			} else if strings.HasPrefix(field, "\\hyphenation{") {
+				exception = true
+				field = field[len("\\hyphenation{"):]
+			}
+			field = strings.TrimSuffix(field, "}")
+			if field == "" || field[0] == '\\' {
+				continue
+			} else if exception {
+				h.addException(field)
+			} else {
+				h.addPattern(field)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// LoadHyphenatorFile reads hyphenation patterns from a file, see LoadHyphenator.
+func LoadHyphenatorFile(filename string) (*Hyphenator, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadHyphenator(f)
+}
+
+func (h *Hyphenator) addPattern(pattern string) {
+	letters := []rune{}
+	levels := []uint8{0}
+	for _, r := range pattern {
+		if '0' <= r && r <= '9' {
+			levels[len(levels)-1] = uint8(r - '0')
+		} else {
+			letters = append(letters, unicode.ToLower(r))
+			levels = append(levels, 0)
+		}
+	}
+	h.patterns[string(letters)] = levels
+	if h.maxLength < len(letters) {
+		h.maxLength = len(letters)
+	}
+}
+
+func (h *Hyphenator) addException(exception string) {
+	word := []rune{}
+	points := []int{}
+	for _, r := range exception {
+		if r == '-' {
+			points = append(points, len(word))
+		} else {
+			word = append(word, unicode.ToLower(r))
+		}
+	}
+	h.exceptions[string(word)] = points
+}
+
+// Hyphenate returns the byte positions in the word where it can be hyphenated.
+func (h *Hyphenator) Hyphenate(word string) []int {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) < h.LeftMin+h.RightMin || len(runes) != utf8.RuneCountInString(word) {
+		return []int{}
+	}
+
+	var points []int // positions in runes
+	if exception, ok := h.exceptions[string(runes)]; ok {
+		points = exception
+	} else {
+		w := append(append([]rune{'.'}, runes...), '.')
+		levels := make([]uint8, len(w)+1)
+		for i := range w {
+			for j := i + 1; j <= len(w) && j-i <= h.maxLength; j++ {
+				if pattern, ok := h.patterns[string(w[i:j])]; ok {
+					for k, level := range pattern {
+						if levels[i+k] < level {
+							levels[i+k] = level
+						}
+					}
+				}
+			}
+		}
+		for m := 1; m < len(runes); m++ {
+			if levels[m+1]%2 == 1 {
+				points = append(points, m)
+			}
+		}
+	}
+
+	positions := []int{}
+	m, pos := 0, 0
+	for _, point := range points {
+		for m < point {
+			_, size := utf8.DecodeRuneInString(word[pos:])
+			pos += size
+			m++
+		}
+		if 0 < point && h.LeftMin <= point && point <= len(runes)-h.RightMin {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
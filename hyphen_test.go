@@ -0,0 +1,40 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+// patterns from Liang's thesis, enough to hyphenate the words below
+var testPatterns = []string{"hy3ph", "he2n", "hena4", "hen5at", "1na", "n2at", "1tio", "2io", "o2n", "1ta", "4ble"}
+
+func TestHyphenator(t *testing.T) {
+	h := NewHyphenator(testPatterns, []string{"pro-ject"})
+	var tts = []struct {
+		word      string
+		positions []int
+	}{
+		{"hyphenation", []int{2, 6}},
+		{"Hyphenation", []int{2, 6}},
+		{"notation", []int{2, 4}},
+		{"project", []int{3}},
+		{"on", []int{}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.word, func(t *testing.T) {
+			test.T(t, h.Hyphenate(tt.word), tt.positions)
+		})
+	}
+
+	h.LeftMin = 3
+	test.T(t, h.Hyphenate("hyphenation"), []int{6})
+}
+
+func TestLoadHyphenator(t *testing.T) {
+	h, err := LoadHyphenator(strings.NewReader("% comment\n\\patterns{\nhy3ph he2n hena4\nhen5at 1na n2at 1tio 2io o2n\n}\n\\hyphenation{\npro-ject\n}\n"))
+	test.Error(t, err)
+	test.T(t, h.Hyphenate("hyphenation"), []int{2, 6})
+	test.T(t, h.Hyphenate("project"), []int{3})
+}
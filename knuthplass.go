@@ -0,0 +1,288 @@
+package canvas
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// KnuthPlass is the total-fit line breaking algorithm of Knuth and Plass as used by TeX. Instead of filling each line as much as possible, it chooses the line breaks of a paragraph that minimize the total demerits of its lines, which avoids rivers and loose lines in justified text. The badness of a line is 100 times the cube of the ratio by which its spaces are stretched, where a ratio of one corresponds to MaxWordSpacing and MaxSentenceSpacing.
+type KnuthPlass struct {
+	Tolerance       float64 // maximum badness of a line, lines are only looser if there is no other solution
+	Looseness       int     // number of lines to add (positive) or remove (negative) from the optimal solution, if possible
+	LinePenalty     float64 // demerits added to each line, higher values prefer fewer lines
+	HyphenPenalty   float64 // penalty for breaking at a hyphenation point
+	FlaggedDemerits float64 // demerits for two consecutive lines ending in a hyphen
+	FitnessDemerits float64 // demerits for adjacent lines whose spacing is very different
+}
+
+// NewKnuthPlass returns a Knuth-Plass line breaker with the default parameters of TeX.
+func NewKnuthPlass() *KnuthPlass {
+	return &KnuthPlass{
+		Tolerance:       200.0,
+		Looseness:       0,
+		LinePenalty:     10.0,
+		HyphenPenalty:   50.0,
+		FlaggedDemerits: 3000.0,
+		FitnessDemerits: 100.0,
+	}
+}
+
+type kpItemKind int
+
+const (
+	kpBox kpItemKind = iota
+	kpGlue
+	kpPenalty
+)
+
+// kpItem is a box, glue or penalty item of the paragraph. Glue with infinite stretch finishes a paragraph, and penalties with infinite negative penalty force a break.
+type kpItem struct {
+	kind         kpItemKind
+	width        float64
+	stretch      float64 // stretch of word and sentence spaces
+	glyphStretch float64 // stretch of glyph spacing, used for lines without spaces
+	infinite     bool    // glue with infinite stretch
+	penalty      float64
+	flagged      bool
+	pos          int // byte position in the text of the boundary
+}
+
+// kpSums are the cumulative sums of the items' widths and stretches.
+type kpSums struct {
+	width, stretch, glyphStretch float64
+	infinite                     int
+}
+
+type kpNode struct {
+	item     int // index of the breakpoint, or -1 for the start of the text
+	line     int // number of lines up to the breakpoint
+	fitness  int
+	demerits float64
+	sums     kpSums // sums up to the first item after the breakpoint that is kept
+	prev     *kpNode
+}
+
+// kpItems converts the text spans into boxes, glue and penalties.
+func (kp *KnuthPlass) kpItems(spans []TextSpan) []kpItem {
+	items := []kpItem{}
+	start := 0
+	for _, span := range spans {
+		xHeight := span.Face.Metrics().XHeight
+		box := func(s string) {
+			if s != "" {
+				glyphStretch := float64(utf8.RuneCountInString(s)) * MaxGlyphSpacing * xHeight
+				items = append(items, kpItem{kind: kpBox, width: span.Face.TextWidth(s), glyphStretch: glyphStretch})
+			}
+		}
+
+		prev := 0
+		for _, boundary := range span.boundaries[:len(span.boundaries)-1] {
+			box(span.Text[prev:boundary.pos])
+			pos := start + boundary.pos
+			switch boundary.kind {
+			case lineBoundary:
+				items = append(items, kpItem{kind: kpGlue, infinite: true, pos: pos})
+				items = append(items, kpItem{kind: kpPenalty, penalty: math.Inf(-1), pos: pos})
+			case sentenceBoundary, wordBoundary:
+				maxSpacing := MaxWordSpacing
+				if boundary.kind == sentenceBoundary {
+					maxSpacing = MaxSentenceSpacing
+				}
+				width := span.Face.TextWidth(span.Text[boundary.pos : boundary.pos+boundary.size])
+				items = append(items, kpItem{kind: kpGlue, width: width, stretch: maxSpacing * xHeight, pos: pos})
+			case opportunityBoundary:
+				items = append(items, kpItem{kind: kpPenalty, pos: pos})
+			case breakBoundary, hyphenBoundary:
+				items = append(items, kpItem{kind: kpPenalty, width: span.Face.TextWidth("-"), penalty: kp.HyphenPenalty, flagged: true, pos: pos})
+			}
+			prev = boundary.pos + boundary.size
+		}
+		box(span.Text[prev:])
+		start += len(span.Text)
+	}
+	items = append(items, kpItem{kind: kpGlue, infinite: true, pos: start})
+	items = append(items, kpItem{kind: kpPenalty, penalty: math.Inf(-1), pos: start})
+	return items
+}
+
+// lineBreaks returns the byte positions of the boundaries in the text of the spans where the lines should be broken. Forced breaks at newlines and at the end of the text are not included.
+func (kp *KnuthPlass) lineBreaks(spans []TextSpan, width, indent float64) []int {
+	items := kp.kpItems(spans)
+	node := kp.breakpoints(items, width, indent, kp.Tolerance, false)
+	if node == nil {
+		node = kp.breakpoints(items, width, indent, math.Inf(1), true)
+	}
+
+	breaks := []int{}
+	for ; node != nil && node.item != -1; node = node.prev {
+		if item := items[node.item]; item.kind != kpPenalty || !math.IsInf(item.penalty, -1) {
+			breaks = append(breaks, item.pos)
+		}
+	}
+	for i := 0; i < len(breaks)/2; i++ {
+		breaks[i], breaks[len(breaks)-1-i] = breaks[len(breaks)-1-i], breaks[i]
+	}
+	return breaks
+}
+
+// breakpoints returns the last node of the optimal sequence of breakpoints, or nil if there is no solution within the tolerance. In an emergency, overfull lines are allowed when there is no other solution.
+func (kp *KnuthPlass) breakpoints(items []kpItem, width, indent, tolerance float64, emergency bool) *kpNode {
+	sums := make([]kpSums, len(items)+1) // sums[i] are the sums of the items before i
+	for i, item := range items {
+		sums[i+1] = sums[i]
+		if item.kind != kpPenalty {
+			sums[i+1].width += item.width // the width of a penalty only counts when breaking there
+		}
+		if item.kind == kpGlue && item.infinite {
+			sums[i+1].infinite++
+		} else if item.kind == kpGlue {
+			sums[i+1].stretch += item.stretch
+		} else if item.kind == kpBox {
+			sums[i+1].glyphStretch += item.glyphStretch
+		}
+	}
+
+	// sumsAfter returns the sums up to the first box or forced break after breakpoint b, as the glue and penalties are discarded at the start of a line
+	sumsAfter := func(b int) kpSums {
+		i := b + 1
+		for i < len(items) && items[i].kind != kpBox && !(items[i].kind == kpPenalty && math.IsInf(items[i].penalty, -1)) {
+			i++
+		}
+		return sums[i]
+	}
+
+	active := []*kpNode{{item: -1, fitness: 1, sums: sumsAfter(-1)}}
+	for b, item := range items {
+		if item.kind == kpBox || item.kind == kpGlue && (item.infinite || b == 0 || items[b-1].kind != kpBox) {
+			continue // not a legal breakpoint
+		}
+		forced := item.kind == kpPenalty && math.IsInf(item.penalty, -1)
+
+		var candidates []*kpNode
+		var deactivated *kpNode
+		for i := 0; i < len(active); i++ {
+			a := active[i]
+			lineWidth := width
+			if a.line == 0 {
+				lineWidth -= indent
+			}
+
+			// adjustment ratio of the line from a to b
+			length := sums[b].width - a.sums.width
+			if item.kind == kpPenalty {
+				length += item.width
+			}
+			r := 0.0
+			if shortfall := lineWidth - length; Epsilon < shortfall {
+				if sums[b].infinite == a.sums.infinite {
+					stretch := sums[b].stretch - a.sums.stretch
+					if stretch <= 0.0 {
+						stretch = sums[b].glyphStretch - a.sums.glyphStretch
+					}
+					r = math.Inf(1)
+					if 0.0 < stretch {
+						r = shortfall / stretch
+					}
+				}
+			} else if shortfall < -Epsilon {
+				r = math.Inf(-1) // there is no shrink
+			}
+
+			if r < -1.0 || forced {
+				// remove active node, keep the closest one in case there are no other nodes left
+				if deactivated == nil || deactivated.item < a.item || deactivated.item == a.item && a.demerits < deactivated.demerits {
+					deactivated = a
+				}
+				active = append(active[:i], active[i+1:]...)
+				i--
+			}
+			if -1.0 <= r {
+				badness := math.Min(100.0*r*r*r, 10000.0)
+				if badness <= tolerance {
+					candidates = kp.addCandidate(candidates, items, a, b, r, badness)
+				}
+			}
+		}
+		if len(active) == 0 && len(candidates) == 0 && deactivated != nil {
+			if !emergency {
+				return nil
+			}
+			candidates = kp.addCandidate(candidates, items, deactivated, b, -1.0, 10000.0)
+		}
+
+		after := sumsAfter(b)
+		for _, candidate := range candidates {
+			candidate.sums = after
+			active = append(active, candidate)
+		}
+		if forced && b == len(items)-1 {
+			return kp.bestNode(candidates)
+		}
+	}
+	return nil
+}
+
+// addCandidate adds a new node for a line from a to breakpoint b to the candidates, keeping only the best node per fitness class, and also per line number if the looseness is not zero.
+func (kp *KnuthPlass) addCandidate(candidates []*kpNode, items []kpItem, a *kpNode, b int, r, badness float64) []*kpNode {
+	item := items[b]
+	fitness := 3 // very loose
+	if r < -0.5 {
+		fitness = 0 // tight
+	} else if r <= 0.5 {
+		fitness = 1 // decent
+	} else if r <= 1.0 {
+		fitness = 2 // loose
+	}
+
+	demerits := (kp.LinePenalty + badness) * (kp.LinePenalty + badness)
+	if item.kind == kpPenalty && 0.0 <= item.penalty {
+		demerits += item.penalty * item.penalty
+	} else if item.kind == kpPenalty && !math.IsInf(item.penalty, -1) {
+		demerits -= item.penalty * item.penalty
+	}
+	if item.kind == kpPenalty && item.flagged && a.item != -1 && items[a.item].kind == kpPenalty && items[a.item].flagged {
+		demerits += kp.FlaggedDemerits
+	}
+	if 1 < fitness-a.fitness || 1 < a.fitness-fitness {
+		demerits += kp.FitnessDemerits
+	}
+	demerits += a.demerits
+
+	for i, candidate := range candidates {
+		if candidate.fitness == fitness && (kp.Looseness == 0 || candidate.line == a.line+1) {
+			if demerits < candidate.demerits {
+				candidates[i] = &kpNode{b, a.line + 1, fitness, demerits, kpSums{}, a}
+			}
+			return candidates
+		}
+	}
+	return append(candidates, &kpNode{b, a.line + 1, fitness, demerits, kpSums{}, a})
+}
+
+// bestNode returns the final node with the fewest demerits, or the node closest to the desired number of lines when the looseness is not zero.
+func (kp *KnuthPlass) bestNode(nodes []*kpNode) *kpNode {
+	var best *kpNode
+	for _, node := range nodes {
+		if best == nil || node.demerits < best.demerits {
+			best = node
+		}
+	}
+	if best == nil || kp.Looseness == 0 {
+		return best
+	}
+
+	lines := best.line + kp.Looseness
+	for _, node := range nodes {
+		diff, bestDiff := node.line-lines, best.line-lines
+		if diff < 0 {
+			diff = -diff
+		}
+		if bestDiff < 0 {
+			bestDiff = -bestDiff
+		}
+		if diff < bestDiff || diff == bestDiff && node.demerits < best.demerits {
+			best = node
+		}
+	}
+	return best
+}
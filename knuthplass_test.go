@@ -0,0 +1,41 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestKnuthPlass(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	rt := NewRichText().Add(face, "the hyphenation notation is a nice notation for hyphenation")
+	rt.SetHyphenator(NewHyphenator(testPatterns, nil))
+	text := rt.ToText(160.0, 0.0, Justify, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"the hyphenation notation", "is a nice notation for hy-", "phenation"})
+
+	// the paragraph is broken as a whole, avoiding the hyphen
+	text = rt.SetLineBreaker(NewKnuthPlass()).ToText(160.0, 0.0, Justify, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"the hyphenation notation", "is a nice notation for", "hyphenation"})
+	test.Float(t, text.lines[0].spans[0].width, 160.0)
+	test.Float(t, text.lines[1].spans[0].width, 160.0)
+
+	// looseness adds lines when feasible
+	kp := NewKnuthPlass()
+	kp.Tolerance = 10000.0
+	kp.Looseness = 1
+	text = rt.SetLineBreaker(kp).ToText(160.0, 0.0, Justify, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 4)
+
+	// words that are wider than the line are overfull
+	rt = NewRichText().Add(face, "mm m mm mmmmm m mmmm mm").SetLineBreaker(NewKnuthPlass())
+	text = rt.ToText(40.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"mm m", "mm", "mmmmm", "m", "mmmm", "mm"})
+
+	// newlines force a break
+	rt = NewRichText().Add(face, "mm mm\nmm").SetLineBreaker(NewKnuthPlass())
+	text = rt.ToText(100.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"mm mm", "mm"})
+}
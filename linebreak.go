@@ -0,0 +1,225 @@
+package canvas
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// lineBreakClass is the line breaking class of a character, see UAX #14.
+type lineBreakClass int
+
+// see lineBreakClass, the complex context (SA), ambiguous (AI) and unknown (XX) classes are resolved to AL, conditional Japanese starters (CJ) to NS and Hangul syllables to ID
+const (
+	lbAL lineBreakClass = iota
+	lbBK
+	lbCR
+	lbLF
+	lbNL
+	lbSP
+	lbZW
+	lbZWJ
+	lbCM
+	lbWJ
+	lbGL
+	lbBA
+	lbBB
+	lbB2
+	lbHY
+	lbCL
+	lbCP
+	lbEX
+	lbIN
+	lbNS
+	lbOP
+	lbQU
+	lbIS
+	lbNU
+	lbPO
+	lbPR
+	lbSY
+	lbID
+	lbHL
+	lbRI
+)
+
+var lineBreakClasses = map[rune]lineBreakClass{
+	'\t': lbBA, '\n': lbLF, '\r': lbCR, '\v': lbBK, '\f': lbBK, '\u0085': lbNL, '\u2028': lbBK, '\u2029': lbBK,
+	' ': lbSP, '\u200B': lbZW, '\u200D': lbZWJ, '\u2060': lbWJ, '\uFEFF': lbWJ,
+	'\u00A0': lbGL, '\u2007': lbGL, '\u202F': lbGL, '\u034F': lbGL,
+	'\u00AD': lbBA, '\u2010': lbBA, '\u2012': lbBA, '\u2013': lbBA, '|': lbBA, '\u1680': lbBA, '\u3000': lbBA,
+	'-': lbHY, '—': lbB2,
+	'´': lbBB, 'ˈ': lbBB, 'ˌ': lbBB, '˟': lbBB,
+	')': lbCP, ']': lbCP,
+	'、': lbCL, '。': lbCL, '﹐': lbCL, '﹒': lbCL, '，': lbCL, '．': lbCL,
+	'!': lbEX, '?': lbEX, '؟': lbEX, '！': lbEX, '？': lbEX,
+	',': lbIS, '.': lbIS, ':': lbIS, ';': lbIS, ';': lbIS, '։': lbIS, '،': lbIS, '⁄': lbIS, '︐': lbIS, '︓': lbIS, '︔': lbIS,
+	'/': lbSY,
+	'%': lbPO, '¢': lbPO, '°': lbPO, '‰': lbPO, '‱': lbPO, '′': lbPO, '″': lbPO, '‴': lbPO, '℃': lbPO, '℉': lbPO,
+	'$': lbPR, '+': lbPR, '\\': lbPR, '£': lbPR, '¥': lbPR, '±': lbPR, '€': lbPR, '№': lbPR,
+	'․': lbIN, '‥': lbIN, '…': lbIN,
+	'"': lbQU, '\'': lbQU,
+	'‼': lbNS, '‽': lbNS, '⁇': lbNS, '⁈': lbNS, '⁉': lbNS, '々': lbNS, '〜': lbNS, '〻': lbNS, '〼': lbNS,
+	'゛': lbNS, '゜': lbNS, 'ゝ': lbNS, 'ゞ': lbNS, '゠': lbNS, '・': lbNS, 'ー': lbNS, 'ヽ': lbNS, 'ヾ': lbNS,
+	'：': lbNS, '；': lbNS, '･': lbNS, 'ｰ': lbNS,
+}
+
+// small kana are conditional Japanese starters
+var smallKana = map[rune]bool{
+	'ぁ': true, 'ぃ': true, 'ぅ': true, 'ぇ': true, 'ぉ': true, 'っ': true, 'ゃ': true, 'ゅ': true, 'ょ': true, 'ゎ': true, 'ゕ': true, 'ゖ': true,
+	'ァ': true, 'ィ': true, 'ゥ': true, 'ェ': true, 'ォ': true, 'ッ': true, 'ャ': true, 'ュ': true, 'ョ': true, 'ヮ': true, 'ヵ': true, 'ヶ': true,
+}
+
+func lineBreakClassOf(r rune) lineBreakClass {
+	if class, ok := lineBreakClasses[r]; ok {
+		return class
+	} else if smallKana[r] || 0x31F0 <= r && r <= 0x31FF {
+		return lbNS
+	} else if 0x1F1E6 <= r && r <= 0x1F1FF {
+		return lbRI
+	} else if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || 0x3040 <= r && r <= 0x30FF || 0xFF01 <= r && r <= 0xFF5A || 0x1F300 <= r && r <= 0x1FAFF {
+		if unicode.Is(unicode.Ps, r) {
+			return lbOP
+		} else if unicode.Is(unicode.Pe, r) {
+			return lbCL
+		}
+		return lbID
+	} else if unicode.Is(unicode.Nd, r) {
+		return lbNU
+	} else if unicode.Is(unicode.Hebrew, r) && unicode.IsLetter(r) {
+		return lbHL
+	} else if unicode.In(r, unicode.Mn, unicode.Mc, unicode.Me, unicode.Cc) {
+		return lbCM
+	} else if unicode.Is(unicode.Ps, r) {
+		return lbOP
+	} else if unicode.Is(unicode.Pe, r) {
+		return lbCL
+	} else if unicode.In(r, unicode.Pi, unicode.Pf) {
+		return lbQU
+	} else if unicode.Is(unicode.Zs, r) {
+		return lbBA
+	} else if unicode.Is(unicode.Sc, r) {
+		return lbPR
+	}
+	return lbAL
+}
+
+// lineBreaks returns the byte positions in the string where a line can be broken between two characters according to the Unicode Line Breaking Algorithm (UAX #14). Breaks after spaces and mandatory breaks are not returned, as these are handled by word and line boundaries.
+func lineBreaks(s string) []int {
+	breaks := []int{}
+	n := utf8.RuneCountInString(s)
+	if n < 2 {
+		return breaks
+	}
+
+	classes := make([]lineBreakClass, 0, n)
+	positions := make([]int, 0, n)
+	for i, r := range s {
+		classes = append(classes, lineBreakClassOf(r))
+		positions = append(positions, i)
+	}
+
+	// LB9 and LB10: combining marks take the class of their base character
+	resolved := make([]lineBreakClass, n)
+	for i, class := range classes {
+		resolved[i] = class
+		if class == lbCM || class == lbZWJ {
+			resolved[i] = lbAL
+			if 0 < i {
+				switch resolved[i-1] {
+				case lbBK, lbCR, lbLF, lbNL, lbSP, lbZW:
+				default:
+					resolved[i] = resolved[i-1]
+				}
+			}
+		}
+	}
+
+	beforeSpaces := resolved[0] // class of the last character that is not a space
+	regionalIndicators := 0
+	if resolved[0] == lbRI {
+		regionalIndicators = 1
+	}
+	for i := 1; i < n; i++ {
+		a, b := resolved[i-1], classes[i]
+		if a != lbSP {
+			beforeSpaces = a
+		}
+		if lineBreakAllowed(classes, resolved, i, beforeSpaces, regionalIndicators) && a != lbSP {
+			breaks = append(breaks, positions[i])
+		}
+		if resolved[i] == lbRI {
+			regionalIndicators++
+		} else if b != lbCM && b != lbZWJ {
+			regionalIndicators = 0
+		}
+	}
+	return breaks
+}
+
+// lineBreakAllowed returns true if there is a break opportunity before character i, see the rules LB4-LB31 of UAX #14.
+func lineBreakAllowed(classes, resolved []lineBreakClass, i int, beforeSpaces lineBreakClass, regionalIndicators int) bool {
+	a, b := resolved[i-1], classes[i]
+	switch {
+	case a == lbBK || a == lbCR || a == lbLF || a == lbNL: // LB4, LB5: mandatory
+		return false
+	case b == lbBK || b == lbCR || b == lbLF || b == lbNL || b == lbSP || b == lbZW: // LB6, LB7
+		return false
+	case beforeSpaces == lbZW: // LB8
+		return true
+	case classes[i-1] == lbZWJ: // LB8a
+		return false
+	case b == lbCM || b == lbZWJ: // LB9
+		return false
+	}
+	if b == lbCM || b == lbZWJ {
+		b = lbAL // LB10
+	}
+
+	switch {
+	case a == lbWJ || b == lbWJ: // LB11
+		return false
+	case a == lbGL: // LB12
+		return false
+	case b == lbGL && a != lbSP && a != lbBA && a != lbHY: // LB12a
+		return false
+	case b == lbCL || b == lbCP || b == lbEX || b == lbIS || b == lbSY: // LB13
+		return false
+	case beforeSpaces == lbOP: // LB14
+		return false
+	case beforeSpaces == lbQU && b == lbOP: // LB15
+		return false
+	case (beforeSpaces == lbCL || beforeSpaces == lbCP) && b == lbNS: // LB16
+		return false
+	case beforeSpaces == lbB2 && b == lbB2: // LB17
+		return false
+	case a == lbSP: // LB18
+		return true
+	case a == lbQU || b == lbQU: // LB19
+		return false
+	case b == lbBA || b == lbHY || b == lbNS || a == lbBB: // LB21
+		return false
+	case (a == lbHY || a == lbBA) && 1 < i && resolved[i-2] == lbHL: // LB21a
+		return false
+	case a == lbSY && b == lbHL: // LB21b
+		return false
+	case b == lbIN: // LB22
+		return false
+	case (a == lbAL || a == lbHL) && b == lbNU || a == lbNU && (b == lbAL || b == lbHL): // LB23
+		return false
+	case a == lbPR && b == lbID || a == lbID && b == lbPO: // LB23a
+		return false
+	case (a == lbPR || a == lbPO) && (b == lbAL || b == lbHL) || (a == lbAL || a == lbHL) && (b == lbPR || b == lbPO): // LB24
+		return false
+	case (a == lbCL || a == lbCP || a == lbNU) && (b == lbPO || b == lbPR) || (a == lbPO || a == lbPR) && b == lbOP || (a == lbPO || a == lbPR || a == lbHY || a == lbIS || a == lbNU || a == lbSY) && b == lbNU: // LB25
+		return false
+	case (a == lbAL || a == lbHL) && (b == lbAL || b == lbHL): // LB28
+		return false
+	case a == lbIS && (b == lbAL || b == lbHL): // LB29
+		return false
+	case (a == lbAL || a == lbHL || a == lbNU) && b == lbOP || a == lbCP && (b == lbAL || b == lbHL || b == lbNU): // LB30
+		return false
+	case a == lbRI && b == lbRI && regionalIndicators%2 == 1: // LB30a
+		return false
+	}
+	return true // LB31
+}
@@ -0,0 +1,31 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestLineBreaks(t *testing.T) {
+	var tts = []struct {
+		s      string
+		breaks []int
+	}{
+		{"abc def", []int{}},
+		{"http://example.com/a/b", []int{7, 19, 21}},
+		{"well-known", []int{5}},
+		{"(abc) 12%", []int{}},
+		{"漢字かな", []int{3, 6, 9}},
+		{"漢字、かな。", []int{3, 9, 12}},
+		{"ちょっと", []int{9}},
+		{"a\u200bb", []int{4}},
+		{"a b", []int{}},
+		{"$12.50-$20", []int{7}},
+		{"éf", []int{}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			test.T(t, lineBreaks(tt.s), tt.breaks)
+		})
+	}
+}
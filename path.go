@@ -649,6 +649,151 @@ func (p *Path) Length() float64 {
 	return d
 }
 
+// Area returns the signed area enclosed by the path, which is positive for counter clockwise and negative for clockwise subpaths. Subpaths that are not closed are implicitly closed, and areas enclosed multiple times are counted multiple times. It is calculated exactly using Green's theorem for all segment types.
+func (p *Path) Area() float64 {
+	area := 0.0
+	p.closedSegments(func(s *segment) {
+		area += s.area()
+	})
+	return area
+}
+
+// Centroid returns the center of mass of the area enclosed by the path, see Area. It returns the center of the bounding box if the path encloses no area.
+func (p *Path) Centroid() Point {
+	area := 0.0
+	var moment Point
+	p.closedSegments(func(s *segment) {
+		area += s.area()
+		moment = moment.Add(s.moment())
+	})
+	if Equal(area, 0.0) {
+		r := p.Bounds()
+		return Point{r.X + r.W/2.0, r.Y + r.H/2.0}
+	}
+	return moment.Div(area)
+}
+
+// ConvexHull returns the smallest convex polygon that contains the path as a closed counter clockwise path. Curves are flattened first, see Flatten. If the path is a single point or a straight line, it returns an open path of one or two points respectively.
+func (p *Path) ConvexHull() *Path {
+	hull := convexHull(p.Flatten().Coords())
+	q := &Path{}
+	for i, pt := range hull {
+		if i == 0 {
+			q.MoveTo(pt.X, pt.Y)
+		} else {
+			q.LineTo(pt.X, pt.Y)
+		}
+	}
+	if 2 < len(hull) {
+		q.Close()
+	}
+	return q
+}
+
+// OrientedBounds returns the minimum-area rectangle that contains the path, given as a rectangle in the coordinate system rotated counter clockwise by the returned angle in degrees in [0,90). That is, the bounding box is r.ToPath().Transform(Identity.Rotate(rot)). Curves are flattened first, see Flatten.
+func (p *Path) OrientedBounds() (Rect, float64) {
+	hull := convexHull(p.Flatten().Coords())
+	if len(hull) < 3 {
+		if len(hull) == 2 {
+			dir := hull[1].Sub(hull[0])
+			rot := dir.Angle()
+			origin := hull[0].Rot(-rot, Point{})
+			return normalizedOrientedBounds(Rect{origin.X, origin.Y, dir.Length(), 0.0}, rot*180.0/math.Pi)
+		} else if len(hull) == 1 {
+			return Rect{hull[0].X, hull[0].Y, 0.0, 0.0}, 0.0
+		}
+		return Rect{}, 0.0
+	}
+
+	best, bestRot, bestArea := Rect{}, 0.0, math.Inf(1)
+	for i := range hull {
+		// rotating calipers: the minimum-area rectangle has a side collinear with an edge of the hull
+		dir := hull[(i+1)%len(hull)].Sub(hull[i]).Norm(1.0)
+		normal := dir.Rot90CCW()
+		xmin, xmax := math.Inf(1), math.Inf(-1)
+		ymin, ymax := math.Inf(1), math.Inf(-1)
+		for _, pt := range hull {
+			x, y := pt.Dot(dir), pt.Dot(normal)
+			xmin, xmax = math.Min(xmin, x), math.Max(xmax, x)
+			ymin, ymax = math.Min(ymin, y), math.Max(ymax, y)
+		}
+		if area := (xmax - xmin) * (ymax - ymin); area < bestArea-Epsilon {
+			best, bestRot, bestArea = Rect{xmin, ymin, xmax - xmin, ymax - ymin}, dir.Angle(), area
+		}
+	}
+	return normalizedOrientedBounds(best, bestRot*180.0/math.Pi)
+}
+
+// normalizedOrientedBounds rotates the coordinate system of an oriented bounding box by multiples of 90 degrees so that its angle is in [0,90).
+func normalizedOrientedBounds(r Rect, rot float64) (Rect, float64) {
+	for rot < -Epsilon {
+		r = Rect{r.Y, -r.X - r.W, r.H, r.W}
+		rot += 90.0
+	}
+	for 90.0-Epsilon <= rot {
+		r = Rect{-r.Y - r.H, r.X, r.H, r.W}
+		rot -= 90.0
+	}
+	return r, math.Max(rot, 0.0)
+}
+
+// convexHull returns the convex hull of the points in counter clockwise order using the monotone chain algorithm, collinear points are removed.
+func convexHull(pts []Point) []Point {
+	pts = append([]Point{}, pts...)
+	sort.Slice(pts, func(i, j int) bool {
+		return pts[i].X < pts[j].X || pts[i].X == pts[j].X && pts[i].Y < pts[j].Y
+	})
+	unique := pts[:0]
+	for i, pt := range pts {
+		if i == 0 || !pt.Equals(unique[len(unique)-1]) {
+			unique = append(unique, pt)
+		}
+	}
+	pts = unique
+	if len(pts) < 3 {
+		return pts
+	}
+
+	hull := make([]Point, 0, 2*len(pts))
+	for _, pass := range []int{0, 1} {
+		start := len(hull)
+		for k := range pts {
+			pt := pts[k]
+			if pass == 1 {
+				pt = pts[len(pts)-1-k]
+			}
+			for start+1 < len(hull) && hull[len(hull)-1].Sub(hull[len(hull)-2]).PerpDot(pt.Sub(hull[len(hull)-2])) <= Epsilon {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, pt)
+		}
+		hull = hull[:len(hull)-1] // the last point is the first point of the other chain
+	}
+	return hull
+}
+
+// closedSegments calls f for each segment of the path, and for an implicit closing line segment for each subpath that is not closed.
+func (p *Path) closedSegments(f func(*segment)) {
+	var start, subpathStart Point
+	for k, i := 0, 0; i < len(p.d); k++ {
+		cmd := p.d[i]
+		s := newPathSegment(p, i, k, start)
+		i += cmdLen(cmd)
+		if cmd == moveToCmd {
+			if 0 < k && p.d[i-cmdLen(cmd)-1] != closeCmd {
+				f(&segment{cmd: lineToCmd, start: start, end: subpathStart})
+			}
+			subpathStart = s.end
+		} else {
+			f(s)
+		}
+		start = s.end
+	}
+	if 0 < len(p.d) && p.d[len(p.d)-1] != closeCmd {
+		f(&segment{cmd: lineToCmd, start: start, end: subpathStart})
+	}
+}
+
 // Transform transform the path by the given transformation matrix and returns a new path.
 func (p *Path) Transform(m Matrix) *Path {
 	p = p.Copy()
@@ -1,6 +1,9 @@
 package canvas
 
-import "math"
+import (
+	"math"
+	"sort"
+)
 
 // intersection between two line segments
 // see http://www.cs.swan.ac.uk/~cssimon/line_intersection.html
@@ -62,3 +65,1051 @@ func intersectionCircleCircle(c0 Point, r0 float64, c1 Point, r1 float64) (Point
 	i2 := Point{c1.Y - c0.Y, c0.X - c1.X}.Mul(c)
 	return i0.Add(i1).Add(i2), i0.Add(i1).Sub(i2), true
 }
+
+////////////////////////////////////////////////////////////////
+
+// segment is a single path segment (a line, quadratic or cubic Bézier, or elliptical arc) used by the intersection and boolean algorithms. Elliptical arcs are kept in their center parametrization so that they can be evaluated and split exactly. All segments are parametrized by t in [0,1].
+type segment struct {
+	cmd        float64
+	start, end Point
+	cp1, cp2   Point // control points for quadratic (cp1 only) and cubic Béziers
+
+	rx, ry, phi, cx, cy, theta0, theta1 float64 // elliptical arcs
+
+	orig   *segment // original path segment
+	next   *segment // next segment in the subpath of the original path, if any
+	index  int      // index of the command in the path, negative for implicit closing segments
+	t0, t1 float64  // parameter range within the original path segment, t0 > t1 if reversed
+}
+
+// pathSegments returns the segments of path p, it skips MoveTo commands and converts Close commands to line segments. When closeOpen is set, subpaths that are not closed will be closed implicitly by an additional line segment. Segments of zero length are skipped.
+func pathSegments(p *Path, closeOpen bool) []*segment {
+	segs := []*segment{}
+	first := 0 // index of the first segment of the subpath
+	add := func(s *segment) {
+		if first < len(segs) {
+			segs[len(segs)-1].next = s
+		}
+		segs = append(segs, s)
+	}
+	addLine := func(start, end Point, index int) {
+		if intersectionEpsilon <= end.Sub(start).Length() {
+			add(&segment{cmd: lineToCmd, start: start, end: end, index: index})
+		}
+	}
+	closeSubpath := func() {
+		if first < len(segs) {
+			segs[len(segs)-1].next = segs[first]
+		}
+	}
+
+	k := 0 // command index
+	nImplicit := 0
+	var start, end, subpathStart Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		switch cmd {
+		case moveToCmd:
+			if closeOpen && 0 < i && p.d[i-1] != closeCmd {
+				nImplicit++
+				addLine(start, subpathStart, -nImplicit)
+				closeSubpath()
+			}
+			end = Point{p.d[i+1], p.d[i+2]}
+			subpathStart = end
+			first = len(segs)
+		case lineToCmd:
+			end = Point{p.d[i+1], p.d[i+2]}
+			addLine(start, end, k)
+		case closeCmd:
+			end = Point{p.d[i+1], p.d[i+2]}
+			addLine(start, end, k)
+			closeSubpath()
+		case quadToCmd:
+			end = Point{p.d[i+3], p.d[i+4]}
+			add(&segment{cmd: quadToCmd, start: start, cp1: Point{p.d[i+1], p.d[i+2]}, end: end, index: k})
+		case cubeToCmd:
+			end = Point{p.d[i+5], p.d[i+6]}
+			add(&segment{cmd: cubeToCmd, start: start, cp1: Point{p.d[i+1], p.d[i+2]}, cp2: Point{p.d[i+3], p.d[i+4]}, end: end, index: k})
+		case arcToCmd:
+			rx, ry, phi := p.d[i+1], p.d[i+2], p.d[i+3]
+			large, sweep := toArcFlags(p.d[i+4])
+			end = Point{p.d[i+5], p.d[i+6]}
+			cx, cy, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
+			add(&segment{cmd: arcToCmd, start: start, end: end, rx: rx, ry: ry, phi: phi, cx: cx, cy: cy, theta0: theta0, theta1: theta1, index: k})
+		}
+		start = end
+		i += cmdLen(cmd)
+		k++
+	}
+	if closeOpen && 0 < len(p.d) && p.d[len(p.d)-1] != closeCmd {
+		nImplicit++
+		addLine(start, subpathStart, -nImplicit)
+		closeSubpath()
+	}
+
+	for _, s := range segs {
+		s.orig = s
+		s.t1 = 1.0
+	}
+	return segs
+}
+
+func (s *segment) pos(t float64) Point {
+	switch s.cmd {
+	case lineToCmd:
+		return s.start.Interpolate(s.end, t)
+	case quadToCmd:
+		return quadraticBezierPos(s.start, s.cp1, s.end, t)
+	case cubeToCmd:
+		return cubicBezierPos(s.start, s.cp1, s.cp2, s.end, t)
+	}
+	return ellipsePos(s.rx, s.ry, s.phi, s.cx, s.cy, s.theta0+t*(s.theta1-s.theta0))
+}
+
+func (s *segment) deriv(t float64) Point {
+	switch s.cmd {
+	case lineToCmd:
+		return s.end.Sub(s.start)
+	case quadToCmd:
+		return quadraticBezierDeriv(s.start, s.cp1, s.end, t)
+	case cubeToCmd:
+		return cubicBezierDeriv(s.start, s.cp1, s.cp2, s.end, t)
+	}
+	return ellipseDeriv(s.rx, s.ry, s.phi, true, s.theta0+t*(s.theta1-s.theta0)).Mul(s.theta1 - s.theta0)
+}
+
+// direction returns the (unnormalized) direction of the segment at t, falling back to the direction of the chord when the derivative vanishes such as at cusps.
+func (s *segment) direction(t float64) Point {
+	d := s.deriv(t)
+	if d.Length() < intersectionEpsilon {
+		if t < 0.5 {
+			d = s.pos(math.Min(t+0.01, 1.0)).Sub(s.pos(t))
+		} else {
+			d = s.pos(t).Sub(s.pos(math.Max(t-0.01, 0.0)))
+		}
+	}
+	return d
+}
+
+// sub returns the part of the segment between t0 and t1, with 0 <= t0 < t1 <= 1.
+func (s *segment) sub(t0, t1 float64) *segment {
+	r := *s
+	r.t0 = s.t0 + t0*(s.t1-s.t0)
+	r.t1 = s.t0 + t1*(s.t1-s.t0)
+	switch s.cmd {
+	case lineToCmd:
+		r.start = s.pos(t0)
+		r.end = s.pos(t1)
+	case quadToCmd:
+		p0, p1, p2 := s.start, s.cp1, s.end
+		if t1 < 1.0 {
+			p0, p1, p2, _, _, _ = quadraticBezierSplit(p0, p1, p2, t1)
+		}
+		if 0.0 < t0 {
+			_, _, _, p0, p1, p2 = quadraticBezierSplit(p0, p1, p2, t0/t1)
+		}
+		r.start, r.cp1, r.end = p0, p1, p2
+	case cubeToCmd:
+		p0, p1, p2, p3 := s.start, s.cp1, s.cp2, s.end
+		if t1 < 1.0 {
+			p0, p1, p2, p3, _, _, _, _ = cubicBezierSplit(p0, p1, p2, p3, t1)
+		}
+		if 0.0 < t0 {
+			_, _, _, _, p0, p1, p2, p3 = cubicBezierSplit(p0, p1, p2, p3, t0/t1)
+		}
+		r.start, r.cp1, r.cp2, r.end = p0, p1, p2, p3
+	case arcToCmd:
+		r.theta0 = s.theta0 + t0*(s.theta1-s.theta0)
+		r.theta1 = s.theta0 + t1*(s.theta1-s.theta0)
+		r.start = s.pos(t0)
+		r.end = s.pos(t1)
+	}
+	if t0 == 0.0 {
+		r.start = s.start
+	}
+	if t1 == 1.0 {
+		r.end = s.end
+	}
+	return &r
+}
+
+// reverse returns the segment in the opposite direction.
+func (s *segment) reverse() *segment {
+	r := *s
+	r.start, r.end = s.end, s.start
+	r.t0, r.t1 = s.t1, s.t0
+	switch s.cmd {
+	case cubeToCmd:
+		r.cp1, r.cp2 = s.cp2, s.cp1
+	case arcToCmd:
+		r.theta0, r.theta1 = s.theta1, s.theta0
+	}
+	return &r
+}
+
+// bounds returns the bounding box of a segment that is monotone in both x and y.
+func (s *segment) bounds() Rect {
+	return Rect{math.Min(s.start.X, s.end.X), math.Min(s.start.Y, s.end.Y), math.Abs(s.end.X - s.start.X), math.Abs(s.end.Y - s.start.Y)}
+}
+
+// extrema returns the parameters t in (0,1) where the segment changes direction in either the x or y axis, in increasing order.
+func (s *segment) extrema() []float64 {
+	ts := []float64{}
+	add := func(t float64) {
+		if !math.IsNaN(t) && tEpsilon < t && t < 1.0-tEpsilon {
+			ts = append(ts, t)
+		}
+	}
+	switch s.cmd {
+	case quadToCmd:
+		if denom := s.start.X - 2.0*s.cp1.X + s.end.X; denom != 0.0 {
+			add((s.start.X - s.cp1.X) / denom)
+		}
+		if denom := s.start.Y - 2.0*s.cp1.Y + s.end.Y; denom != 0.0 {
+			add((s.start.Y - s.cp1.Y) / denom)
+		}
+	case cubeToCmd:
+		a := -s.start.X + 3.0*s.cp1.X - 3.0*s.cp2.X + s.end.X
+		b := 2.0*s.start.X - 4.0*s.cp1.X + 2.0*s.cp2.X
+		c := -s.start.X + s.cp1.X
+		t1, t2 := solveQuadraticFormula(a, b, c)
+		add(t1)
+		add(t2)
+
+		a = -s.start.Y + 3.0*s.cp1.Y - 3.0*s.cp2.Y + s.end.Y
+		b = 2.0*s.start.Y - 4.0*s.cp1.Y + 2.0*s.cp2.Y
+		c = -s.start.Y + s.cp1.Y
+		t1, t2 = solveQuadraticFormula(a, b, c)
+		add(t1)
+		add(t2)
+	case arcToCmd:
+		// angles where the derivative of x(theta) or y(theta) vanishes, see ellipsePos
+		sinphi, cosphi := math.Sincos(s.phi)
+		thetaRight := math.Atan2(-s.ry*sinphi, s.rx*cosphi)
+		thetaTop := math.Atan2(s.ry*cosphi, s.rx*sinphi)
+		dtheta := s.theta1 - s.theta0
+		for _, theta := range []float64{thetaRight, thetaTop} {
+			for k := -3; k <= 3; k++ {
+				add((theta + float64(k)*math.Pi - s.theta0) / dtheta)
+			}
+		}
+	}
+	sort.Float64s(ts)
+	return ts
+}
+
+// monotoneSegments splits all segments into parts that are monotone in both x and y.
+func monotoneSegments(segs []*segment) []*segment {
+	monos := []*segment{}
+	for _, s := range segs {
+		t0 := 0.0
+		for _, t := range s.extrema() {
+			if tEpsilon < t-t0 {
+				monos = append(monos, s.sub(t0, t))
+				t0 = t
+			}
+		}
+		monos = append(monos, s.sub(t0, 1.0))
+	}
+	return monos
+}
+
+// appendTo appends the segment to path p, p must have its current position at the start of the segment.
+func (s *segment) appendTo(p *Path) {
+	switch s.cmd {
+	case lineToCmd:
+		p.LineTo(s.end.X, s.end.Y)
+	case quadToCmd:
+		p.QuadTo(s.cp1.X, s.cp1.Y, s.end.X, s.end.Y)
+	case cubeToCmd:
+		p.CubeTo(s.cp1.X, s.cp1.Y, s.cp2.X, s.cp2.Y, s.end.X, s.end.Y)
+	case arcToCmd:
+		dtheta := s.theta1 - s.theta0
+		p.ArcTo(s.rx, s.ry, s.phi*180.0/math.Pi, math.Pi < math.Abs(dtheta), 0.0 < dtheta, s.end.X, s.end.Y)
+	}
+}
+
+////////////////////////////////////////////////////////////////
+
+// intersectionEpsilon is the distance below which two points are considered to be coincident when calculating intersections.
+const intersectionEpsilon = 1e-9
+
+// tEpsilon is the parametric distance below which a parameter is considered to be at the start or end of a segment.
+const tEpsilon = 1e-9
+
+// intersection subdivision stops when the segment parts are smaller than this size, after which Newton's method is used.
+const intersectionSubdivisionSize = 1e-6
+const intersectionSubdivisionDepth = 64
+
+type segmentIntersection struct {
+	i, j    int     // indices of the segments
+	ti, tj  float64 // parameters along the segments
+	pos     Point
+	overlap bool // start or end of a part where the segments are coincident
+}
+
+// findIntersections returns the intersections between all pairs of segments, which must be monotone in both x and y. It uses a sweep line along the x-axis to avoid testing segments whose bounding boxes do not overlap. Intersections at the start or end of a segment have their parameter snapped to exactly 0 or 1, and the intersection point snapped to that end point.
+func findIntersections(segs []*segment) []segmentIntersection {
+	bounds := make([]Rect, len(segs))
+	order := make([]int, len(segs))
+	for i, s := range segs {
+		bounds[i] = s.bounds()
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return bounds[order[a]].X < bounds[order[b]].X
+	})
+
+	zs := []segmentIntersection{}
+	active := []int{}
+	for _, i := range order {
+		x := bounds[i].X - intersectionEpsilon
+		k := 0
+		for _, j := range active {
+			if x <= bounds[j].X+bounds[j].W {
+				active[k] = j
+				k++
+			}
+		}
+		active = active[:k]
+
+		for _, j := range active {
+			if bounds[i].Y-intersectionEpsilon <= bounds[j].Y+bounds[j].H && bounds[j].Y-intersectionEpsilon <= bounds[i].Y+bounds[i].H {
+				a, b := i, j
+				if b < a {
+					a, b = b, a
+				}
+				params, overlap := intersectMonotoneSegments(segs[a], segs[b])
+				for _, z := range params {
+					zs = append(zs, segmentIntersection{a, b, z[0], z[1], snapIntersection(segs[a], segs[b], z[0], z[1]), overlap})
+				}
+			}
+		}
+		active = append(active, i)
+	}
+	sort.SliceStable(zs, func(a, b int) bool {
+		return zs[a].i < zs[b].i || zs[a].i == zs[b].i && (zs[a].j < zs[b].j || zs[a].j == zs[b].j && zs[a].ti < zs[b].ti)
+	})
+	return zs
+}
+
+// snapIntersection returns the intersection point, preferring the exact end points of either segment.
+func snapIntersection(a, b *segment, ta, tb float64) Point {
+	if ta == 0.0 {
+		return a.start
+	} else if ta == 1.0 {
+		return a.end
+	} else if tb == 0.0 {
+		return b.start
+	} else if tb == 1.0 {
+		return b.end
+	}
+	return a.pos(ta)
+}
+
+// intersectMonotoneSegments returns the parameters (ta,tb) of the intersections between a and b, which are both monotone in x and y. Parameters close to the start or end of the segments are snapped to 0 or 1. For coincident (overlapping) segments it returns the start and end of the overlap, and true.
+func intersectMonotoneSegments(a, b *segment) ([][2]float64, bool) {
+	var zs [][2]float64
+	coincident, overlap := overlapMonotoneSegments(a, b)
+	if coincident {
+		zs = overlap
+	} else if a.cmd == lineToCmd && b.cmd == lineToCmd {
+		zs = intersectionLineLineParams(a.start, a.end, b.start, b.end)
+	} else if a.cmd == lineToCmd {
+		zs = intersectionLineSegment(a.start, a.end, b)
+	} else if b.cmd == lineToCmd {
+		zs = intersectionLineSegment(b.start, b.end, a)
+		for i := range zs {
+			zs[i][0], zs[i][1] = zs[i][1], zs[i][0]
+		}
+	} else if a.cmd == arcToCmd && b.cmd == arcToCmd && a.rx == a.ry && b.rx == b.ry {
+		zs = intersectionCircularArcs(a, b)
+	} else {
+		intersectionSubdivision(a, b, 0.0, 1.0, 0.0, 1.0, a.start, a.end, b.start, b.end, 0, &zs)
+	}
+
+	// snap to end points and remove duplicates
+	for i := range zs {
+		zs[i][0] = snapParam(a, zs[i][0])
+		zs[i][1] = snapParam(b, zs[i][1])
+	}
+	sort.Slice(zs, func(i, j int) bool { return zs[i][0] < zs[j][0] })
+	k := 0
+	for i := range zs {
+		if 0 < k && a.pos(zs[i][0]).Sub(a.pos(zs[k-1][0])).Length() < intersectionSubdivisionSize && b.pos(zs[i][1]).Sub(b.pos(zs[k-1][1])).Length() < intersectionSubdivisionSize {
+			// prefer end points
+			if zs[i][0] == 0.0 || zs[i][0] == 1.0 || zs[i][1] == 0.0 || zs[i][1] == 1.0 {
+				zs[k-1] = zs[i]
+			}
+			continue
+		}
+		zs[k] = zs[i]
+		k++
+	}
+	return zs[:k], coincident
+}
+
+func snapParam(s *segment, t float64) float64 {
+	t = math.Max(0.0, math.Min(1.0, t))
+	pos := s.pos(t)
+	if t < tEpsilon || pos.Sub(s.start).Length() < intersectionSubdivisionSize {
+		return 0.0
+	} else if 1.0-tEpsilon < t || pos.Sub(s.end).Length() < intersectionSubdivisionSize {
+		return 1.0
+	}
+	return t
+}
+
+// intersectionLineLineParams returns the parameters of the intersection of two line segments, or the start and end of the overlap if they are collinear.
+func intersectionLineLineParams(a0, a1, b0, b1 Point) [][2]float64 {
+	da := a1.Sub(a0)
+	db := b1.Sub(b0)
+	div := da.PerpDot(db)
+	if math.Abs(div) < intersectionEpsilon*da.Length()*db.Length() {
+		return nil // parallel, collinear lines are handled by overlapMonotoneSegments
+	}
+
+	ta := db.PerpDot(a0.Sub(b0)) / div
+	tb := da.PerpDot(a0.Sub(b0)) / div
+	if -tEpsilon <= ta && ta <= 1.0+tEpsilon && -tEpsilon <= tb && tb <= 1.0+tEpsilon {
+		return [][2]float64{{ta, tb}}
+	}
+	return nil
+}
+
+// intersectionLineSegment returns the parameters of the intersections between a line segment and a Bézier or arc segment.
+func intersectionLineSegment(l0, l1 Point, s *segment) [][2]float64 {
+	d := l1.Sub(l0)
+	dd := d.Dot(d)
+	ts := []float64{}
+	switch s.cmd {
+	case quadToCmd, cubeToCmd:
+		// signed distances of the control points to the line, the roots of the Bézier in this space are the intersections
+		n := d.Rot90CCW()
+		e0 := s.start.Sub(l0).Dot(n)
+		e1 := s.cp1.Sub(l0).Dot(n)
+		if s.cmd == quadToCmd {
+			e2 := s.end.Sub(l0).Dot(n)
+			t1, t2 := solveQuadraticFormula(e0-2.0*e1+e2, 2.0*e1-2.0*e0, e0)
+			ts = append(ts, t1, t2)
+		} else {
+			e2 := s.cp2.Sub(l0).Dot(n)
+			e3 := s.end.Sub(l0).Dot(n)
+			t1, t2, t3 := solveCubicFormula(-e0+3.0*e1-3.0*e2+e3, 3.0*e0-6.0*e1+3.0*e2, -3.0*e0+3.0*e1, e0)
+			ts = append(ts, t1, t2, t3)
+		}
+	case arcToCmd:
+		// transform the line to the space where the ellipse is a unit circle
+		sinphi, cosphi := math.Sincos(-s.phi)
+		toUnit := func(p Point) Point {
+			p = p.Sub(Point{s.cx, s.cy})
+			return Point{(cosphi*p.X - sinphi*p.Y) / s.rx, (sinphi*p.X + cosphi*p.Y) / s.ry}
+		}
+		a := toUnit(l0)
+		da := toUnit(l1).Sub(a)
+
+		// find the closest point of the line to the center, this handles tangent lines robustly
+		um := -a.Dot(da) / da.Dot(da)
+		h := a.Add(da.Mul(um)).Length()
+		if 1.0+intersectionEpsilon < h {
+			break
+		}
+		du := math.Sqrt(math.Max(0.0, 1.0-h*h)) / da.Length()
+		us := []float64{um - du, um + du}
+		if du*da.Length() < intersectionEpsilon {
+			us = []float64{um}
+		}
+		for _, u := range us {
+			if -tEpsilon <= u && u <= 1.0+tEpsilon {
+				ts = append(ts, s.arcParam(a.Add(da.Mul(u)).Angle()))
+			}
+		}
+	}
+
+	zs := [][2]float64{}
+	for _, t := range ts {
+		if !math.IsNaN(t) && -tEpsilon <= t && t <= 1.0+tEpsilon {
+			t = math.Max(0.0, math.Min(1.0, t))
+			tl := s.pos(t).Sub(l0).Dot(d) / dd
+			if -tEpsilon <= tl && tl <= 1.0+tEpsilon {
+				zs = append(zs, [2]float64{math.Max(0.0, math.Min(1.0, tl)), t})
+			}
+		}
+	}
+	return zs
+}
+
+// intersectionCircularArcs returns the parameters of the intersections between two arcs of circles, tangent circles are handled robustly.
+func intersectionCircularArcs(a, b *segment) [][2]float64 {
+	ca, cb := Point{a.cx, a.cy}, Point{b.cx, b.cy}
+	d := cb.Sub(ca)
+	R := d.Length()
+	if R < intersectionEpsilon || a.rx+b.rx < R-intersectionEpsilon || R+intersectionEpsilon < math.Abs(a.rx-b.rx) {
+		return nil // concentric circles are handled by overlapMonotoneSegments
+	}
+
+	// distance along the line between the centers and perpendicular distance to the intersections
+	x := (R*R + a.rx*a.rx - b.rx*b.rx) / (2.0 * R)
+	y := math.Sqrt(math.Max(0.0, a.rx*a.rx-x*x))
+	m := ca.Add(d.Mul(x / R))
+	n := d.Rot90CCW().Mul(y / R)
+	ps := []Point{m.Add(n)}
+	if intersectionEpsilon <= y {
+		ps = append(ps, m.Sub(n))
+	}
+
+	zs := [][2]float64{}
+	for _, p := range ps {
+		ta := a.arcParam(p.Sub(ca).Angle() - a.phi)
+		tb := b.arcParam(p.Sub(cb).Angle() - b.phi)
+		if -tEpsilon <= ta && ta <= 1.0+tEpsilon && -tEpsilon <= tb && tb <= 1.0+tEpsilon {
+			zs = append(zs, [2]float64{ta, tb})
+		}
+	}
+	return zs
+}
+
+// arcParam returns the parameter t along the arc for angle theta (before stretching and rotating the ellipse), it can return values outside [0,1] if theta is not on the arc.
+func (s *segment) arcParam(theta float64) float64 {
+	dtheta := s.theta1 - s.theta0
+	t := angleNorm(theta-s.theta0) / dtheta
+	if dtheta < 0.0 {
+		t = (angleNorm(theta-s.theta0) - 2.0*math.Pi) / dtheta
+	}
+	if 1.0 < t && math.Abs(2.0*math.Pi/dtheta)-t < tEpsilon*100.0 {
+		t = 0.0 // theta is just before theta0
+	}
+	return t
+}
+
+// paramAt returns the parameter of point p on segment s, which must be monotone in x and y, and whether the point lies on the segment.
+func (s *segment) paramAt(p Point) (float64, bool) {
+	b := s.bounds()
+	if p.X < b.X-intersectionEpsilon || b.X+b.W+intersectionEpsilon < p.X || p.Y < b.Y-intersectionEpsilon || b.Y+b.H+intersectionEpsilon < p.Y {
+		return 0.0, false
+	}
+
+	var t float64
+	if s.cmd == lineToCmd {
+		d := s.end.Sub(s.start)
+		t = p.Sub(s.start).Dot(d) / d.Dot(d)
+	} else if b.H < b.W {
+		t = s.bisect(func(q Point) float64 { return q.X }, p.X)
+	} else {
+		t = s.bisect(func(q Point) float64 { return q.Y }, p.Y)
+	}
+	t = math.Max(0.0, math.Min(1.0, t))
+	return t, s.pos(t).Sub(p).Length() < intersectionEpsilon*100.0
+}
+
+// bisect returns the parameter t for which f(pos(t)) equals v, with f monotone along the segment.
+func (s *segment) bisect(f func(Point) float64, v float64) float64 {
+	tmin, tmax := 0.0, 1.0
+	increasing := f(s.start) < f(s.end)
+	for i := 0; i < 64 && tEpsilon*1e-3 < tmax-tmin; i++ {
+		t := (tmin + tmax) / 2.0
+		if (f(s.pos(t)) < v) == increasing {
+			tmin = t
+		} else {
+			tmax = t
+		}
+	}
+	return (tmin + tmax) / 2.0
+}
+
+// overlapMonotoneSegments returns true if a and b (monotone in x and y) are coincident along a part of their length, and returns the parameters of the start and end points of the overlap.
+func overlapMonotoneSegments(a, b *segment) (bool, [][2]float64) {
+	if a.cmd == lineToCmd && b.cmd == lineToCmd {
+		da := a.end.Sub(a.start)
+		db := b.end.Sub(b.start)
+		if intersectionEpsilon*da.Length()*db.Length() <= math.Abs(da.PerpDot(db)) || intersectionEpsilon*da.Length() <= math.Abs(da.PerpDot(b.start.Sub(a.start))) {
+			return false, nil
+		}
+	} else if a.cmd == lineToCmd || b.cmd == lineToCmd {
+		return false, nil // Béziers are never straight and arcs never have zero radius
+	} else {
+		for _, t := range []float64{0.5, 0.25, 0.75} {
+			if _, ok := b.paramAt(a.pos(t)); !ok {
+				return false, nil
+			}
+		}
+	}
+
+	zs := [][2]float64{}
+	if tb, ok := b.paramAt(a.start); ok {
+		zs = append(zs, [2]float64{0.0, tb})
+	}
+	if tb, ok := b.paramAt(a.end); ok {
+		zs = append(zs, [2]float64{1.0, tb})
+	}
+	if ta, ok := a.paramAt(b.start); ok {
+		zs = append(zs, [2]float64{ta, 0.0})
+	}
+	if ta, ok := a.paramAt(b.end); ok {
+		zs = append(zs, [2]float64{ta, 1.0})
+	}
+	if len(zs) < 2 {
+		return false, nil
+	}
+	return true, zs
+}
+
+// intersectionSubdivision finds intersections between two curves that are monotone in x and y by recursively subdividing them, which makes the bounding box of each part equal to the box spanned by its end points. When the parts are small enough, Newton's method is used to refine the intersection.
+func intersectionSubdivision(a, b *segment, ta0, ta1, tb0, tb1 float64, pa0, pa1, pb0, pb1 Point, depth int, zs *[][2]float64) {
+	if math.Max(pa0.X, pa1.X)+intersectionEpsilon < math.Min(pb0.X, pb1.X) || math.Max(pb0.X, pb1.X)+intersectionEpsilon < math.Min(pa0.X, pa1.X) ||
+		math.Max(pa0.Y, pa1.Y)+intersectionEpsilon < math.Min(pb0.Y, pb1.Y) || math.Max(pb0.Y, pb1.Y)+intersectionEpsilon < math.Min(pa0.Y, pa1.Y) {
+		return
+	}
+
+	sizeA := math.Max(math.Abs(pa1.X-pa0.X), math.Abs(pa1.Y-pa0.Y))
+	sizeB := math.Max(math.Abs(pb1.X-pb0.X), math.Abs(pb1.Y-pb0.Y))
+	if intersectionSubdivisionDepth <= depth || sizeA < intersectionSubdivisionSize && sizeB < intersectionSubdivisionSize {
+		// the parts may be close but not intersect, as happens for curves that nearly touch
+		ta, tb := intersectionNewton(a, b, (ta0+ta1)/2.0, (tb0+tb1)/2.0)
+		if a.pos(ta).Sub(b.pos(tb)).Length() < intersectionEpsilon*100.0 {
+			*zs = append(*zs, [2]float64{ta, tb})
+		}
+		return
+	}
+
+	if sizeB <= sizeA {
+		tm := (ta0 + ta1) / 2.0
+		pm := a.pos(tm)
+		intersectionSubdivision(a, b, ta0, tm, tb0, tb1, pa0, pm, pb0, pb1, depth+1, zs)
+		intersectionSubdivision(a, b, tm, ta1, tb0, tb1, pm, pa1, pb0, pb1, depth+1, zs)
+	} else {
+		tm := (tb0 + tb1) / 2.0
+		pm := b.pos(tm)
+		intersectionSubdivision(a, b, ta0, ta1, tb0, tm, pa0, pa1, pb0, pm, depth+1, zs)
+		intersectionSubdivision(a, b, ta0, ta1, tm, tb1, pa0, pa1, pm, pb1, depth+1, zs)
+	}
+}
+
+// intersectionNewton refines the intersection of a and b near (ta,tb) using Newton's method.
+func intersectionNewton(a, b *segment, ta, tb float64) (float64, float64) {
+	for i := 0; i < 16; i++ {
+		r := b.pos(tb).Sub(a.pos(ta))
+		if r.Length() < intersectionEpsilon*1e-3 {
+			break
+		}
+		u, v := a.deriv(ta), b.deriv(tb).Neg()
+		det := u.PerpDot(v)
+		if det == 0.0 {
+			break
+		}
+		ta = math.Max(0.0, math.Min(1.0, ta+r.PerpDot(v)/det))
+		tb = math.Max(0.0, math.Min(1.0, tb+u.PerpDot(r)/det))
+	}
+	return ta, tb
+}
+
+// windings returns the winding number of point p with respect to the segments, which must be monotone in x and y and form closed subpaths. Counter clockwise windings are counted positively.
+func windings(segs []*segment, p Point) int {
+	n := 0
+	for _, s := range segs {
+		if (p.Y < s.start.Y) == (p.Y < s.end.Y) {
+			continue
+		}
+		if math.Max(s.start.X, s.end.X) < p.X {
+			continue
+		} else if p.X < math.Min(s.start.X, s.end.X) {
+			// crossing is certainly to the right of p
+		} else {
+			var x float64
+			if s.cmd == lineToCmd {
+				x = s.start.X + (s.end.X-s.start.X)*(p.Y-s.start.Y)/(s.end.Y-s.start.Y)
+			} else {
+				x = s.pos(s.bisect(func(q Point) float64 { return q.Y }, p.Y)).X
+			}
+			if x < p.X {
+				continue
+			}
+		}
+		if s.start.Y < s.end.Y {
+			n++
+		} else {
+			n--
+		}
+	}
+	return n
+}
+
+////////////////////////////////////////////////////////////////
+
+// Intersection is an intersection between two paths, or between a path and itself. Segments are indexed by the path commands where the first MoveTo command has index zero, so that the segment at index i ends at p.Coords()[i]. The parameters TA and TB run from 0 to 1 along the segment and correspond to the Bézier curve parameter for quadratic and cubic Béziers, and are linear in the angle for elliptical arcs.
+type Intersection struct {
+	Pos                Point   // position of the intersection
+	SegmentA, SegmentB int     // segment index in the first and second path
+	TA, TB             float64 // parameter along the segment of the first and second path
+}
+
+// Intersections returns all intersections between p and q, sorted by segment index and parameter along p. Intersections at the vertex between two segments are reported for the latter segment with a parameter of zero. Where p and q overlap, the start and end points of the overlap are returned.
+func (p *Path) Intersections(q *Path) []Intersection {
+	ps := monotoneSegments(pathSegments(p, false))
+	qs := monotoneSegments(pathSegments(q, false))
+	segs := append(append([]*segment{}, ps...), qs...)
+
+	zs := []Intersection{}
+	for _, z := range mergeOverlaps(segs, findIntersections(segs)) {
+		if z.i < len(ps) && len(ps) <= z.j {
+			segA, ta := segs[z.i].origParam(z.ti)
+			segB, tb := segs[z.j].origParam(z.tj)
+			zs = append(zs, Intersection{z.pos, segA, segB, ta, tb})
+		}
+	}
+	return uniqueIntersections(zs)
+}
+
+// SelfIntersections returns all intersections of p with itself, sorted by segment index and parameter. Each intersection is reported once with SegmentA <= SegmentB, and TA < TB if both are on the same segment. Consecutive segments touching at their shared vertex are not considered to intersect. Where p overlaps itself, the start and end points of the overlap are returned.
+func (p *Path) SelfIntersections() []Intersection {
+	segs := monotoneSegments(pathSegments(p, false))
+
+	zs := []Intersection{}
+	for _, z := range mergeOverlaps(segs, findIntersections(segs)) {
+		segA, ta := segs[z.i].origParam(z.ti)
+		segB, tb := segs[z.j].origParam(z.tj)
+		if segB < segA || segA == segB && tb < ta {
+			segA, segB, ta, tb = segB, segA, tb, ta
+		}
+		if segA != segB || tEpsilon < tb-ta {
+			zs = append(zs, Intersection{z.pos, segA, segB, ta, tb})
+		}
+	}
+	return uniqueIntersections(zs)
+}
+
+// mergeOverlaps removes the intersections inside overlaps, which occur where an overlap continues from one pair of monotone segments into the adjacent pair, so that only the start and end points of the overlap remain.
+func mergeOverlaps(segs []*segment, zs []segmentIntersection) []segmentIntersection {
+	adjacent := func(i, j int) bool {
+		if j < i {
+			i, j = j, i
+		}
+		return j == i+1 && segs[i].end.Equals(segs[j].start)
+	}
+
+	interior := make([]bool, len(zs))
+	for a, za := range zs {
+		if !za.overlap {
+			continue
+		}
+		for b := a + 1; b < len(zs); b++ {
+			zb := zs[b]
+			if !zb.overlap || intersectionSubdivisionSize <= za.pos.Sub(zb.pos).Length() {
+				continue
+			} else if !(adjacent(za.i, zb.i) && adjacent(za.j, zb.j)) && !(adjacent(za.i, zb.j) && adjacent(za.j, zb.i)) {
+				continue
+			}
+
+			// remove all intersections between the four segments at this point, including where they merely touch
+			in := func(k int) bool {
+				return k == za.i || k == za.j || k == zb.i || k == zb.j
+			}
+			for c, zc := range zs {
+				if in(zc.i) && in(zc.j) && zc.pos.Sub(za.pos).Length() < intersectionSubdivisionSize {
+					interior[c] = true
+				}
+			}
+		}
+	}
+
+	k := 0
+	for i, z := range zs {
+		if !interior[i] {
+			zs[k] = z
+			k++
+		}
+	}
+	return zs[:k]
+}
+
+// origParam returns the segment index and parameter in the original path. Parameters at the end of a segment are moved to the start of the next segment, if any.
+func (s *segment) origParam(t float64) (int, float64) {
+	t = s.t0 + t*(s.t1-s.t0)
+	if 1.0-tEpsilon < t {
+		if s.next != nil {
+			return s.next.index, 0.0
+		}
+		return s.index, 1.0
+	} else if t < tEpsilon {
+		return s.index, 0.0
+	}
+	return s.index, t
+}
+
+// uniqueIntersections sorts the intersections and removes duplicates, which occur at the vertices between segments.
+func uniqueIntersections(zs []Intersection) []Intersection {
+	sort.SliceStable(zs, func(i, j int) bool {
+		if zs[i].SegmentA != zs[j].SegmentA {
+			return zs[i].SegmentA < zs[j].SegmentA
+		} else if zs[i].TA != zs[j].TA {
+			return zs[i].TA < zs[j].TA
+		} else if zs[i].SegmentB != zs[j].SegmentB {
+			return zs[i].SegmentB < zs[j].SegmentB
+		}
+		return zs[i].TB < zs[j].TB
+	})
+
+	k := 0
+	for i, z := range zs {
+		if 0 < i && z.SegmentA == zs[k-1].SegmentA && z.SegmentB == zs[k-1].SegmentB && z.Pos.Sub(zs[k-1].Pos).Length() < intersectionSubdivisionSize {
+			continue
+		}
+		zs[k] = z
+		k++
+	}
+	return zs[:k]
+}
+
+////////////////////////////////////////////////////////////////
+
+// BooleanOp is a boolean operation between two paths.
+type BooleanOp int
+
+// see BooleanOp
+const (
+	AndOp BooleanOp = iota // intersection
+	OrOp                   // union
+	NotOp                  // difference
+	XorOp                  // exclusive or
+)
+
+// And returns the intersection of the areas filled by p and q as a new path, using the NonZero fill rule. See Boolean.
+func (p *Path) And(q *Path) *Path {
+	return p.Boolean(AndOp, q, NonZero)
+}
+
+// Or returns the union of the areas filled by p and q as a new path, using the NonZero fill rule. See Boolean.
+func (p *Path) Or(q *Path) *Path {
+	return p.Boolean(OrOp, q, NonZero)
+}
+
+// Not returns the area filled by p that is not filled by q (ie. the difference p - q) as a new path, using the NonZero fill rule. See Boolean.
+func (p *Path) Not(q *Path) *Path {
+	return p.Boolean(NotOp, q, NonZero)
+}
+
+// Xor returns the area filled by either p or q but not by both as a new path, using the NonZero fill rule. See Boolean.
+func (p *Path) Xor(q *Path) *Path {
+	return p.Boolean(XorOp, q, NonZero)
+}
+
+// Settle returns a path that fills the same area as p for the given fill rule, but without self-intersections or overlapping subpaths. Filled areas run counter clockwise and holes run clockwise, see Boolean. This cleans up outlines such as those returned by Stroke, which overlap themselves at joins and tight curves.
+func (p *Path) Settle(fillRule FillRule) *Path {
+	return p.Boolean(OrOp, &Path{}, fillRule)
+}
+
+// Boolean returns the result of the boolean operation op between the areas filled by p and q as a new path. The fill rule determines which areas of p and q are filled, subpaths that are not closed are closed implicitly. The resulting path has no self-intersections or overlapping subpaths, filled areas run counter clockwise and holes run clockwise, so that it fills the same area for any fill rule. Lines, Béziers and arcs are kept as such and are split at their intersections.
+func (p *Path) Boolean(op BooleanOp, q *Path, fillRule FillRule) *Path {
+	ps := monotoneSegments(pathSegments(p, true))
+	qs := monotoneSegments(pathSegments(q, true))
+	segs := append(append([]*segment{}, ps...), qs...)
+	if len(segs) == 0 {
+		return &Path{}
+	}
+
+	// split all segments at their intersections
+	type split struct {
+		t   float64
+		pos Point
+	}
+	splits := make([][]split, len(segs))
+	zs := findIntersections(segs)
+	mergeIntersections(segs, zs)
+	for _, z := range zs {
+		if 0.0 < z.ti && z.ti < 1.0 {
+			splits[z.i] = append(splits[z.i], split{z.ti, z.pos})
+		}
+		if 0.0 < z.tj && z.tj < 1.0 {
+			splits[z.j] = append(splits[z.j], split{z.tj, z.pos})
+		}
+	}
+
+	inside := func(n int) bool {
+		if fillRule == NonZero {
+			return n != 0
+		}
+		return n%2 != 0
+	}
+	filled := func(x Point) bool {
+		a, b := inside(windings(ps, x)), inside(windings(qs, x))
+		switch op {
+		case AndOp:
+			return a && b
+		case OrOp:
+			return a || b
+		case NotOp:
+			return a && !b
+		}
+		return a != b
+	}
+
+	// keep the parts that separate a filled from an unfilled area, oriented such that the filled area is to the left
+	pieces := []*segment{}
+	for i, s := range segs {
+		sort.Slice(splits[i], func(a, b int) bool { return splits[i][a].t < splits[i][b].t })
+
+		t0, start := 0.0, s.start
+		for k := 0; k <= len(splits[i]); k++ {
+			t1, end := 1.0, s.end
+			if k < len(splits[i]) {
+				t1, end = splits[i][k].t, splits[i][k].pos
+				if end.Sub(start).Length() < intersectionSubdivisionSize || end.Sub(s.end).Length() < intersectionSubdivisionSize {
+					continue
+				}
+			}
+			piece := s.sub(t0, t1)
+			piece.start, piece.end = start, end
+			t0, start = t1, end
+
+			mid := piece.pos(0.5)
+			d := math.Min(intersectionSubdivisionSize, piece.end.Sub(piece.start).Length()/100.0)
+			n := piece.direction(0.5).Rot90CCW()
+			n = n.Mul(d / n.Length())
+			left, right := filled(mid.Add(n)), filled(mid.Sub(n))
+			if left && !right {
+				pieces = appendUniquePiece(pieces, piece)
+			} else if !left && right {
+				pieces = appendUniquePiece(pieces, piece.reverse())
+			}
+		}
+	}
+	return chainPieces(pieces)
+}
+
+// mergeIntersections moves intersection points that are very close to each other or to the end point of a segment onto the same point. This happens at points where more than two segments meet, and prevents tiny pieces that cannot be chained.
+func mergeIntersections(segs []*segment, zs []segmentIntersection) {
+	// end points come first so that they are preferred as the representative point
+	points := make([]Point, 0, 2*len(segs)+len(zs))
+	for _, s := range segs {
+		points = append(points, s.start, s.end)
+	}
+	for _, z := range zs {
+		points = append(points, z.pos)
+	}
+
+	parent := make([]int, len(points))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return points[order[a]].X < points[order[b]].X })
+	for a := range order {
+		for b := a + 1; b < len(order) && points[order[b]].X-points[order[a]].X < intersectionSubdivisionSize; b++ {
+			if points[order[a]].Sub(points[order[b]]).Length() < intersectionSubdivisionSize {
+				i, j := find(order[a]), find(order[b])
+				if j < i {
+					i, j = j, i
+				}
+				parent[j] = i
+			}
+		}
+	}
+	for k := range zs {
+		zs[k].pos = points[find(2*len(segs)+k)]
+	}
+}
+
+// appendUniquePiece appends a piece unless an equal piece exists already, which happens for coincident segments.
+func appendUniquePiece(pieces []*segment, piece *segment) []*segment {
+	mid := piece.pos(0.5)
+	for _, other := range pieces {
+		if other.start.Sub(piece.start).Length() < intersectionSubdivisionSize && other.end.Sub(piece.end).Length() < intersectionSubdivisionSize && other.pos(0.5).Sub(mid).Length() < intersectionSubdivisionSize {
+			return pieces
+		}
+	}
+	return append(pieces, piece)
+}
+
+// chainPieces connects the pieces into closed subpaths. When several pieces leave from the same point, it takes the leftmost turn so that touching subpaths remain separated.
+func chainPieces(pieces []*segment) *Path {
+	used := make([]bool, len(pieces))
+	p := &Path{}
+	for i := range pieces {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		loop := []*segment{pieces[i]}
+		for {
+			last := loop[len(loop)-1]
+			if last.end.Sub(loop[0].start).Length() < intersectionSubdivisionSize && 1 < len(loop) {
+				break
+			}
+
+			next := -1
+			angle := 0.0
+			dir := last.direction(1.0)
+			for j, piece := range pieces {
+				if !used[j] && piece.start.Sub(last.end).Length() < intersectionSubdivisionSize {
+					if a := dir.AngleBetween(piece.direction(0.0)); next == -1 || angle < a {
+						next = j
+						angle = a
+					}
+				}
+			}
+			if next == -1 {
+				break // should not happen, close the path anyways
+			}
+			used[next] = true
+			loop = append(loop, pieces[next])
+		}
+		appendLoop(p, loop)
+	}
+	return p
+}
+
+// appendLoop appends a closed loop of pieces to p, merging consecutive pieces that originate from the same path segment.
+func appendLoop(p *Path, loop []*segment) {
+	mergeable := func(a, b *segment) bool {
+		return a.orig == b.orig && a.cmd == b.cmd && math.Abs(a.t1-b.t0) < tEpsilon*1e-3 && (a.t0 < a.t1) == (b.t0 < b.t1)
+	}
+
+	// start at a piece that cannot be merged with its predecessor
+	first := 0
+	for i := range loop {
+		if !mergeable(loop[(i+len(loop)-1)%len(loop)], loop[i]) {
+			first = i
+			break
+		}
+	}
+	loop = append(loop[first:], loop[:first]...)
+
+	start := loop[0].start
+	p.MoveTo(start.X, start.Y)
+	for i := 0; i < len(loop); {
+		a := loop[i]
+		j := i + 1
+		for j < len(loop) && mergeable(loop[j-1], loop[j]) {
+			j++
+		}
+		s := a
+		if i+1 < j {
+			b := loop[j-1]
+			if a.t0 < a.t1 {
+				s = a.orig.sub(a.t0, b.t1)
+			} else {
+				s = a.orig.sub(b.t1, a.t0).reverse()
+			}
+			s.start = a.start
+			s.end = b.end
+		}
+		if j == len(loop) {
+			s.end = start
+		}
+		s.appendTo(p)
+		i = j
+	}
+	p.Close()
+}
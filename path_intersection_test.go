@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/dtrenin7/test"
@@ -69,3 +70,188 @@ func TestIntersectionCircleCircle(t *testing.T) {
 		})
 	}
 }
+
+func TestPathBoolean(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p, q     string
+		op       BooleanOp
+		fillRule FillRule
+		r        string
+	}{
+		// overlapping rectangles
+		{"L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", AndOp, NonZero, "M10 5L10 10L5 10L5 5z"},
+		{"L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", OrOp, NonZero, "M0 0L10 0L10 5L15 5L15 15L5 15L5 10L0 10z"},
+		{"L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", NotOp, NonZero, "M0 0L10 0L10 5L5 5L5 10L0 10z"},
+		{"L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", XorOp, NonZero, "M0 0L10 0L10 5L5 5L5 10L0 10zM10 10L10 5L15 5L15 15L5 15L5 10z"},
+
+		// orientation of q does not matter
+		{"L10 0L10 10L0 10z", "M5 5L5 15L15 15L15 5z", AndOp, NonZero, "M10 5L10 10L5 10L5 5z"},
+
+		// touching and coincident rectangles
+		{"L10 0L10 10L0 10z", "M10 0L20 0L20 10L10 10z", OrOp, NonZero, "M0 0L20 0L20 10L0 10z"},
+		{"L10 0L10 10L0 10z", "M10 10L20 10L20 20L10 20z", OrOp, NonZero, "M0 0L10 0L10 10L0 10zM10 10L20 10L20 20L10 20z"},
+		{"L10 0L10 10L0 10z", "L10 0L10 10L0 10z", OrOp, NonZero, "M0 0L10 0L10 10L0 10z"},
+		{"L10 0L10 10L0 10z", "L10 0L10 10L0 10z", NotOp, NonZero, ""},
+
+		// nested rectangles
+		{"L10 0L10 10L0 10z", "M2 2L8 2L8 8L2 8z", NotOp, NonZero, "M0 0L10 0L10 10L0 10zM8 2L2 2L2 8L8 8z"},
+		{"L10 0L10 10L0 10z", "M2 2L8 2L8 8L2 8z", AndOp, NonZero, "M2 2L8 2L8 8L2 8z"},
+
+		// fill rules and self-intersections
+		{"L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", "", OrOp, NonZero, "M0 0L10 0L10 10L0 10z"},
+		{"L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", "", OrOp, EvenOdd, "M0 0L10 0L10 10L0 10zM8 2L2 2L2 8L8 8z"},
+		{"L10 10L10 0L0 10z", "", OrOp, NonZero, "M0 0L5 5L0 10zM10 10L5 5L10 0z"},
+		{"L10 0L10 10", "", OrOp, NonZero, "M0 0L10 0L10 10z"},
+
+		// curves
+		{"M10 5A5 5 0 0 1 0 5A5 5 0 0 1 10 5z", "M15 5A5 5 0 0 1 5 5A5 5 0 0 1 15 5z", AndOp, NonZero, "M10 5A5 5 0 0 1 7.5 9.330127018922193A5 5 0 0 1 5 5A5 5 0 0 1 7.5 0.669872981077807A5 5 0 0 1 10 5z"},
+		{"M10 5A5 5 0 0 1 0 5A5 5 0 0 1 10 5z", "M15 5A5 5 0 0 1 5 5A5 5 0 0 1 15 5z", OrOp, NonZero, "M7.5 9.330127018922193A5 5 0 0 1 0 5A5 5 0 0 1 7.5 0.669872981077807A5 5 0 0 1 15 5A5 5 0 0 1 7.5 9.330127018922193z"},
+		{"M0 0C10 0 10 10 0 10z", "M5 -5L6 -5L6 15L5 15z", AndOp, NonZero, "M5 1.150998205402495C5.375672217930862 1.3678926615519416 5.709005551264195 1.6092314841209592 6 1.869504831500295L6 8.130495168499706C5.709005551264196 8.39076851587904 5.3756722179308625 8.632107338448058 5 8.849001794597505z"},
+		{"M0 0Q10 5 0 10z", "M0 5L10 5L10 10L0 10z", NotOp, NonZero, "M0 0Q5 2.5 5 5L0 5z"},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.p, " ", tt.q), func(t *testing.T) {
+			r := MustParseSVG(tt.p).Boolean(tt.op, MustParseSVG(tt.q), tt.fillRule)
+			test.T(t, r, MustParseSVG(tt.r))
+		})
+	}
+}
+
+func TestPathBooleanInterior(t *testing.T) {
+	Epsilon = 1e-6
+	p := Circle(5.0).Translate(5.0, 5.0)
+	q := Ellipse(8.0, 2.0).Transform(Identity.Rotate(30.0)).Translate(8.0, 6.0)
+	for _, op := range []BooleanOp{AndOp, OrOp, NotOp, XorOp} {
+		r := p.Boolean(op, q, NonZero)
+		for _, x := range []Point{{5.0, 4.0}, {1.0, 4.5}, {8.0, 6.0}, {14.0, 9.0}, {4.0, 9.0}, {20.0, 20.0}} {
+			inP, inQ := p.Interior(x.X, x.Y, NonZero), q.Interior(x.X, x.Y, NonZero)
+			var inside bool
+			switch op {
+			case AndOp:
+				inside = inP && inQ
+			case OrOp:
+				inside = inP || inQ
+			case NotOp:
+				inside = inP && !inQ
+			case XorOp:
+				inside = inP != inQ
+			}
+			test.That(t, r.Interior(x.X, x.Y, NonZero) == inside, "operation", op, "at", x)
+			test.That(t, r.Interior(x.X, x.Y, EvenOdd) == inside, "operation", op, "at", x)
+		}
+	}
+}
+
+func TestPathSettle(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p        string
+		fillRule FillRule
+		r        string
+	}{
+		{"L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", NonZero, "M0 0L10 0L10 10L0 10z"},
+		{"L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", EvenOdd, "M0 0L10 0L10 10L0 10zM8 2L2 2L2 8L8 8z"},
+		{"L10 10L10 0L0 10z", NonZero, "M0 0L5 5L0 10zM10 10L5 5L10 0z"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.p).Settle(tt.fillRule), MustParseSVG(tt.r))
+		})
+	}
+
+	// strokes overlap themselves at inner joins
+	stroke := MustParseSVG("M0 0L10 0L10 1").Stroke(4.0, ButtCap, MiterJoin)
+	test.That(t, 0 < len(stroke.SelfIntersections()))
+	settled := stroke.Settle(NonZero)
+	test.T(t, len(settled.SelfIntersections()), 0)
+	test.T(t, settled, MustParseSVG("M0 -2L12 -2L12 1L9 1L10 2L0 2z"))
+}
+
+func TestPathIntersections(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p, q string
+		zs   []Intersection
+	}{
+		{"L10 10", "M0 10L10 0", []Intersection{{Point{5.0, 5.0}, 1, 1, 0.5, 0.5}}},
+		{"L10 10", "M0 10L4 6", []Intersection{}},
+		{"L10 0L10 10L0 10z", "M5 5L15 5L15 15L5 15z", []Intersection{
+			{Point{10.0, 5.0}, 2, 1, 0.5, 0.5},
+			{Point{5.0, 10.0}, 3, 4, 0.5, 0.5},
+		}},
+		{"L10 0L10 10L0 10z", "M-5 -5L15 15", []Intersection{
+			{Point{0.0, 0.0}, 1, 1, 0.0, 0.25},
+			{Point{10.0, 10.0}, 3, 1, 0.0, 0.75},
+		}},
+		{"L10 0L10 10L0 10z", "M10 -5L10 15", []Intersection{
+			{Point{10.0, 0.0}, 2, 1, 0.0, 0.25},
+			{Point{10.0, 10.0}, 3, 1, 0.0, 0.75},
+		}},
+		{"M5 0A5 5 0 0 1 -5 0A5 5 0 0 1 5 0z", "M-10 0L10 0", []Intersection{
+			{Point{5.0, 0.0}, 1, 1, 0.0, 0.75},
+			{Point{-5.0, 0.0}, 2, 1, 0.0, 0.25},
+		}},
+		{"M5 0A5 5 0 0 1 -5 0", "M0 -10L0 10", []Intersection{{Point{0.0, 5.0}, 1, 1, 0.5, 0.75}}},
+		{"Q10 0 10 10", "M0 5L10 5", []Intersection{{Point{10.0*math.Sqrt(2.0) - 5.0, 5.0}, 1, 1, 0.5 * math.Sqrt(2.0), math.Sqrt(2.0) - 0.5}}},
+		{"M0 0C10 10 10 0 0 10", "M5 -5L5 15", []Intersection{
+			{Point{5.0, 4.037749551350624}, 1, 1, 0.5 - 0.5/math.Sqrt(3.0), 0.45188747756753117},
+			{Point{5.0, 5.962250448649376}, 1, 1, 0.5 + 0.5/math.Sqrt(3.0), 0.5481125224324689},
+		}},
+		{"M0 0C10 0 10 10 0 10", "M0 0C10 0 10 10 0 10", []Intersection{
+			{Point{0.0, 0.0}, 1, 1, 0.0, 0.0},
+			{Point{0.0, 10.0}, 1, 1, 1.0, 1.0},
+		}},
+		{"M0 0C10 0 10 10 0 10", "M0 10C10 10 10 0 0 0", []Intersection{
+			{Point{0.0, 0.0}, 1, 1, 0.0, 1.0},
+			{Point{0.0, 10.0}, 1, 1, 1.0, 0.0},
+		}},
+		{"L10 0L10 10", "M5 0L10 0L10 5", []Intersection{
+			{Point{5.0, 0.0}, 1, 1, 0.5, 0.0},
+			{Point{10.0, 5.0}, 2, 2, 0.5, 1.0},
+		}},
+	}
+	for _, tt := range tts {
+		t.Run(fmt.Sprint(tt.p, " ", tt.q), func(t *testing.T) {
+			zs := MustParseSVG(tt.p).Intersections(MustParseSVG(tt.q))
+			test.T(t, len(zs), len(tt.zs))
+			for i := range zs {
+				if i < len(tt.zs) {
+					test.T(t, zs[i].Pos, tt.zs[i].Pos)
+					test.T(t, zs[i].SegmentA, tt.zs[i].SegmentA)
+					test.T(t, zs[i].SegmentB, tt.zs[i].SegmentB)
+					test.Float(t, zs[i].TA, tt.zs[i].TA)
+					test.Float(t, zs[i].TB, tt.zs[i].TB)
+				}
+			}
+		})
+	}
+}
+
+func TestPathSelfIntersections(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p  string
+		zs []Intersection
+	}{
+		{"L10 0L10 10L0 10z", []Intersection{}},
+		{"L10 10L10 0L0 10z", []Intersection{{Point{5.0, 5.0}, 1, 3, 0.5, 0.5}}},
+		{"L10 0L5 0L5 5", []Intersection{{Point{5.0, 0.0}, 1, 3, 0.5, 0.0}}},
+		{"M0 0C20 10 -10 10 10 0", []Intersection{{Point{5.0, 3.0}, 1, 1, 0.5 - math.Sqrt(0.15), 0.5 + math.Sqrt(0.15)}}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			zs := MustParseSVG(tt.p).SelfIntersections()
+			test.T(t, len(zs), len(tt.zs))
+			for i := range zs {
+				if i < len(tt.zs) {
+					test.T(t, zs[i].Pos, tt.zs[i].Pos)
+					test.T(t, zs[i].SegmentA, tt.zs[i].SegmentA)
+					test.T(t, zs[i].SegmentB, tt.zs[i].SegmentB)
+					test.Float(t, zs[i].TA, tt.zs[i].TA)
+					test.Float(t, zs[i].TB, tt.zs[i].TB)
+				}
+			}
+		})
+	}
+}
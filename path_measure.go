@@ -0,0 +1,330 @@
+package canvas
+
+import (
+	"math"
+)
+
+// ParamAt returns the segment index and parameter t at a distance d along the path, see Intersection for the indexing of segments and the parametrization of segments. Distances are clamped to the start and end of the path, and MoveTo commands do not add to the length of the path. The distance is approximated for Béziers and elliptical arcs.
+func (p *Path) ParamAt(d float64) (int, float64) {
+	if len(p.d) == 0 {
+		return 0, 0.0
+	} else if d <= 0.0 {
+		if seg, ok := p.firstSegment(); ok {
+			return seg, 0.0
+		}
+		return 0, 0.0
+	}
+
+	last, lastT := 0, 0.0
+	k := 0 // command index
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		s := newPathSegment(p, i, k, start)
+		start = s.end
+		i += cmdLen(cmd)
+		k++
+		if cmd == moveToCmd {
+			continue
+		}
+
+		length := s.length(1.0)
+		if d <= length && 0.0 < length {
+			return k - 1, s.invLength(d / length)
+		}
+		d -= length
+		last, lastT = k-1, 1.0
+	}
+	return last, lastT
+}
+
+// PointAt returns the position at a distance d along the path.
+func (p *Path) PointAt(d float64) Point {
+	return p.PointAtParam(p.ParamAt(d))
+}
+
+// TangentAt returns the unit tangent in the direction of the path at a distance d along the path.
+func (p *Path) TangentAt(d float64) Point {
+	return p.TangentAtParam(p.ParamAt(d))
+}
+
+// NormalAt returns the unit normal at a distance d along the path, which is the tangent rotated by 90 degrees counter clockwise, ie. it points to the left of the path.
+func (p *Path) NormalAt(d float64) Point {
+	return p.NormalAtParam(p.ParamAt(d))
+}
+
+// CurvatureAt returns the signed curvature at a distance d along the path, which is the inverse of the radius of curvature. It is positive when the path bends to the left (counter clockwise) and zero for straight lines.
+func (p *Path) CurvatureAt(d float64) float64 {
+	return p.CurvatureAtParam(p.ParamAt(d))
+}
+
+// PointAtParam returns the position at parameter t in [0,1] of segment seg, see Intersection for the indexing of segments.
+func (p *Path) PointAtParam(seg int, t float64) Point {
+	s, ok := p.segment(seg)
+	if !ok {
+		return Point{}
+	}
+	return s.pos(t)
+}
+
+// TangentAtParam returns the unit tangent at parameter t in [0,1] of segment seg, see Intersection for the indexing of segments. At cusps the direction of the chord is used, and it returns a zero vector for MoveTo commands and segments of zero length.
+func (p *Path) TangentAtParam(seg int, t float64) Point {
+	s, ok := p.segment(seg)
+	if !ok {
+		return Point{}
+	}
+	return s.direction(t).Norm(1.0)
+}
+
+// NormalAtParam returns the unit normal at parameter t in [0,1] of segment seg, which points to the left of the path, see TangentAtParam.
+func (p *Path) NormalAtParam(seg int, t float64) Point {
+	return p.TangentAtParam(seg, t).Rot90CCW()
+}
+
+// CurvatureAtParam returns the signed curvature at parameter t in [0,1] of segment seg, see CurvatureAt.
+func (p *Path) CurvatureAtParam(seg int, t float64) float64 {
+	s, ok := p.segment(seg)
+	if !ok {
+		return 0.0
+	}
+	d := s.deriv(t)
+	if Equal(d.Length(), 0.0) {
+		return 0.0
+	}
+	return d.PerpDot(s.deriv2(t)) / math.Pow(d.Dot(d), 1.5)
+}
+
+// firstSegment returns the index of the first command after the first MoveTo and whether it exists.
+func (p *Path) firstSegment() (int, bool) {
+	if 0 < len(p.d) && p.d[0] == moveToCmd {
+		return 1, cmdLen(moveToCmd) < len(p.d)
+	}
+	return 0, 0 < len(p.d)
+}
+
+// segment returns the segment at command index seg, MoveTo commands are returned as a line segment of zero length.
+func (p *Path) segment(seg int) (*segment, bool) {
+	if seg < 0 {
+		return nil, false
+	}
+	var start Point
+	k := 0
+	for i := 0; i < len(p.d); {
+		s := newPathSegment(p, i, k, start)
+		if k == seg {
+			return s, true
+		}
+		start = s.end
+		i += cmdLen(p.d[i])
+		k++
+	}
+	return nil, false
+}
+
+// newPathSegment returns the segment of the command at position i in the path data with command index k, starting at start. Close commands are returned as line segments and MoveTo commands as line segments of zero length.
+func newPathSegment(p *Path, i, k int, start Point) *segment {
+	s := &segment{cmd: lineToCmd, start: start, index: k, t1: 1.0}
+	s.orig = s
+	switch cmd := p.d[i]; cmd {
+	case moveToCmd:
+		s.end = Point{p.d[i+1], p.d[i+2]}
+		s.start = s.end
+	case lineToCmd, closeCmd:
+		s.end = Point{p.d[i+1], p.d[i+2]}
+	case quadToCmd:
+		s.cmd = quadToCmd
+		s.cp1 = Point{p.d[i+1], p.d[i+2]}
+		s.end = Point{p.d[i+3], p.d[i+4]}
+	case cubeToCmd:
+		s.cmd = cubeToCmd
+		s.cp1 = Point{p.d[i+1], p.d[i+2]}
+		s.cp2 = Point{p.d[i+3], p.d[i+4]}
+		s.end = Point{p.d[i+5], p.d[i+6]}
+	case arcToCmd:
+		s.cmd = arcToCmd
+		s.rx, s.ry, s.phi = p.d[i+1], p.d[i+2], p.d[i+3]
+		large, sweep := toArcFlags(p.d[i+4])
+		s.end = Point{p.d[i+5], p.d[i+6]}
+		s.cx, s.cy, s.theta0, s.theta1 = ellipseToCenter(start.X, start.Y, s.rx, s.ry, s.phi, large, sweep, s.end.X, s.end.Y)
+	}
+	return s
+}
+
+// deriv2 returns the second derivative of the segment at t.
+func (s *segment) deriv2(t float64) Point {
+	switch s.cmd {
+	case lineToCmd:
+		return Point{}
+	case quadToCmd:
+		return s.start.Sub(s.cp1.Mul(2.0)).Add(s.end).Mul(2.0)
+	case cubeToCmd:
+		return cubicBezierDeriv2(s.start, s.cp1, s.cp2, s.end, t)
+	}
+	dtheta := s.theta1 - s.theta0
+	return ellipseDeriv2(s.rx, s.ry, s.phi, true, s.theta0+t*dtheta).Mul(dtheta * dtheta)
+}
+
+// length returns the arc length of the segment from its start to t, using a composite Gauss-Legendre quadrature for accuracy on curves with varying speed.
+func (s *segment) length(t float64) float64 {
+	if s.cmd == lineToCmd {
+		return t * s.end.Sub(s.start).Length()
+	}
+	speed := func(t float64) float64 {
+		return s.deriv(t).Length()
+	}
+	const n = 8
+	L := 0.0
+	for i := 0; i < n; i++ {
+		L += gaussLegendre7(speed, t*float64(i)/n, t*float64(i+1)/n)
+	}
+	return L
+}
+
+// invLength returns the parameter t where the arc length from the start of the segment is the given fraction of the segment's length, using Newton's method.
+func (s *segment) invLength(fraction float64) float64 {
+	if fraction <= 0.0 {
+		return 0.0
+	} else if 1.0 <= fraction {
+		return 1.0
+	} else if s.cmd == lineToCmd {
+		return fraction
+	}
+
+	// the fraction of the quadrature is used to cancel out errors in the quadrature
+	d := fraction * s.length(1.0)
+	t := fraction
+	for n := 0; n < 20; n++ {
+		v := s.deriv(t).Length()
+		if Equal(v, 0.0) {
+			break
+		}
+		dt := (s.length(t) - d) / v
+		t = math.Max(0.0, math.Min(1.0, t-dt))
+		if math.Abs(dt) < 1e-9 {
+			break
+		}
+	}
+	return t
+}
+
+// ClosestPoint returns the point on the path closest to q, with its segment index and parameter t and the distance to q, see Intersection for the indexing of segments. It is computed on the segments directly, ie. Béziers and elliptical arcs are not flattened. For an empty path the distance is infinite, and for paths with only MoveTo commands it returns the closest MoveTo position.
+func (p *Path) ClosestPoint(q Point) (Point, int, float64, float64) {
+	closest, seg, t, dist := Point{}, 0, 0.0, math.Inf(1)
+	moveOnly := true
+	var start Point
+	for k, i := 0, 0; i < len(p.d); k++ {
+		cmd := p.d[i]
+		s := newPathSegment(p, i, k, start)
+		start = s.end
+		i += cmdLen(cmd)
+		if cmd == moveToCmd {
+			if d := s.end.Sub(q).Length(); moveOnly && d < dist {
+				closest, seg, t, dist = s.end, k, 0.0, d
+			}
+			continue
+		} else if moveOnly {
+			moveOnly = false
+			dist = math.Inf(1)
+		}
+
+		if ts, d := s.closestParam(q); d < dist {
+			closest, seg, t, dist = s.pos(ts), k, ts, d
+		}
+	}
+	return closest, seg, t, dist
+}
+
+// closestParam returns the parameter of the point on the segment closest to q and its distance. Curves are sampled to find the local minima of the distance, which are refined with Newton's method on the derivative of the squared distance.
+func (s *segment) closestParam(q Point) (float64, float64) {
+	if s.cmd == lineToCmd {
+		d := s.end.Sub(s.start)
+		t := 0.0
+		if l2 := d.Dot(d); 0.0 < l2 {
+			t = math.Max(0.0, math.Min(1.0, q.Sub(s.start).Dot(d)/l2))
+		}
+		return t, s.pos(t).Sub(q).Length()
+	}
+
+	const n = 16
+	dists := [n + 1]float64{}
+	for i := 0; i <= n; i++ {
+		dists[i] = s.pos(float64(i) / n).Sub(q).Length()
+	}
+
+	tBest, dBest := 0.0, dists[0]
+	for i := 0; i <= n; i++ {
+		if 0 < i && dists[i-1] < dists[i] || i < n && dists[i+1] < dists[i] {
+			continue // not a local minimum
+		}
+
+		t := float64(i) / n
+		for j := 0; j < 20; j++ {
+			// minimize |pos(t)-q|^2, whose derivative is 2*(pos(t)-q).deriv(t)
+			v := s.pos(t).Sub(q)
+			dp := s.deriv(t)
+			f := v.Dot(dp)
+			df := dp.Dot(dp) + v.Dot(s.deriv2(t))
+			if df <= 0.0 {
+				break
+			}
+			dt := f / df
+			t = math.Max(math.Max(0.0, (float64(i)-1.0)/n), math.Min(math.Min(1.0, (float64(i)+1.0)/n), t-dt))
+			if math.Abs(dt) < 1e-12 {
+				break
+			}
+		}
+		if d := s.pos(t).Sub(q).Length(); d < dBest {
+			tBest, dBest = t, d
+		}
+	}
+	return tBest, dBest
+}
+
+// integrate returns the integral of f over t in [0,1] of the segment using Gauss-Legendre quadrature. Elliptical arcs are integrated piecewise.
+func (s *segment) integrate(f func(pos, deriv Point) float64) float64 {
+	n := 1
+	if s.cmd == arcToCmd {
+		n = int(math.Ceil(math.Abs(s.theta1-s.theta0) / (math.Pi / 16.0)))
+	}
+	integral := 0.0
+	for k := 0; k < n; k++ {
+		integral += gaussLegendre5(func(t float64) float64 {
+			return f(s.pos(t), s.deriv(t))
+		}, float64(k)/float64(n), float64(k+1)/float64(n))
+	}
+	return integral
+}
+
+// area returns the contribution of the segment to the signed area using Green's theorem, ie. the integral of (x*dy - y*dx)/2.
+func (s *segment) area() float64 {
+	switch s.cmd {
+	case lineToCmd:
+		return s.start.PerpDot(s.end) / 2.0
+	case arcToCmd:
+		// the ellipse relative to its center sweeps an area of rx*ry/2 per radian
+		center := Point{s.cx, s.cy}
+		return (center.PerpDot(s.end.Sub(s.start)) + s.rx*s.ry*(s.theta1-s.theta0)) / 2.0
+	}
+	return s.integrate(func(pos, deriv Point) float64 {
+		return pos.PerpDot(deriv) / 2.0
+	})
+}
+
+// moment returns the contribution of the segment to the first moments of area using Green's theorem, ie. the integrals of x^2*dy/2 and -y^2*dx/2.
+func (s *segment) moment() Point {
+	if s.cmd == lineToCmd {
+		a, b := s.start, s.end
+		return Point{
+			(b.Y - a.Y) * (a.X*a.X + a.X*b.X + b.X*b.X) / 6.0,
+			-(b.X - a.X) * (a.Y*a.Y + a.Y*b.Y + b.Y*b.Y) / 6.0,
+		}
+	}
+	return Point{
+		s.integrate(func(pos, deriv Point) float64 {
+			return pos.X * pos.X * deriv.Y / 2.0
+		}),
+		s.integrate(func(pos, deriv Point) float64 {
+			return -pos.Y * pos.Y * deriv.X / 2.0
+		}),
+	}
+}
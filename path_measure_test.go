@@ -0,0 +1,121 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestPathParamAt(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p   string
+		d   float64
+		seg int
+		t   float64
+	}{
+		{"", 5.0, 0, 0.0},
+		{"M0 0", 5.0, 0, 0.0},
+		{"M0 0L10 0L10 10", -1.0, 1, 0.0},
+		{"M0 0L10 0L10 10", 5.0, 1, 0.5},
+		{"M0 0L10 0L10 10", 10.0, 1, 1.0},
+		{"M0 0L10 0L10 10", 15.0, 2, 0.5},
+		{"M0 0L10 0L10 10", 25.0, 2, 1.0},
+		{"M0 0L10 0M20 0L30 0", 15.0, 3, 0.5},
+		{"M0 0L10 0L10 10z", 30.0, 3, 1.0 / math.Sqrt(2.0)},
+		{"M10 0A10 10 0 0 1 -10 0", 5.0 * math.Pi, 1, 0.5},
+		{"M0 0Q5 10 10 0", quadraticBezierLength(Point{0, 0}, Point{5, 10}, Point{10, 0}) / 2.0, 1, 0.5},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			seg, tpos := MustParseSVG(tt.p).ParamAt(tt.d)
+			test.T(t, seg, tt.seg)
+			test.Float(t, tpos, tt.t)
+		})
+	}
+}
+
+func TestPathPointAt(t *testing.T) {
+	Epsilon = 1e-4 // arc lengths are approximated
+	var tts = []struct {
+		p         string
+		d         float64
+		pos       Point
+		tangent   Point
+		normal    Point
+		curvature float64
+	}{
+		{"M0 0L10 0L10 10", 5.0, Point{5.0, 0.0}, Point{1.0, 0.0}, Point{0.0, 1.0}, 0.0},
+		{"M0 0L10 0L10 10", 15.0, Point{10.0, 5.0}, Point{0.0, 1.0}, Point{-1.0, 0.0}, 0.0},
+		{"M0 0L10 0L10 10", 50.0, Point{10.0, 10.0}, Point{0.0, 1.0}, Point{-1.0, 0.0}, 0.0},
+		{"M10 0A10 10 0 0 1 -10 0", 5.0 * math.Pi, Point{0.0, 10.0}, Point{-1.0, 0.0}, Point{0.0, -1.0}, 0.1},
+		{"M10 0A10 10 0 0 1 -10 0", 2.5 * math.Pi, Point{10.0 / math.Sqrt(2.0), 10.0 / math.Sqrt(2.0)}, Point{-1.0 / math.Sqrt(2.0), 1.0 / math.Sqrt(2.0)}, Point{-1.0 / math.Sqrt(2.0), -1.0 / math.Sqrt(2.0)}, 0.1},
+		{"M-10 0A10 10 0 0 0 10 0", 5.0 * math.Pi, Point{0.0, 10.0}, Point{1.0, 0.0}, Point{0.0, 1.0}, -0.1},
+		{"M0 0Q5 10 10 0", quadraticBezierLength(Point{0, 0}, Point{5, 10}, Point{10, 0}) / 2.0, Point{5.0, 5.0}, Point{1.0, 0.0}, Point{0.0, 1.0}, -0.4},
+		{"M0 0C0 10 10 10 10 0", 10.0, Point{5.0, 7.5}, Point{1.0, 0.0}, Point{0.0, 1.0}, -0.8 / 3.0},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			p := MustParseSVG(tt.p)
+			test.T(t, p.PointAt(tt.d), tt.pos)
+			test.T(t, p.TangentAt(tt.d), tt.tangent)
+			test.T(t, p.NormalAt(tt.d), tt.normal)
+			test.T(t, Equal(p.CurvatureAt(tt.d), tt.curvature), true)
+		})
+	}
+}
+
+func TestPathPointAtParam(t *testing.T) {
+	p := MustParseSVG("M0 0L10 0C10 10 0 10 0 0")
+	test.T(t, p.PointAtParam(0, 0.5), Point{0.0, 0.0})
+	test.T(t, p.TangentAtParam(0, 0.5), Point{})
+	test.T(t, p.PointAtParam(1, 0.5), Point{5.0, 0.0})
+	test.T(t, p.PointAtParam(2, 0.5), Point{5.0, 7.5})
+	test.T(t, p.TangentAtParam(2, 0.5), Point{-1.0, 0.0})
+	test.T(t, p.NormalAtParam(2, 0.5), Point{0.0, -1.0})
+	test.T(t, p.PointAtParam(3, 0.5), Point{})
+	test.Float(t, p.CurvatureAtParam(3, 0.5), 0.0)
+}
+
+func TestPathClosestPoint(t *testing.T) {
+	Epsilon = 1e-6
+	cubicPos := cubicBezierPos(Point{0.0, 0.0}, Point{0.0, 10.0}, Point{10.0, 10.0}, Point{10.0, 0.0}, 0.25)
+	cubicNormal := cubicBezierDeriv(Point{0.0, 0.0}, Point{0.0, 10.0}, Point{10.0, 10.0}, Point{10.0, 0.0}, 0.25).Rot90CCW().Norm(1.0)
+	arcPos := cubicBezierPos(Point{10.0, 0.0}, Point{10.0, 5.5}, Point{5.5, 10.0}, Point{0.0, 10.0}, 0.01)
+	arcNormal := cubicBezierDeriv(Point{10.0, 0.0}, Point{10.0, 5.5}, Point{5.5, 10.0}, Point{0.0, 10.0}, 0.01).Rot90CW().Norm(0.5)
+	var tts = []struct {
+		p    string
+		q    Point
+		pos  Point
+		seg  int
+		t    float64
+		dist float64
+	}{
+		{"M3 4", Point{0.0, 0.0}, Point{3.0, 4.0}, 0, 0.0, 5.0},
+		{"M0 0L10 0L10 10", Point{5.0, 3.0}, Point{5.0, 0.0}, 1, 0.5, 3.0},
+		{"M0 0L10 0L10 10", Point{12.0, 5.0}, Point{10.0, 5.0}, 2, 0.5, 2.0},
+		{"M0 0L10 0L10 10", Point{-1.0, -1.0}, Point{0.0, 0.0}, 1, 0.0, math.Sqrt(2.0)},
+		{"M0 0L10 0L10 10z", Point{4.0, 6.0}, Point{5.0, 5.0}, 3, 0.5, math.Sqrt(2.0)},
+		{"M0 0L10 0M0 5L10 5", Point{5.0, 4.0}, Point{5.0, 5.0}, 3, 0.5, 1.0},
+		{"M10 0A10 10 0 0 1 -10 0", Point{0.0, 5.0}, Point{0.0, 10.0}, 1, 0.5, 5.0},
+		{"M10 0A10 10 0 0 1 -10 0", Point{3.0, 4.0}, Point{6.0, 8.0}, 1, math.Atan2(8.0, 6.0) / math.Pi, 5.0},
+		{"M10 0A10 10 0 0 1 -10 0", Point{0.0, -5.0}, Point{10.0, 0.0}, 1, 0.0, math.Sqrt(125.0)},
+		{"M0 0Q5 10 10 0", Point{5.0, 10.0}, Point{5.0, 5.0}, 1, 0.5, 5.0},
+		{"M0 0C0 10 10 10 10 0", Point{5.0, 10.0}, Point{5.0, 7.5}, 1, 0.5, 2.5},
+		{"M0 0C0 10 10 10 10 0", cubicPos.Add(cubicNormal), cubicPos, 1, 0.25, 1.0},
+		{"M10 0C10 5.5 5.5 10 0 10", arcPos.Add(arcNormal), arcPos, 1, 0.01, 0.5},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			pos, seg, tpos, dist := MustParseSVG(tt.p).ClosestPoint(tt.q)
+			test.T(t, pos, tt.pos)
+			test.T(t, seg, tt.seg)
+			test.Float(t, tpos, tt.t)
+			test.Float(t, dist, tt.dist)
+		})
+	}
+
+	_, _, _, dist := (&Path{}).ClosestPoint(Point{})
+	test.That(t, math.IsInf(dist, 1))
+}
@@ -0,0 +1,220 @@
+package canvas
+
+import (
+	"math"
+)
+
+// Interpolate returns the path interpolated linearly between p for t=0 and q for t=1, which can be used to animate the transition between two shapes. Paths with the same command structure, such as the same path with different coordinates, are interpolated coordinate by coordinate. Otherwise both paths are first normalized by MatchPaths.
+func (p *Path) Interpolate(q *Path, t float64) *Path {
+	if !p.compatible(q) {
+		p, q = MatchPaths(p, q)
+	}
+
+	r := &Path{make([]float64, len(p.d))}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		n := cmdLen(cmd)
+		r.d[i], r.d[i+n-1] = cmd, cmd
+		for j := i + 1; j < i+n-1; j++ {
+			r.d[j] = p.d[j] + t*(q.d[j]-p.d[j])
+		}
+		if cmd == arcToCmd {
+			r.d[i+4] = p.d[i+4] // arc flags
+		}
+		i += n
+	}
+	return r
+}
+
+// compatible returns true if p and q have the same commands in the same order, and the same flags for arcs.
+func (p *Path) compatible(q *Path) bool {
+	if len(p.d) != len(q.d) {
+		return false
+	}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		if q.d[i] != cmd || cmd == arcToCmd && p.d[i+4] != q.d[i+4] {
+			return false
+		}
+		i += cmdLen(cmd)
+	}
+	return true
+}
+
+// MatchPaths returns p and q converted to paths with the same structure so that they can be interpolated command by command, see Interpolate. Lines, quadratic Béziers and arcs are converted to cubic Béziers, and the longest segments are split until the corresponding subpaths have the same number of segments. Subpaths without a counterpart are matched against a copy that is collapsed to the center of their bounds. When corresponding subpaths are both closed, their orientation and start point are aligned to minimize the movement between them, otherwise both are left open.
+func MatchPaths(p, q *Path) (*Path, *Path) {
+	ps, qs := morphSubpaths(p), morphSubpaths(q)
+	for len(ps) < len(qs) {
+		ps = append(ps, qs[len(ps)].collapse())
+	}
+	for len(qs) < len(ps) {
+		qs = append(qs, ps[len(qs)].collapse())
+	}
+
+	rp, rq := &Path{}, &Path{}
+	for i := range ps {
+		a, b := ps[i], qs[i]
+		closed := a.closed && b.closed
+		if closed && (0.0 <= a.area()) != (0.0 <= b.area()) {
+			b = b.reverse()
+		}
+		a.split(len(b.segs))
+		b.split(len(a.segs))
+		if closed {
+			b.rotate(a)
+		}
+		a.appendTo(rp, closed)
+		b.appendTo(rq, closed)
+	}
+	return rp, rq
+}
+
+// morphSubpath is a subpath consisting only of cubic Béziers.
+type morphSubpath struct {
+	start  Point
+	segs   [][4]Point
+	closed bool
+}
+
+// morphSubpaths converts p to subpaths of cubic Béziers, including the closing segment of closed subpaths.
+func morphSubpaths(p *Path) []*morphSubpath {
+	p = p.ReplaceArcs()
+
+	subpaths := []*morphSubpath{}
+	var sp *morphSubpath
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		end := Point{p.d[i-3], p.d[i-2]}
+		switch cmd {
+		case moveToCmd:
+			sp = &morphSubpath{start: end}
+			subpaths = append(subpaths, sp)
+		case lineToCmd, closeCmd:
+			if cmd == closeCmd {
+				sp.closed = true
+				if start.Equals(end) {
+					break
+				}
+			}
+			sp.segs = append(sp.segs, [4]Point{start, start.Interpolate(end, 1.0/3.0), start.Interpolate(end, 2.0/3.0), end})
+		case quadToCmd:
+			cp := Point{p.d[i-5], p.d[i-4]}
+			cp1, cp2 := quadraticToCubicBezier(start, cp, end)
+			sp.segs = append(sp.segs, [4]Point{start, cp1, cp2, end})
+		case cubeToCmd:
+			sp.segs = append(sp.segs, [4]Point{start, {p.d[i-7], p.d[i-6]}, {p.d[i-5], p.d[i-4]}, end})
+		}
+		start = end
+	}
+	return subpaths
+}
+
+// collapse returns a copy of the subpath with all points at the center of its bounds.
+func (sp *morphSubpath) collapse() *morphSubpath {
+	xmin, xmax := sp.start.X, sp.start.X
+	ymin, ymax := sp.start.Y, sp.start.Y
+	for _, seg := range sp.segs {
+		for _, pt := range seg[1:] {
+			xmin, xmax = math.Min(xmin, pt.X), math.Max(xmax, pt.X)
+			ymin, ymax = math.Min(ymin, pt.Y), math.Max(ymax, pt.Y)
+		}
+	}
+	center := Point{(xmin + xmax) / 2.0, (ymin + ymax) / 2.0}
+
+	segs := make([][4]Point, len(sp.segs))
+	for i := range segs {
+		segs[i] = [4]Point{center, center, center, center}
+	}
+	return &morphSubpath{center, segs, sp.closed}
+}
+
+// area returns the signed area of the control polygon, which is positive for counter clockwise subpaths.
+func (sp *morphSubpath) area() float64 {
+	area := 0.0
+	for _, seg := range sp.segs {
+		for j := 1; j < 4; j++ {
+			area += seg[j-1].PerpDot(seg[j])
+		}
+	}
+	return area / 2.0
+}
+
+// reverse returns the subpath in the opposite direction.
+func (sp *morphSubpath) reverse() *morphSubpath {
+	n := len(sp.segs)
+	segs := make([][4]Point, n)
+	for i, seg := range sp.segs {
+		segs[n-1-i] = [4]Point{seg[3], seg[2], seg[1], seg[0]}
+	}
+	start := sp.start
+	if 0 < n {
+		start = segs[0][0]
+	}
+	return &morphSubpath{start, segs, sp.closed}
+}
+
+// split splits the longest segments in half until the subpath has at least n segments.
+func (sp *morphSubpath) split(n int) {
+	if len(sp.segs) == 0 {
+		for len(sp.segs) < n {
+			sp.segs = append(sp.segs, [4]Point{sp.start, sp.start, sp.start, sp.start})
+		}
+		return
+	}
+
+	lengths := make([]float64, len(sp.segs))
+	for i, seg := range sp.segs {
+		lengths[i] = cubicBezierLength(seg[0], seg[1], seg[2], seg[3])
+	}
+	for len(sp.segs) < n {
+		k := 0
+		for i, length := range lengths {
+			if lengths[k] < length {
+				k = i
+			}
+		}
+
+		seg := sp.segs[k]
+		p0, p1, p2, p3, q0, q1, q2, q3 := cubicBezierSplit(seg[0], seg[1], seg[2], seg[3], 0.5)
+		sp.segs = append(sp.segs[:k+1], sp.segs[k:]...)
+		sp.segs[k], sp.segs[k+1] = [4]Point{p0, p1, p2, p3}, [4]Point{q0, q1, q2, q3}
+		lengths = append(lengths[:k+1], lengths[k:]...)
+		lengths[k] = cubicBezierLength(p0, p1, p2, p3)
+		lengths[k+1] = cubicBezierLength(q0, q1, q2, q3)
+	}
+}
+
+// rotate changes the start point of the closed subpath to the segment boundary that minimizes the distances to the segment boundaries of ref, which must have the same number of segments.
+func (sp *morphSubpath) rotate(ref *morphSubpath) {
+	n := len(sp.segs)
+	if n == 0 {
+		return
+	}
+
+	best, minDist := 0, math.Inf(1)
+	for k := 0; k < n; k++ {
+		dist := 0.0
+		for i := 0; i < n; i++ {
+			d := sp.segs[(i+k)%n][0].Sub(ref.segs[i][0])
+			dist += d.Dot(d)
+		}
+		if dist < minDist {
+			best, minDist = k, dist
+		}
+	}
+	sp.segs = append(sp.segs[best:], sp.segs[:best]...)
+	sp.start = sp.segs[0][0]
+}
+
+// appendTo appends the subpath to p with cubic Bézier commands only, so that the structure is independent of the coordinates.
+func (sp *morphSubpath) appendTo(p *Path, closed bool) {
+	p.d = append(p.d, moveToCmd, sp.start.X, sp.start.Y, moveToCmd)
+	for _, seg := range sp.segs {
+		p.d = append(p.d, cubeToCmd, seg[1].X, seg[1].Y, seg[2].X, seg[2].Y, seg[3].X, seg[3].Y, cubeToCmd)
+	}
+	if closed {
+		p.d = append(p.d, closeCmd, sp.start.X, sp.start.Y, closeCmd)
+	}
+}
@@ -0,0 +1,65 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+// cubicPath returns a path of cubic Béziers through the given control and end points without simplifying straight segments.
+func cubicPath(start Point, pts ...Point) *Path {
+	p := &Path{[]float64{moveToCmd, start.X, start.Y, moveToCmd}}
+	for i := 0; i+2 < len(pts); i += 3 {
+		p.d = append(p.d, cubeToCmd, pts[i].X, pts[i].Y, pts[i+1].X, pts[i+1].Y, pts[i+2].X, pts[i+2].Y, cubeToCmd)
+	}
+	return p
+}
+
+func TestPathInterpolate(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p, q string
+		t    float64
+		r    *Path
+	}{
+		{"M0 0L10 0", "M0 10L20 10", 0.5, MustParseSVG("M0 5L15 5")},
+		{"M0 0A5 5 0 0 1 10 0", "M0 0A10 10 0 0 1 10 0", 0.5, MustParseSVG("M0 0A7.5 7.5 0 0 1 10 0")},
+		{"M0 0L12 0", "M0 0Q6 12 12 0", 0.5, cubicPath(Point{0.0, 0.0}, Point{4.0, 4.0}, Point{8.0, 4.0}, Point{12.0, 0.0})},
+		{"M0 0L12 0", "M0 0L6 6L12 0", 0.5, cubicPath(Point{0.0, 0.0}, Point{2.0, 1.0}, Point{4.0, 2.0}, Point{6.0, 3.0}, Point{8.0, 2.0}, Point{10.0, 1.0}, Point{12.0, 0.0})},
+		{"M0 0L12 0", "M0 0L12 0M24 24L36 24", 0.5, cubicPath(Point{0.0, 0.0}, Point{4.0, 0.0}, Point{8.0, 0.0}, Point{12.0, 0.0}).Append(cubicPath(Point{27.0, 24.0}, Point{29.0, 24.0}, Point{31.0, 24.0}, Point{33.0, 24.0}))},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p+"-"+tt.q, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.p).Interpolate(MustParseSVG(tt.q), tt.t), tt.r)
+		})
+	}
+}
+
+func TestMatchPaths(t *testing.T) {
+	Epsilon = 1e-6
+	p, q := MatchPaths(MustParseSVG("M0 0L12 0"), MustParseSVG("M0 0L6 6L12 0"))
+	test.T(t, p, cubicPath(Point{0.0, 0.0}, Point{2.0, 0.0}, Point{4.0, 0.0}, Point{6.0, 0.0}, Point{8.0, 0.0}, Point{10.0, 0.0}, Point{12.0, 0.0}))
+	test.T(t, q, cubicPath(Point{0.0, 0.0}, Point{2.0, 2.0}, Point{4.0, 4.0}, Point{6.0, 6.0}, Point{8.0, 4.0}, Point{10.0, 2.0}, Point{12.0, 0.0}))
+
+	// closed subpaths with opposite orientations and start points are aligned
+	square := MustParseSVG("M0 0L10 0L10 10L0 10z")
+	p, q = MatchPaths(square, MustParseSVG("M10 10L10 0L0 0L0 10z"))
+	test.That(t, p.compatible(q))
+	test.T(t, q, p)
+
+	// interpolating between a square and a circle keeps the subpath closed
+	circle := Circle(5.0).Translate(5.0, 5.0)
+	r := square.Interpolate(circle, 0.0)
+	test.That(t, r.Closed())
+	test.Float(t, r.Area(), 100.0)
+	r = square.Interpolate(circle, 1.0)
+	test.That(t, r.Closed())
+	test.Float(t, r.Area(), circle.ReplaceArcs().Area())
+	test.That(t, square.Interpolate(circle, 0.5).Area() < 100.0)
+
+	// closed and open subpaths are both left open
+	p, q = MatchPaths(square, MustParseSVG("M0 0L10 0"))
+	test.That(t, !p.Closed())
+	test.That(t, !q.Closed())
+	test.That(t, p.compatible(q))
+}
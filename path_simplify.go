@@ -0,0 +1,263 @@
+package canvas
+
+import (
+	"math"
+)
+
+// fitCurvesCornerAngle is the minimum angle in radians between consecutive line segments for which FitCurves keeps a corner instead of fitting a smooth curve through it.
+const fitCurvesCornerAngle = math.Pi / 3.0
+
+// Simplify removes points from runs of consecutive line segments using the Ramer-Douglas-Peucker algorithm, so that the simplified path deviates at most tolerance from the original path. Bézier and arc segments are kept as they are, and subpaths remain closed or open.
+func (p *Path) Simplify(tolerance float64) *Path {
+	return p.replaceLines(func(q *Path, pts []Point) {
+		keep := make([]bool, len(pts))
+		keep[0], keep[len(pts)-1] = true, true
+		simplifyRDP(pts, 0, len(pts)-1, tolerance, keep)
+		for i := 1; i < len(pts); i++ {
+			if keep[i] {
+				q.LineTo(pts[i].X, pts[i].Y)
+			}
+		}
+	})
+}
+
+// FitCurves replaces runs of consecutive line segments by a minimal number of cubic Béziers that deviate at most tolerance from the points of the original path, using the algorithm of Schneider from Graphics Gems. Corners where the direction changes by more than 60 degrees are preserved, and Bézier and arc segments are kept as they are. It complements Flatten, and Polyline.Smoothen which passes through all points.
+func (p *Path) FitCurves(tolerance float64) *Path {
+	return p.replaceLines(func(q *Path, pts []Point) {
+		// remove duplicate points
+		n := 1
+		for _, pt := range pts[1:] {
+			if !pt.Equals(pts[n-1]) {
+				pts[n] = pt
+				n++
+			}
+		}
+		pts = pts[:n]
+		if n == 1 {
+			return
+		}
+
+		// split at corners
+		first := 0
+		for i := 1; i < len(pts); i++ {
+			if i == len(pts)-1 || fitCurvesCornerAngle < math.Abs(pts[i].Sub(pts[i-1]).AngleBetween(pts[i+1].Sub(pts[i]))) {
+				fitCubic(q, pts[first:i+1], endTangent(pts[first:i+1]), endTangent(reversePoints(pts[first:i+1])), tolerance*tolerance)
+				first = i
+			}
+		}
+	})
+}
+
+// replaceLines calls replace for each run of consecutive line segments with their points, including the start point, which must append the replacing segments to q. Runs are not continued over the closing segment of closed subpaths, which is always kept as a close command.
+func (p *Path) replaceLines(replace func(q *Path, pts []Point)) *Path {
+	q := &Path{}
+	pts := []Point{}
+	flush := func() {
+		if 1 < len(pts) {
+			replace(q, pts)
+		}
+		pts = pts[:0]
+	}
+
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		end := Point{p.d[i-3], p.d[i-2]}
+		switch cmd {
+		case moveToCmd:
+			flush()
+			q.MoveTo(end.X, end.Y)
+			pts = append(pts, end)
+		case lineToCmd:
+			if len(pts) == 0 {
+				pts = append(pts, start)
+			}
+			pts = append(pts, end)
+		case closeCmd:
+			if len(pts) == 0 {
+				pts = append(pts, start)
+			}
+			pts = append(pts, end)
+			if 2 < len(pts) {
+				// keep the closing segment and replace the others
+				pts = pts[:len(pts)-1]
+				flush()
+			} else {
+				pts = pts[:0]
+			}
+			q.Close()
+		default:
+			flush()
+			q.d = append(q.d, p.d[i-cmdLen(cmd):i]...)
+		}
+		start = end
+	}
+	flush()
+	return q
+}
+
+// simplifyRDP marks the points between i and j that need to be kept so that the polyline stays within tolerance.
+func simplifyRDP(pts []Point, i, j int, tolerance float64, keep []bool) {
+	if j <= i+1 {
+		return
+	}
+	maxDist, index := -1.0, i
+	for k := i + 1; k < j; k++ {
+		if d := distanceToLine(pts[k], pts[i], pts[j]); maxDist < d {
+			maxDist, index = d, k
+		}
+	}
+	if tolerance < maxDist {
+		keep[index] = true
+		simplifyRDP(pts, i, index, tolerance, keep)
+		simplifyRDP(pts, index, j, tolerance, keep)
+	}
+}
+
+// distanceToLine returns the distance from p to the line segment between a and b.
+func distanceToLine(p, a, b Point) float64 {
+	d := b.Sub(a)
+	if l2 := d.Dot(d); 0.0 < l2 {
+		t := math.Max(0.0, math.Min(1.0, p.Sub(a).Dot(d)/l2))
+		return p.Sub(a.Add(d.Mul(t))).Length()
+	}
+	return p.Sub(a).Length()
+}
+
+// fitCubic appends cubic Béziers to q that fit the points within an error of errorSq (squared distance), with unit tangents tHat1 and tHat2 at the start and end pointing inwards.
+func fitCubic(q *Path, pts []Point, tHat1, tHat2 Point, errorSq float64) {
+	if len(pts) == 2 {
+		dist := pts[1].Sub(pts[0]).Length() / 3.0
+		cp1 := pts[0].Add(tHat1.Mul(dist))
+		cp2 := pts[1].Add(tHat2.Mul(dist))
+		q.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, pts[1].X, pts[1].Y)
+		return
+	}
+
+	u := chordLengthParameterize(pts)
+	bezier := generateBezier(pts, u, tHat1, tHat2)
+	maxError, split := bezierMaxError(pts, bezier, u)
+	if maxError < errorSq {
+		q.CubeTo(bezier[1].X, bezier[1].Y, bezier[2].X, bezier[2].Y, bezier[3].X, bezier[3].Y)
+		return
+	}
+
+	// try to improve the parametrization before splitting, the chord length parametrization is often too coarse
+	for n := 0; n < 4; n++ {
+		u = reparameterize(pts, u, bezier)
+		bezier = generateBezier(pts, u, tHat1, tHat2)
+		if maxError, split = bezierMaxError(pts, bezier, u); maxError < errorSq {
+			q.CubeTo(bezier[1].X, bezier[1].Y, bezier[2].X, bezier[2].Y, bezier[3].X, bezier[3].Y)
+			return
+		}
+	}
+
+	// split at the point of maximum error and fit both halves
+	tHatCenter := pts[split-1].Sub(pts[split+1]).Norm(1.0)
+	if tHatCenter.IsZero() {
+		tHatCenter = pts[split-1].Sub(pts[split]).Rot90CCW().Norm(1.0)
+	}
+	fitCubic(q, pts[:split+1], tHat1, tHatCenter, errorSq)
+	fitCubic(q, pts[split:], tHatCenter.Neg(), tHat2, errorSq)
+}
+
+// endTangent returns the unit tangent at the first point pointing inwards, estimated from a quadratic through the first three points if available.
+func endTangent(pts []Point) Point {
+	if 2 < len(pts) {
+		if t := pts[1].Mul(4.0).Sub(pts[0].Mul(3.0)).Sub(pts[2]).Norm(1.0); !t.IsZero() && 0.0 < t.Dot(pts[1].Sub(pts[0])) {
+			return t
+		}
+	}
+	return pts[1].Sub(pts[0]).Norm(1.0)
+}
+
+func reversePoints(pts []Point) []Point {
+	r := make([]Point, len(pts))
+	for i, pt := range pts {
+		r[len(pts)-1-i] = pt
+	}
+	return r
+}
+
+// generateBezier finds the control points of a cubic Bézier through the end points with the given tangents, using least squares for the distances of the control points along the tangents.
+func generateBezier(pts []Point, u []float64, tHat1, tHat2 Point) [4]Point {
+	first, last := pts[0], pts[len(pts)-1]
+	var c [2][2]float64
+	var x [2]float64
+	for i, p := range pts {
+		b0, b1, b2, b3 := bernstein(u[i])
+		a1 := tHat1.Mul(b1)
+		a2 := tHat2.Mul(b2)
+		c[0][0] += a1.Dot(a1)
+		c[0][1] += a1.Dot(a2)
+		c[1][1] += a2.Dot(a2)
+
+		tmp := p.Sub(first.Mul(b0 + b1)).Sub(last.Mul(b2 + b3))
+		x[0] += a1.Dot(tmp)
+		x[1] += a2.Dot(tmp)
+	}
+	c[1][0] = c[0][1]
+
+	alpha1, alpha2 := 0.0, 0.0
+	if det := c[0][0]*c[1][1] - c[1][0]*c[0][1]; det != 0.0 {
+		alpha1 = (x[0]*c[1][1] - x[1]*c[0][1]) / det
+		alpha2 = (c[0][0]*x[1] - c[1][0]*x[0]) / det
+	}
+
+	// fall back to the Wu-Barsky heuristic when the control points are too close or on the wrong side
+	segLength := last.Sub(first).Length()
+	epsilon := 1e-6 * segLength
+	if alpha1 < epsilon || alpha2 < epsilon {
+		alpha1 = segLength / 3.0
+		alpha2 = alpha1
+	}
+	return [4]Point{first, first.Add(tHat1.Mul(alpha1)), last.Add(tHat2.Mul(alpha2)), last}
+}
+
+// reparameterize improves the parameters of the points along the Bézier using one step of Newton's method.
+func reparameterize(pts []Point, u []float64, bezier [4]Point) []float64 {
+	uPrime := make([]float64, len(u))
+	for i, p := range pts {
+		t := u[i]
+		v := cubicBezierPos(bezier[0], bezier[1], bezier[2], bezier[3], t).Sub(p)
+		d1 := cubicBezierDeriv(bezier[0], bezier[1], bezier[2], bezier[3], t)
+		d2 := cubicBezierDeriv2(bezier[0], bezier[1], bezier[2], bezier[3], t)
+		uPrime[i] = t
+		if denom := d1.Dot(d1) + v.Dot(d2); denom != 0.0 {
+			uPrime[i] = math.Max(0.0, math.Min(1.0, t-v.Dot(d1)/denom))
+		}
+	}
+	return uPrime
+}
+
+// bezierMaxError returns the maximum squared distance between the points and the Bézier at their parameters, and the index of that point.
+func bezierMaxError(pts []Point, bezier [4]Point, u []float64) (float64, int) {
+	maxDist, split := 0.0, len(pts)/2
+	for i := 1; i < len(pts)-1; i++ {
+		v := cubicBezierPos(bezier[0], bezier[1], bezier[2], bezier[3], u[i]).Sub(pts[i])
+		if d := v.Dot(v); maxDist <= d {
+			maxDist, split = d, i
+		}
+	}
+	return maxDist, split
+}
+
+// chordLengthParameterize returns parameters for the points in [0,1] proportional to the distance along the polyline.
+func chordLengthParameterize(pts []Point) []float64 {
+	u := make([]float64, len(pts))
+	for i := 1; i < len(pts); i++ {
+		u[i] = u[i-1] + pts[i].Sub(pts[i-1]).Length()
+	}
+	if total := u[len(u)-1]; total != 0.0 {
+		for i := range u {
+			u[i] /= total
+		}
+	}
+	return u
+}
+
+func bernstein(t float64) (float64, float64, float64, float64) {
+	mt := 1.0 - t
+	return mt * mt * mt, 3.0 * t * mt * mt, 3.0 * t * t * mt, t * t * t
+}
@@ -0,0 +1,65 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestPathSimplify(t *testing.T) {
+	var tts = []struct {
+		p         string
+		tolerance float64
+		r         string
+	}{
+		{"M0 0L1 0.1L2 0L3 0.1L4 0", 0.2, "M0 0L4 0"},
+		{"M0 0L1 0.1L2 0L3 0.1L4 0", 0.05, "M0 0L1 0.1L2 0L3 0.1L4 0"},
+		{"M0 0L5 1L10 0", 2.0, "M0 0L10 0"},
+		{"M0 0L5 1L10 0", 0.5, "M0 0L5 1L10 0"},
+		{"M0 0L5 0.1L10 0L10 10L0 10z", 0.5, "M0 0L10 0L10 10L0 10z"},
+		{"M0 0L1 0.01L2 0Q3 1 4 0L5 0.01L6 0", 0.1, "M0 0L2 0Q3 1 4 0L6 0"},
+		{"M0 0L1 0.01L2 0M0 5L1 5.01L2 5", 0.1, "M0 0L2 0M0 5L2 5"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.p).Simplify(tt.tolerance), MustParseSVG(tt.r))
+		})
+	}
+}
+
+func TestPathFitCurves(t *testing.T) {
+	Epsilon = 1e-3
+	var tts = []struct {
+		p         string
+		tolerance float64
+		r         string
+	}{
+		{"M0 0L10 0", 0.1, "M0 0C3.333 0 6.667 0 10 0"},
+		{"M0 0L10 0L10 10", 0.1, "M0 0C3.333 0 6.667 0 10 0C10 3.333 10 6.667 10 10"},
+		{"M0 0L5 0L10 0", 0.1, "M0 0C3.333 0 6.667 0 10 0"},
+		{"M0 0L5 0L5 0L10 0", 0.1, "M0 0C3.333 0 6.667 0 10 0"},
+		{"M0 0L10 0L10 10L0 10z", 0.1, "M0 0C3.333 0 6.667 0 10 0C10 3.333 10 6.667 10 10C6.667 10 3.333 10 0 10z"},
+		{"M0 0Q5 10 10 0", 0.1, "M0 0Q5 10 10 0"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.p).FitCurves(tt.tolerance), MustParseSVG(tt.r))
+		})
+	}
+
+	// a densely sampled quarter circle is fitted by a single cubic Bézier
+	polyline := &Path{}
+	polyline.MoveTo(10.0, 0.0)
+	for i := 1; i <= 50; i++ {
+		theta := float64(i) / 50.0 * math.Pi / 2.0
+		polyline.LineTo(10.0*math.Cos(theta), 10.0*math.Sin(theta))
+	}
+	p := polyline.FitCurves(0.01)
+	test.T(t, len(p.d), cmdLen(moveToCmd)+cmdLen(cubeToCmd))
+	test.T(t, p.Pos(), Point{0.0, 10.0})
+	for _, coord := range polyline.Coords() {
+		_, _, _, dist := p.ClosestPoint(coord)
+		test.That(t, dist < 0.01, "point", coord, "has distance", dist)
+	}
+}
@@ -299,11 +299,18 @@ type pathStrokeState struct {
 	cp1, cp2                    Point   // Béziers
 	rx, ry, rot, theta0, theta1 float64 // arcs
 	large, sweep                bool    // arcs
+
+	seg    *segment // variable widths
+	d0, d1 float64  // variable widths, distance along the path of start and end
 }
 
 // offsetSegment returns the rhs and lhs paths from offsetting a path segment.
-// It closes rhs and lhs when p is closed as well.
-func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Path) {
+// It closes rhs and lhs when p is closed as well. When profile is not nil it overrides halfWidth and gives the stroke width along p, the offsets are then flattened.
+func offsetSegment(p *Path, halfWidth float64, profile StrokeProfile, cr Capper, jr Joiner) (*Path, *Path) {
+	if profile != nil {
+		halfWidth = 1.0 // normals are scaled to the half width at their position below
+	}
+
 	// only non-empty paths are evaluated
 	closed := false
 	states := []pathStrokeState{}
@@ -392,10 +399,31 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 			}
 			closed = true
 		}
+		if profile != nil && 0 < len(states) && states[len(states)-1].seg == nil {
+			states[len(states)-1].seg = newPathSegment(p, i, 0, start)
+		}
 		start = end
 		i += cmdLen(cmd)
 	}
 
+	var halfWidthAt func(float64) float64
+	if profile != nil {
+		d := 0.0
+		for i := range states {
+			states[i].d0 = d
+			d += states[i].seg.length(1.0)
+			states[i].d1 = d
+		}
+		length := d
+		halfWidthAt = func(d float64) float64 {
+			return math.Max(0.0, profile(d, length)/2.0)
+		}
+		for i := range states {
+			states[i].n0 = states[i].n0.Norm(halfWidthAt(states[i].d0))
+			states[i].n1 = states[i].n1.Norm(halfWidthAt(states[i].d1))
+		}
+	}
+
 	rhs, lhs := &Path{}, &Path{}
 	rStart := states[0].p0.Add(states[0].n0)
 	lStart := states[0].p0.Sub(states[0].n0)
@@ -405,16 +433,19 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 	rhsInnerBends := []int{}
 	lhsInnerBends := []int{}
 	for i, cur := range states {
-		switch cur.cmd {
-		case lineToCmd:
+		switch {
+		case profile != nil:
+			rhs = rhs.Join(offsetVariable(cur, halfWidthAt, 1.0))
+			lhs = lhs.Join(offsetVariable(cur, halfWidthAt, -1.0))
+		case cur.cmd == lineToCmd:
 			rEnd := cur.p1.Add(cur.n1)
 			lEnd := cur.p1.Sub(cur.n1)
 			rhs.LineTo(rEnd.X, rEnd.Y)
 			lhs.LineTo(lEnd.X, lEnd.Y)
-		case cubeToCmd:
+		case cur.cmd == cubeToCmd:
 			rhs = rhs.Join(strokeCubicBezier(cur.p0, cur.cp1, cur.cp2, cur.p1, halfWidth, Tolerance))
 			lhs = lhs.Join(strokeCubicBezier(cur.p0, cur.cp1, cur.cp2, cur.p1, -halfWidth, Tolerance))
-		case arcToCmd:
+		case cur.cmd == arcToCmd:
 			rStart := cur.p0.Add(cur.n0)
 			lStart := cur.p0.Sub(cur.n0)
 			rEnd := cur.p1.Add(cur.n1)
@@ -443,6 +474,9 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 			}
 
 			if !cur.n1.Equals(next.n0) {
+				if profile != nil {
+					halfWidth = halfWidthAt(cur.d1)
+				}
 				jr.Join(rhs, lhs, halfWidth, cur.p1, cur.n1, next.n0, cur.r1, next.r0)
 
 				if !cur.n1.Equals(next.n0.Neg()) {
@@ -468,14 +502,57 @@ func offsetSegment(p *Path, halfWidth float64, cr Capper, jr Joiner) (*Path, *Pa
 	}
 
 	// default to CCW direction
+	halfWidthStart, halfWidthEnd := halfWidth, halfWidth
+	if profile != nil {
+		halfWidthStart, halfWidthEnd = halfWidthAt(states[0].d0), halfWidthAt(states[len(states)-1].d1)
+	}
 	lhs = lhs.Reverse()
-	cr.Cap(rhs, halfWidth, states[len(states)-1].p1, states[len(states)-1].n1)
+	cr.Cap(rhs, halfWidthEnd, states[len(states)-1].p1, states[len(states)-1].n1)
 	rhs = rhs.Join(lhs)
-	cr.Cap(rhs, halfWidth, states[0].p0, states[0].n0.Neg())
+	cr.Cap(rhs, halfWidthStart, states[0].p0, states[0].n0.Neg())
 	rhs.Close()
 	return rhs, nil
 }
 
+// offsetVariable returns the offset of a path segment to the right (sign=1) or left (sign=-1) at the half width given along the path, flattened so that the lines deviate at most Tolerance from the offset curve. The end points coincide with the normals of the stroke state, so that the joins connect.
+func offsetVariable(state pathStrokeState, halfWidthAt func(float64) float64, sign float64) *Path {
+	s := state.seg
+	offset := func(t float64) Point {
+		if t == 0.0 {
+			return state.p0.Add(state.n0.Mul(sign))
+		} else if t == 1.0 {
+			return state.p1.Add(state.n1.Mul(sign))
+		}
+		n := s.direction(t).Rot90CW().Norm(sign * halfWidthAt(state.d0+s.length(t)))
+		return s.pos(t).Add(n)
+	}
+
+	p := &Path{}
+	start := offset(0.0)
+	p.MoveTo(start.X, start.Y)
+
+	// start with a few pieces so that variations of the width within the segment are not missed
+	const n = 4
+	for i := 0; i < n; i++ {
+		t0, t1 := float64(i)/n, float64(i+1)/n
+		flattenOffsetVariable(p, offset, t0, t1, offset(t0), offset(t1), 0)
+	}
+	return p
+}
+
+// flattenOffsetVariable adds lines to p for the offset between t0 and t1, subdividing while the offset deviates more than Tolerance from the line between its end points.
+func flattenOffsetVariable(p *Path, offset func(float64) Point, t0, t1 float64, start, end Point, depth int) {
+	const maxDepth = 12
+	tm := (t0 + t1) / 2.0
+	mid := offset(tm)
+	if depth < maxDepth && Tolerance < distanceToLine(mid, start, end) {
+		flattenOffsetVariable(p, offset, t0, tm, start, mid, depth+1)
+		flattenOffsetVariable(p, offset, tm, t1, mid, end, depth+1)
+		return
+	}
+	p.LineTo(end.X, end.Y)
+}
+
 func closeInnerBends(p *Path, indices []int, closed bool) {
 	// closed paths end with a LineTo to the original MoveTo but are not (yet) closed
 	di := 0
@@ -517,7 +594,7 @@ func closeInnerBends(p *Path, indices []int, closed bool) {
 	}
 }
 
-// Offset offsets the path to expand by w and returns a new path. If w is negative it will contract. Path must be closed.
+// Offset offsets the path to expand by w and returns a new path. If w is negative it will contract. Path must be closed. The result may overlap itself at tight curves, see OffsetSettled for a clean outline.
 func (p *Path) Offset(w float64, fillRule FillRule) *Path {
 	if Equal(w, 0.0) {
 		return p
@@ -541,7 +618,7 @@ func (p *Path) Offset(w float64, fillRule FillRule) *Path {
 			useRHS = !useRHS
 		}
 
-		rhs, lhs := offsetSegment(ps, math.Abs(w), ButtCap, RoundJoin)
+		rhs, lhs := offsetSegment(ps, math.Abs(w), nil, ButtCap, RoundJoin)
 		if useRHS {
 			q = q.Append(rhs)
 		} else {
@@ -551,14 +628,70 @@ func (p *Path) Offset(w float64, fillRule FillRule) *Path {
 	return q
 }
 
+// OffsetSettled expands (w > 0) or contracts (w < 0) the area filled by p by a distance of |w| in all directions and returns a new path without self-intersections or overlapping subpaths, which can be used directly as a toolpath. Unlike Offset it is computed as the union or difference of the settled area with the stroke along its outline, so that loops at tight curves and inner joins do not occur and parts that vanish when contracting are removed. Outward corners are rounded.
+func (p *Path) OffsetSettled(w float64, fillRule FillRule) *Path {
+	area := p.Settle(fillRule)
+	if Equal(w, 0.0) || area.Empty() {
+		return area
+	}
+
+	border := area.Stroke(2.0*math.Abs(w), ButtCap, RoundJoin)
+	if 0.0 < w {
+		return area.Or(border)
+	}
+	return area.Not(border)
+}
+
 // Stroke converts a path into a stroke of width w and returns a new path. It uses cr to cap the start and end of the path, and
 // jr to join all path elemtents. If the path closes itself, it will use a join between the start and end instead of capping them.
 // The tolerance is the maximum deviation from the original path when flattening Béziers and optimizing the stroke.
+// The outline may overlap itself at joins and tight curves, which Settle removes.
 func (p *Path) Stroke(w float64, cr Capper, jr Joiner) *Path {
+	return p.stroke(w/2.0, nil, cr, jr)
+}
+
+// StrokeProfile returns the stroke width at distance d along a subpath of the given length, for strokes of variable width such as tapered lines. Negative widths are taken as zero.
+type StrokeProfile func(d, length float64) float64
+
+// WidthStop is a stroke width at an offset between 0 and 1 relative to the length of a subpath.
+type WidthStop struct {
+	Offset float64
+	Width  float64
+}
+
+// WidthStops returns a stroke profile that interpolates linearly between the widths of the stops, which must be ordered by offset. Before the first and after the last stop their widths are used. For example, WidthStops(WidthStop{0.0, 2.0}, WidthStop{1.0, 0.0}) tapers each subpath from a width of 2 to a point.
+func WidthStops(stops ...WidthStop) StrokeProfile {
+	return func(d, length float64) float64 {
+		if len(stops) == 0 {
+			return 0.0
+		}
+
+		t := 0.0
+		if 0.0 < length {
+			t = d / length
+		}
+		if t <= stops[0].Offset {
+			return stops[0].Width
+		}
+		for i, stop := range stops[1:] {
+			if t < stop.Offset {
+				prev := stops[i]
+				return prev.Width + (t-prev.Offset)/(stop.Offset-prev.Offset)*(stop.Width-prev.Width)
+			}
+		}
+		return stops[len(stops)-1].Width
+	}
+}
+
+// StrokeVariable converts a path into a stroke of variable width and returns a new path. The width along each subpath is given by profile, and the path is capped and joined as in Stroke using the widths at the caps and joins. Since the width of a stroke may vary along each segment, the outline is flattened.
+func (p *Path) StrokeVariable(profile StrokeProfile, cr Capper, jr Joiner) *Path {
+	return p.stroke(0.0, profile, cr, jr)
+}
+
+func (p *Path) stroke(halfWidth float64, profile StrokeProfile, cr Capper, jr Joiner) *Path {
 	q := &Path{}
-	halfWidth := w / 2.0
 	for _, ps := range p.Split() {
-		rhs, lhs := offsetSegment(ps, halfWidth, cr, jr)
+		rhs, lhs := offsetSegment(ps, halfWidth, profile, cr, jr)
 		if lhs != nil { // closed path
 			// inner path should go opposite direction to cancel the outer path
 			if ps.CCW() {
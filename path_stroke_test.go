@@ -76,6 +76,49 @@ func TestPathStroke(t *testing.T) {
 	}
 }
 
+func TestPathStrokeVariable(t *testing.T) {
+	Tolerance = 0.01
+	Epsilon = 1e-3
+	taper := WidthStops(WidthStop{0.0, 2.0}, WidthStop{1.0, 0.0})
+	bulge := WidthStops(WidthStop{0.0, 0.0}, WidthStop{0.5, 4.0}, WidthStop{1.0, 0.0})
+	constant := func(d, length float64) float64 { return 2.0 }
+	var tts = []struct {
+		orig    string
+		profile StrokeProfile
+		cp      Capper
+		jr      Joiner
+		stroke  string
+	}{
+		{"M0 0L10 0", taper, ButtCap, RoundJoin, "M0 -1L10 0L0 1z"},
+		{"M0 0L10 0", taper, RoundCap, RoundJoin, "M0 -1L10 0L0 1A1 1 0 0 1 0 -1z"},
+		{"M0 0L10 0L10 10", constant, ButtCap, RoundJoin, "M0 -1L10 -1A1 1 0 0 1 11 0L11 10L9 10L9 1L0 1z"},
+		{"M0 0L10 0L10 10", bulge, ButtCap, MiterJoin, "M0 0L10 -2L12 -2L12 0L10 10L8.33333 1.66667z"},
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			stroke := MustParseSVG(tt.orig).StrokeVariable(tt.profile, tt.cp, tt.jr)
+			test.T(t, stroke, MustParseSVG(tt.stroke))
+		})
+	}
+
+	// curved segments with a constant profile are close to the regular stroke
+	for _, orig := range []string{"M0 0C0 10 10 10 10 0", "M10 0A10 10 0 0 1 -10 0", "M0 0L10 0L10 10z"} {
+		p := MustParseSVG(orig)
+		test.That(t, math.Abs(p.StrokeVariable(constant, RoundCap, RoundJoin).Area()-p.Stroke(2.0, RoundCap, RoundJoin).Area()) < 0.1, orig)
+	}
+}
+
+func TestWidthStops(t *testing.T) {
+	profile := WidthStops(WidthStop{0.25, 1.0}, WidthStop{0.5, 3.0}, WidthStop{1.0, 2.0})
+	test.Float(t, profile(0.0, 10.0), 1.0)
+	test.Float(t, profile(2.5, 10.0), 1.0)
+	test.Float(t, profile(3.75, 10.0), 2.0)
+	test.Float(t, profile(7.5, 10.0), 2.5)
+	test.Float(t, profile(20.0, 10.0), 2.0)
+	test.Float(t, profile(0.0, 0.0), 1.0)
+	test.Float(t, WidthStops()(1.0, 10.0), 0.0)
+}
+
 func TestPathStrokeEllipse(t *testing.T) {
 	rx, ry := 20.0, 10.0
 	nphi := 12
@@ -109,3 +152,32 @@ func TestPathOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestPathOffsetSettled(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		orig   string
+		w      float64
+		offset string
+	}{
+		{"M0 0L10 0L10 10L0 10z", 0.0, "M0 0L10 0L10 10L0 10z"},
+		{"M0 0L10 0L10 10L0 10z", 1.0, "M0 -1L10 -1A1 1 0 0 1 11 0L11 10A1 1 0 0 1 10 11L0 11A1 1 0 0 1 -1 10L-1 0A1 1 0 0 1 0 -1z"},
+		{"M0 0L10 0L10 10L0 10z", -1.0, "M1 9L1 1L9 1L9 9z"},
+		{"M0 0L10 0L10 10L0 10z", -6.0, ""},
+		{"M0 0L20 0L20 2L0 2z", -1.5, ""},
+
+		// inner corners of a U shape are rounded when contracting
+		{"M0 0L10 0L10 10L9 10L9 1L1 1L1 10L0 10z", -0.4, "M0.4 9.6L0.4 0.4L9.6 0.4L9.6 9.6L9.4 9.6L9.4 1A0.4 0.4 0 0 0 9 0.6L1 0.6A0.4 0.4 0 0 0 0.6 1L0.6 9.6z"},
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			offset := MustParseSVG(tt.orig).OffsetSettled(tt.w, NonZero)
+			test.T(t, offset, MustParseSVG(tt.offset))
+			test.T(t, len(offset.SelfIntersections()), 0)
+		})
+	}
+
+	// a circle contracts to a smaller circle
+	circle := Circle(5.0).OffsetSettled(-2.0, NonZero)
+	test.Float(t, circle.Area(), 9.0*math.Pi)
+}
@@ -231,6 +231,79 @@ func TestPathBounds(t *testing.T) {
 	}
 }
 
+func TestPathArea(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		orig     string
+		area     float64
+		centroid Point
+	}{
+		{"", 0.0, Point{0.0, 0.0}},
+		{"L10 0", 0.0, Point{5.0, 0.0}},
+		{"L10 0L10 10L0 10z", 100.0, Point{5.0, 5.0}},
+		{"L10 0L10 10L0 10", 100.0, Point{5.0, 5.0}},
+		{"L0 10L10 10L10 0z", -100.0, Point{5.0, 5.0}},
+		{"L10 0L0 10z", 50.0, Point{10.0 / 3.0, 10.0 / 3.0}},
+		{"L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", 136.0, Point{5.0, 5.0}},
+		{"M0 0Q5 10 10 0z", -100.0 / 3.0, Point{5.0, 2.0}},
+		{"M0 0C0 10 10 10 10 0z", -60.0, Point{5.0, 45.0 / 14.0}},
+		{"M10 0A10 10 0 0 1 -10 0z", 50.0 * math.Pi, Point{0.0, 40.0 / (3.0 * math.Pi)}},
+		{"M3 4A10 5 90 0 1 -7 4A10 5 90 0 1 3 4z", 50.0 * math.Pi, Point{-2.0, 4.0}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			p := MustParseSVG(tt.orig)
+			test.Float(t, p.Area(), tt.area)
+			test.T(t, p.Centroid(), tt.centroid)
+		})
+	}
+}
+
+func TestPathConvexHull(t *testing.T) {
+	var tts = []struct {
+		orig string
+		hull string
+	}{
+		{"", ""},
+		{"M5 5", "M5 5"},
+		{"L10 0L5 0", "M0 0L10 0"},
+		{"L10 0L5 5L10 10L0 10z", "M0 0L10 0L10 10L0 10z"},
+		{"L0 10L10 10L10 0z", "M0 0L10 0L10 10L0 10z"},
+		{"L5 0L10 0L10 10L0 10z", "M0 0L10 0L10 10L0 10z"},
+		{"M0 0L10 0M5 -5L5 5", "M0 0L5 -5L10 0L5 5z"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).ConvexHull(), MustParseSVG(tt.hull))
+		})
+	}
+}
+
+func TestPathOrientedBounds(t *testing.T) {
+	Epsilon = 1e-6
+	var tts = []struct {
+		p      *Path
+		bounds Rect
+		rot    float64
+	}{
+		{&Path{}, Rect{}, 0.0},
+		{MustParseSVG("M5 5"), Rect{5.0, 5.0, 0.0, 0.0}, 0.0},
+		{MustParseSVG("L10 10"), Rect{0.0, 0.0, math.Sqrt(200.0), 0.0}, 45.0},
+		{Rectangle(10.0, 5.0), Rect{0.0, 0.0, 10.0, 5.0}, 0.0},
+		{Rectangle(10.0, 5.0).Translate(2.0, 3.0), Rect{2.0, 3.0, 10.0, 5.0}, 0.0},
+		{Rectangle(10.0, 5.0).Transform(Identity.Rotate(30.0)), Rect{0.0, 0.0, 10.0, 5.0}, 30.0},
+		{Rectangle(10.0, 5.0).Transform(Identity.Rotate(120.0)), Rect{-5.0, 0.0, 5.0, 10.0}, 30.0},
+		{MustParseSVG("L10 0L10 10L0 10zM5 -5L15 5L5 15L-5 5z"), Rect{0.0, -10.0 / math.Sqrt(2.0), 20.0 / math.Sqrt(2.0), 20.0 / math.Sqrt(2.0)}, 45.0},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p.String(), func(t *testing.T) {
+			bounds, rot := tt.p.OrientedBounds()
+			test.T(t, bounds, tt.bounds)
+			test.Float(t, rot, tt.rot)
+		})
+	}
+}
+
 // for quadratic Bézier use https://www.wolframalpha.com/input/?i=length+of+the+curve+%7Bx%3D2*(1-t)*t*50.00+%2B+t%5E2*100.00,+y%3D2*(1-t)*t*66.67+%2B+t%5E2*0.00%7D+from+0+to+1
 // for cubic Bézier use https://www.wolframalpha.com/input/?i=length+of+the+curve+%7Bx%3D3*(1-t)%5E2*t*0.00+%2B+3*(1-t)*t%5E2*100.00+%2B+t%5E3*100.00,+y%3D3*(1-t)%5E2*t*66.67+%2B+3*(1-t)*t%5E2*66.67+%2B+t%5E3*0.00%7D+from+0+to+1
 // for ellipse use https://www.wolframalpha.com/input/?i=length+of+the+curve+%7Bx%3D10.00*cos(t),+y%3D20.0*sin(t)%7D+from+0+to+pi
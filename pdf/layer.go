@@ -0,0 +1,79 @@
+package pdf
+
+import (
+	"fmt"
+)
+
+// BeginLayer starts the named layer, which is written as an optional content group that PDF viewers can show or hide independently. The layer contains the content rendered until the matching EndLayer, including on following pages, and layers with the same name are the same optional content group. Layers can be nested, in which case content is only visible when all its layers are visible.
+func (r *PDF) BeginLayer(name string) {
+	r.w.endContent()
+
+	ref, ok := r.w.pdf.layers[name]
+	if !ok {
+		ref = r.w.pdf.reserveObject()
+		r.w.pdf.layers[name] = ref
+		r.w.pdf.layerNames = append(r.w.pdf.layerNames, name)
+	}
+	r.w.pdf.layerStack = append(r.w.pdf.layerStack, name)
+	r.w.beginLayer(name)
+}
+
+// EndLayer ends the last layer started by BeginLayer.
+func (r *PDF) EndLayer() {
+	if len(r.w.pdf.layerStack) == 0 {
+		return
+	}
+	r.w.endContent()
+	r.w.pdf.layerStack = r.w.pdf.layerStack[:len(r.w.pdf.layerStack)-1]
+	r.w.endLayer()
+}
+
+// beginLayer starts a marked-content sequence for the optional content group of the named layer and adds it to the page's resources.
+func (w *pdfPageWriter) beginLayer(name string) {
+	if _, ok := w.resources["Properties"]; !ok {
+		w.resources["Properties"] = pdfDict{}
+	}
+	for i, layerName := range w.pdf.layerNames {
+		if layerName == name {
+			resName := pdfName(fmt.Sprintf("OC%d", i))
+			w.resources["Properties"].(pdfDict)[resName] = w.pdf.layers[name]
+			fmt.Fprintf(w, " /OC /%v BDC", resName)
+			break
+		}
+	}
+	w.openLayers++
+}
+
+// endLayer ends the marked-content sequence started by beginLayer.
+func (w *pdfPageWriter) endLayer() {
+	if 0 < w.openLayers {
+		w.EndMarkedContent()
+		w.openLayers--
+	}
+}
+
+// writeLayers writes the optional content groups of the layers and returns the optional content properties for the document catalog, or nil if there are no layers.
+func (w *pdfWriter) writeLayers() pdfDict {
+	if len(w.layerNames) == 0 {
+		return nil
+	} else if w.profile == PDFX1a {
+		w.forbid("layers")
+	}
+
+	ocgs := pdfArray{}
+	for _, name := range w.layerNames {
+		ref := w.layers[name]
+		w.writeReservedObject(ref, pdfDict{
+			"Type": pdfName("OCG"),
+			"Name": pdfTextString(name),
+		})
+		ocgs = append(ocgs, ref)
+	}
+	return pdfDict{
+		"OCGs": ocgs,
+		"D": pdfDict{
+			"Name":  "Layers",
+			"Order": ocgs,
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dtrenin7/canvas"
+	"github.com/dtrenin7/test"
+)
+
+func TestPDFLayers(t *testing.T) {
+	rect := canvas.Rectangle(10.0, 10.0)
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetCompression(false)
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.BeginLayer("Dimensions")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.BeginLayer("Annotations")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.NewPage(100, 100)
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.EndLayer()
+	pdf.EndLayer()
+	pdf.EndLayer() // unbalanced layers are ignored
+	pdf.BeginLayer("Dimensions")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	// layers are 5 and 6, and open layers continue on the second page
+	test.That(t, strings.Contains(out, "cm 0 0 m 10 0 l 10 10 l 0 10 l f /OC /OC0 BDC 0 0 m 10 0 l 10 10 l 0 10 l f /OC /OC1 BDC 0 0 m 10 0 l 10 10 l 0 10 l f EMC EMC\n"), "layers of the first page")
+	test.That(t, strings.Contains(out, "cm /OC /OC0 BDC /OC /OC1 BDC 0 0 m 10 0 l 10 10 l 0 10 l f EMC EMC /OC /OC0 BDC 0 0 m 10 0 l 10 10 l 0 10 l f EMC\n"), "layers of the second page")
+	test.That(t, strings.Contains(out, "/Resources << /Properties << /OC0 5 0 R /OC1 6 0 R >> >>"), "layers in page resources")
+	test.That(t, strings.Contains(out, "5 0 obj\n<< /Type /OCG /Name (Dimensions) >>"), "first layer")
+	test.That(t, strings.Contains(out, "6 0 obj\n<< /Type /OCG /Name (Annotations) >>"), "second layer")
+	test.That(t, strings.Contains(out, "/OCProperties << /D << /Name (Layers) /Order [5 0 R 6 0 R] >> /OCGs [5 0 R 6 0 R] >>"), "layers in catalog")
+
+	// layers are not allowed in PDF/X-1a
+	buf.Reset()
+	pdf = New(buf, 100, 100)
+	pdf.SetProfile(PDFX1a)
+	pdf.SetInfo("Title", "", "", "")
+	pdf.BeginLayer("Dimensions")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.EndLayer()
+	err = pdf.Close()
+	test.T(t, err.Error(), "PDF: PDF/X-1a does not allow layers")
+}
@@ -0,0 +1,143 @@
+package pdf
+
+import (
+	"sort"
+)
+
+// Outline is an item in the document outline, also known as bookmarks, which jumps to a position on a page when clicked and which can have child items.
+type Outline struct {
+	Title    string
+	Page     int     // page number starting at 1
+	Y        float64 // position on the page in mm shown at the top of the window, or zero to show the whole page
+	Open     bool    // whether the child items are shown initially
+	Children []*Outline
+}
+
+// Add adds a child item to the outline item and returns it.
+func (o *Outline) Add(title string, page int, y float64) *Outline {
+	child := &Outline{Title: title, Page: page, Y: y}
+	o.Children = append(o.Children, child)
+	return child
+}
+
+// AddOutline adds a top-level item to the document outline and returns it, child items can be added with Outline.Add. Pages are numbered in the order they were added starting at 1, and may refer to pages that are added later.
+func (r *PDF) AddOutline(title string, page int, y float64) *Outline {
+	item := &Outline{Title: title, Page: page, Y: y}
+	r.w.pdf.outlines = append(r.w.pdf.outlines, item)
+	return item
+}
+
+// PageLabelStyle is the numbering style of page labels.
+type PageLabelStyle int
+
+// see PageLabelStyle
+const (
+	DecimalLabels    PageLabelStyle = iota // 1, 2, 3, ...
+	UpperRomanLabels                       // I, II, III, ...
+	LowerRomanLabels                       // i, ii, iii, ...
+	UpperAlphaLabels                       // A, B, C, ..., AA, BB, ...
+	LowerAlphaLabels                       // a, b, c, ..., aa, bb, ...
+	NoNumberLabels                         // only the prefix
+)
+
+// SetPageLabels sets the page labels that PDF viewers show instead of the page numbers, from the given page number starting at 1 up to the next page with labels set. Labels consist of the prefix followed by the number of the page in the given style, where the first page has number start. For example, front matter can be labelled with lower-case roman numerals and the main matter from its first page onwards with decimal numerals starting at 1.
+func (r *PDF) SetPageLabels(page int, style PageLabelStyle, prefix string, start int) {
+	if page < 1 {
+		return
+	}
+
+	label := pdfDict{}
+	switch style {
+	case DecimalLabels:
+		label["S"] = pdfName("D")
+	case UpperRomanLabels:
+		label["S"] = pdfName("R")
+	case LowerRomanLabels:
+		label["S"] = pdfName("r")
+	case UpperAlphaLabels:
+		label["S"] = pdfName("A")
+	case LowerAlphaLabels:
+		label["S"] = pdfName("a")
+	}
+	if prefix != "" {
+		label["P"] = pdfTextString(prefix)
+	}
+	if 1 < start {
+		label["St"] = start
+	}
+	r.w.pdf.pageLabels[page-1] = label
+}
+
+// writeOutline writes the document outline and returns its reference, or zero if there is no outline.
+func (w *pdfWriter) writeOutline() pdfRef {
+	if len(w.outlines) == 0 {
+		return 0
+	}
+
+	ref := w.reserveObject()
+	first, last, count := w.writeOutlineItems(w.outlines, ref)
+	w.writeReservedObject(ref, pdfDict{
+		"Type":  pdfName("Outlines"),
+		"First": first,
+		"Last":  last,
+		"Count": count,
+	})
+	return ref
+}
+
+// writeOutlineItems writes the outline items and their children, and returns the references to the first and last item and the number of visible items.
+func (w *pdfWriter) writeOutlineItems(items []*Outline, parent pdfRef) (pdfRef, pdfRef, int) {
+	refs := make([]pdfRef, len(items))
+	for i := range items {
+		refs[i] = w.reserveObject()
+	}
+
+	count := 0
+	for i, item := range items {
+		dict := pdfDict{
+			"Title":  pdfTextString(item.Title),
+			"Parent": parent,
+		}
+		if dest := w.pageDestination(item.Page, item.Y); dest != nil {
+			dict["Dest"] = dest
+		}
+		if 0 < i {
+			dict["Prev"] = refs[i-1]
+		}
+		if i+1 < len(items) {
+			dict["Next"] = refs[i+1]
+		}
+		count++
+		if 0 < len(item.Children) {
+			first, last, n := w.writeOutlineItems(item.Children, refs[i])
+			dict["First"] = first
+			dict["Last"] = last
+			if item.Open {
+				dict["Count"] = n
+				count += n
+			} else {
+				dict["Count"] = -n
+			}
+		}
+		w.writeReservedObject(refs[i], dict)
+	}
+	return refs[0], refs[len(refs)-1], count
+}
+
+// pageLabelNums returns the number tree array of the page labels, which are keyed by their zero-based page index.
+func (w *pdfWriter) pageLabelNums() pdfArray {
+	indices := make([]int, 0, len(w.pageLabels))
+	for index := range w.pageLabels {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	nums := pdfArray{}
+	if len(indices) != 0 && indices[0] != 0 {
+		nums = append(nums, 0, pdfDict{"S": pdfName("D")}) // the first page must have a label
+	}
+	for _, index := range indices {
+		nums = append(nums, index, w.pageLabels[index])
+	}
+	return nums
+}
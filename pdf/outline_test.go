@@ -0,0 +1,52 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func TestPDFOutline(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetCompression(false)
+	pdf.NewPage(100, 100)
+	chapter := pdf.AddOutline("Chapter", 1, 0)
+	chapter.Open = true
+	chapter.Add("Section", 2, 50)
+	chapter.Add("Über", 2, 20).Add("Subsection", 2, 10)
+	pdf.AddOutline("Appendix", 3, 0)
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	// pages are 4 and 5, items are 9 (Chapter), 10 (Appendix), 11 (Section), 12 (Über) and 13 (Subsection), outline root is 8
+	test.That(t, strings.Contains(out, "/Type /Outlines /Count 4 /First 9 0 R /Last 10 0 R"), "outline root")
+	test.That(t, strings.Contains(out, "9 0 obj\n<< /Count 2 /Dest [4 0 R /Fit] /First 11 0 R /Last 12 0 R /Next 10 0 R /Parent 8 0 R /Title (Chapter) >>"), "open item")
+	test.That(t, strings.Contains(out, "10 0 obj\n<< /Parent 8 0 R /Prev 9 0 R /Title (Appendix) >>"), "item to page that does not exist")
+	test.That(t, strings.Contains(out, "11 0 obj\n<< /Dest [5 0 R /XYZ null 141.73228 null] /Next 12 0 R /Parent 9 0 R /Title (Section) >>"), "child item")
+	test.That(t, strings.Contains(out, "12 0 obj\n<< /Count -1 /Dest [5 0 R /XYZ null 56.692913 null] /First 13 0 R /Last 13 0 R /Parent 9 0 R /Prev 11 0 R /Title (\xFE\xFF\x00\xDC\x00b\x00e\x00r) >>"), "closed item")
+	test.That(t, strings.Contains(out, "/Outlines 8 0 R /PageMode /UseOutlines"), "outline in catalog")
+}
+
+func TestPDFPageLabels(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetCompression(false)
+	pdf.SetPageLabels(0, DecimalLabels, "", 1)
+	pdf.SetPageLabels(2, LowerRomanLabels, "", 1)
+	pdf.SetPageLabels(4, DecimalLabels, "", 1)
+	pdf.SetPageLabels(6, UpperAlphaLabels, "A-", 3)
+	err := pdf.Close()
+	test.Error(t, err)
+	test.That(t, strings.Contains(buf.String(), "/PageLabels << /Nums [0 << /S /D >> 1 << /S /r >> 3 << /S /D >> 5 << /P (A-) /S /A /St 3 >>]"), "page labels in catalog")
+
+	buf.Reset()
+	pdf = New(buf, 100, 100)
+	pdf.SetPageLabels(1, NoNumberLabels, "Cover", 1)
+	err = pdf.Close()
+	test.Error(t, err)
+	test.That(t, strings.Contains(buf.String(), "/PageLabels << /Nums [0 << /P (Cover) >>]"), "labels without numbers")
+}
@@ -0,0 +1,300 @@
+package pdf
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Profile is an output profile that restricts the PDF to a subset of features as required by standards for archiving or printing.
+type Profile int
+
+// see Profile
+const (
+	NoProfile Profile = iota
+	PDFA2b            // PDF/A-2b for long-term archiving
+	PDFX1a            // PDF/X-1a:2003 for printing, colors are converted to CMYK and transparency is not allowed
+	PDFX4             // PDF/X-4 for printing with transparency and RGB colors
+)
+
+func (profile Profile) String() string {
+	switch profile {
+	case PDFA2b:
+		return "PDF/A-2b"
+	case PDFX1a:
+		return "PDF/X-1a"
+	case PDFX4:
+		return "PDF/X-4"
+	}
+	return "PDF"
+}
+
+// version returns the PDF version that the profile is based on.
+func (profile Profile) version() string {
+	switch profile {
+	case PDFX1a:
+		return "1.4"
+	case PDFX4:
+		return "1.6"
+	}
+	return "1.7"
+}
+
+// isPDFX returns true for the PDF/X profiles.
+func (profile Profile) isPDFX() bool {
+	return profile == PDFX1a || profile == PDFX4
+}
+
+// SetProfile sets the output profile, which must be set before rendering. The document is written with the XMP metadata, document ID, output intent and page boxes required by the profile, and Close returns an error when the drawing uses features that the profile forbids. PDF/A-2b and PDF/X-4 use the sRGB color space as output intent by default, and PDF/X-1a uses the registered CGATS TR 001 (SWOP) printing condition, see SetOutputIntent. PDF/X requires a title, see SetInfo.
+func (r *PDF) SetProfile(profile Profile) {
+	r.w.pdf.profile = profile
+	if profile == PDFX1a {
+		r.w.pdf.intentID, r.w.pdf.intentICC = "CGATS TR 001", nil
+	} else {
+		r.w.pdf.intentID, r.w.pdf.intentICC = "sRGB IEC61966-2.1", sRGBProfile()
+	}
+}
+
+// SetOutputIntent sets the output intent of an output profile, which is the printing condition or color space that the document is intended for. The identifier is the name of the condition such as "FOGRA39", preferably as registered at the ICC. The ICC profile describes the condition, and may only be nil for registered conditions with PDF/X-1a. Colors are converted to the output intent by the viewer or printer. It must be called after SetProfile.
+func (r *PDF) SetOutputIntent(identifier string, icc []byte) {
+	r.w.pdf.intentID, r.w.pdf.intentICC = identifier, icc
+}
+
+// forbid records that the drawing uses a feature that the output profile forbids, which is returned as an error by Close.
+func (w *pdfWriter) forbid(feature string) {
+	for _, f := range w.violations {
+		if f == feature {
+			return
+		}
+	}
+	w.violations = append(w.violations, feature)
+}
+
+// writeOutputIntent writes the output intent of the output profile and returns it.
+func (w *pdfWriter) writeOutputIntent() pdfDict {
+	intent := pdfDict{
+		"Type":                      pdfName("OutputIntent"),
+		"S":                         pdfName("GTS_PDFX"),
+		"OutputConditionIdentifier": w.intentID,
+		"Info":                      w.intentID,
+		"RegistryName":              "http://www.color.org",
+	}
+	if w.profile == PDFA2b {
+		intent["S"] = pdfName("GTS_PDFA1")
+	}
+
+	n := iccComponents(w.intentICC)
+	if w.intentICC == nil && w.profile != PDFX1a {
+		w.forbid("output intents without ICC profile")
+	} else if w.intentICC != nil && n == 0 {
+		w.forbid("invalid ICC profiles")
+	} else if w.profile == PDFX1a && w.intentICC != nil && n != 4 {
+		w.forbid("output intents that are not CMYK")
+	}
+	if w.intentICC != nil && n != 0 {
+		intent["DestOutputProfile"] = w.writeObject(pdfStream{
+			dict: pdfDict{
+				"N":      n,
+				"Filter": pdfFilterFlate,
+			},
+			stream: w.intentICC,
+		})
+	}
+	return intent
+}
+
+// defaultRGB returns the ICC based sRGB color space that replaces DeviceRGB when the output intent is not RGB, or nil otherwise. PDF/X-1a instead converts colors to DeviceCMYK.
+func (w *pdfWriter) defaultRGB() pdfArray {
+	if w.profile == NoProfile || w.profile == PDFX1a || iccComponents(w.intentICC) == 3 {
+		return nil
+	}
+	if w.defaultRGBRef == 0 {
+		w.defaultRGBRef = w.writeObject(pdfStream{
+			dict: pdfDict{
+				"N":      3,
+				"Filter": pdfFilterFlate,
+			},
+			stream: sRGBProfile(),
+		})
+	}
+	return pdfArray{pdfName("ICCBased"), w.defaultRGBRef}
+}
+
+// documentID returns the identifier of the document that is derived from its metadata.
+func (w *pdfWriter) documentID(created time.Time) []byte {
+	h := md5.New()
+	fmt.Fprintf(h, "%v\n%v\n%v\n%v\n%v\n%d", created.Format(time.RFC3339Nano), w.title, w.subject, w.keywords, w.author, w.pos)
+	return h.Sum(nil)
+}
+
+// xmpMetadata returns the XMP metadata packet of the document as required by the output profile, it must match the document information dictionary.
+func (w *pdfWriter) xmpMetadata(created time.Time, id []byte) []byte {
+	escape := func(s string) string {
+		sb := &strings.Builder{}
+		xml.EscapeText(sb, []byte(s))
+		return sb.String()
+	}
+	uuid := fmt.Sprintf("uuid:%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+	date := created.Format("2006-01-02T15:04:05Z07:00")
+
+	b := &bytes.Buffer{}
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	b.WriteString("<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	b.WriteString("<rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\" xmlns:xmpMM=\"http://ns.adobe.com/xap/1.0/mm/\"")
+	switch w.profile {
+	case PDFA2b:
+		b.WriteString(" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\"")
+	case PDFX1a:
+		b.WriteString(" xmlns:pdfx=\"http://ns.adobe.com/pdfx/1.3/\"")
+	case PDFX4:
+		b.WriteString(" xmlns:pdfxid=\"http://www.npes.org/pdfx/ns/id/\"")
+	}
+	b.WriteString(">\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if w.title != "" {
+		fmt.Fprintf(b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", escape(w.title))
+	}
+	if w.subject != "" {
+		fmt.Fprintf(b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", escape(w.subject))
+	}
+	if w.author != "" {
+		fmt.Fprintf(b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", escape(w.author))
+	}
+	if w.keywords != "" {
+		fmt.Fprintf(b, "<pdf:Keywords>%s</pdf:Keywords>\n", escape(w.keywords))
+	}
+	b.WriteString("<pdf:Producer>dtrenin7/canvas</pdf:Producer>\n")
+	fmt.Fprintf(b, "<xmp:CreateDate>%s</xmp:CreateDate>\n<xmp:ModifyDate>%s</xmp:ModifyDate>\n<xmp:MetadataDate>%s</xmp:MetadataDate>\n", date, date, date)
+	fmt.Fprintf(b, "<xmpMM:DocumentID>%s</xmpMM:DocumentID>\n<xmpMM:InstanceID>%s</xmpMM:InstanceID>\n", uuid, uuid)
+	b.WriteString("<xmpMM:VersionID>1</xmpMM:VersionID>\n<xmpMM:RenditionClass>default</xmpMM:RenditionClass>\n")
+	switch w.profile {
+	case PDFA2b:
+		b.WriteString("<pdfaid:part>2</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>\n")
+	case PDFX1a:
+		b.WriteString("<pdf:Trapped>False</pdf:Trapped>\n")
+		b.WriteString("<pdfx:GTS_PDFXVersion>PDF/X-1:2003</pdfx:GTS_PDFXVersion>\n<pdfx:GTS_PDFXConformance>PDF/X-1a:2003</pdfx:GTS_PDFXConformance>\n")
+	case PDFX4:
+		b.WriteString("<pdf:Trapped>False</pdf:Trapped>\n")
+		b.WriteString("<pdfxid:GTS_PDFXVersion>PDF/X-4</pdfxid:GTS_PDFXVersion>\n")
+	}
+	b.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n")
+	b.WriteString("<?xpacket end=\"w\"?>")
+	return b.Bytes()
+}
+
+// iccComponents returns the number of color components of the ICC profile's color space, or zero if the profile is invalid or has an unsupported color space.
+func iccComponents(icc []byte) int {
+	if len(icc) < 128 || string(icc[36:40]) != "acsp" {
+		return 0
+	}
+	switch string(icc[16:20]) {
+	case "GRAY":
+		return 1
+	case "RGB ":
+		return 3
+	case "CMYK":
+		return 4
+	}
+	return 0
+}
+
+// sRGBProfile returns an ICC version 2 display profile of the sRGB color space with primaries adapted to the D50 illuminant.
+func sRGBProfile() []byte {
+	s15Fixed16 := func(f float64) uint32 {
+		return uint32(int32(math.Round(f * 65536.0)))
+	}
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		binary.BigEndian.PutUint32(b[8:], s15Fixed16(x))
+		binary.BigEndian.PutUint32(b[12:], s15Fixed16(y))
+		binary.BigEndian.PutUint32(b[16:], s15Fixed16(z))
+		return b
+	}
+
+	desc := "sRGB IEC61966-2.1"
+	descTag := make([]byte, 12+len(desc)+1+4+4+2+1+67)
+	copy(descTag, "desc")
+	binary.BigEndian.PutUint32(descTag[8:], uint32(len(desc)+1))
+	copy(descTag[12:], desc)
+
+	cprtTag := append([]byte("text\x00\x00\x00\x00No copyright, use freely"), 0)
+
+	// the sRGB transfer function is sampled, which is more accurate than a gamma of 2.2
+	trcTag := make([]byte, 12+2*1024)
+	copy(trcTag, "curv")
+	binary.BigEndian.PutUint32(trcTag[8:], 1024)
+	for i := 0; i < 1024; i++ {
+		v := float64(i) / 1023.0
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(trcTag[12+2*i:], uint16(math.Round(v*65535.0)))
+	}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", descTag},
+		{"cprt", cprtTag},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trcTag},
+		{"gTRC", trcTag},
+		{"bTRC", trcTag},
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntrRGB XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2000) // creation date
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	binary.BigEndian.PutUint32(header[68:], s15Fixed16(0.9642)) // D50 illuminant
+	binary.BigEndian.PutUint32(header[72:], s15Fixed16(1.0))
+	binary.BigEndian.PutUint32(header[76:], s15Fixed16(0.8249))
+
+	table := make([]byte, 4+12*len(tags))
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	data := []byte{}
+	offsets := map[string]int{} // tags with the same data share it
+	for i, tag := range tags {
+		offset, ok := offsets[string(tag.data)]
+		if !ok {
+			offset = len(header) + len(table) + len(data)
+			offsets[string(tag.data)] = offset
+			data = append(data, tag.data...)
+			for len(data)%4 != 0 {
+				data = append(data, 0)
+			}
+		}
+		copy(table[4+12*i:], tag.sig)
+		binary.BigEndian.PutUint32(table[4+12*i+4:], uint32(offset))
+		binary.BigEndian.PutUint32(table[4+12*i+8:], uint32(len(tag.data)))
+	}
+
+	icc := append(append(header, table...), data...)
+	binary.BigEndian.PutUint32(icc, uint32(len(icc)))
+	return icc
+}
+
+// rgbToCMYK converts the RGB color components between 0 and 1 to CMYK without color management.
+func rgbToCMYK(r, g, b float64) (float64, float64, float64, float64) {
+	k := 1.0 - math.Max(r, math.Max(g, b))
+	if k == 1.0 {
+		return 0.0, 0.0, 0.0, 1.0
+	}
+	return (1.0 - r - k) / (1.0 - k), (1.0 - g - k) / (1.0 - k), (1.0 - b - k) / (1.0 - k), k
+}
@@ -0,0 +1,137 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/dtrenin7/canvas"
+	"github.com/dtrenin7/test"
+)
+
+func TestPDFProfileA2b(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetProfile(PDFA2b)
+	pdf.SetCompression(false)
+	pdf.SetInfo("Invoice & receipt", "", "", "Jane")
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), canvas.DefaultStyle, canvas.Identity)
+	pdf.RenderImage(image.NewRGBA(image.Rect(0, 0, 1, 1)), canvas.Identity)
+	pdf.AddLink("https://example.com/", canvas.Rect{X: 0, Y: 0, W: 10, H: 10})
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	test.That(t, strings.HasPrefix(out, "%PDF-1.7\n%\xE2\xE3\xCF\xD3\n"), "binary header")
+	test.That(t, strings.Contains(out, "/OutputIntents [<< /Type /OutputIntent /DestOutputProfile "), "output intent with ICC profile")
+	test.That(t, strings.Contains(out, "/OutputConditionIdentifier (sRGB IEC61966-2.1) /RegistryName (http://www.color.org) /S /GTS_PDFA1 >>]"), "PDF/A output intent")
+	test.That(t, strings.Contains(out, "<< /Type /Metadata /Subtype /XML /Length "), "XMP metadata stream")
+	test.That(t, strings.Contains(out, "<pdfaid:part>2</pdfaid:part>\n<pdfaid:conformance>B</pdfaid:conformance>"), "PDF/A identification")
+	test.That(t, strings.Contains(out, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">Invoice &amp; receipt</rdf:li></rdf:Alt></dc:title>"), "escaped title")
+	test.That(t, strings.Contains(out, "/Author (Jane)"), "document information")
+	test.That(t, strings.Contains(out, "/F 4 /P 4 0 R"), "printable link")
+	test.That(t, strings.Contains(out, "/ID [("), "document ID")
+	test.That(t, !strings.Contains(out, "/Interpolate"), "no image interpolation")
+
+	buf.Reset()
+	pdf = New(buf, 100, 100)
+	pdf.SetProfile(PDFA2b)
+	pdf.AddTextAnnotation(0, 0, "note")
+	err = pdf.Close()
+	test.T(t, err.Error(), "PDF: PDF/A-2b does not allow annotations without appearance streams")
+}
+
+func TestPDFProfileX1a(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetProfile(PDFX1a)
+	pdf.SetCompression(false)
+	pdf.SetInfo("Flyer", "", "", "")
+	style := canvas.DefaultStyle
+	style.Fill.Color = canvas.Red
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	pdf.RenderImage(image.NewGray(image.Rect(0, 0, 1, 1)), canvas.Identity)
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	test.That(t, strings.HasPrefix(out, "%PDF-1.4\n"), "PDF version")
+	test.That(t, strings.Contains(out, " 0 1 1 0 k 0 0 m"), "colors are converted to CMYK")
+	test.That(t, strings.Contains(out, "/ColorSpace /DeviceCMYK"), "images are converted to CMYK")
+	test.That(t, !strings.Contains(out, "/Group"), "no transparency groups")
+	test.That(t, strings.Contains(out, "/TrimBox [0 0 283.46457 283.46457]"), "trim box")
+	test.That(t, strings.Contains(out, "/OutputConditionIdentifier (CGATS TR 001) /RegistryName (http://www.color.org) /S /GTS_PDFX >>]"), "registered output intent")
+	test.That(t, !strings.Contains(out, "/DestOutputProfile"), "registered output intent without ICC profile")
+	test.That(t, strings.Contains(out, "/GTS_PDFXConformance (PDF/X-1a:2003) /GTS_PDFXVersion (PDF/X-1:2003)"), "PDF/X identification")
+	test.That(t, strings.Contains(out, "/Trapped /False"), "trapping")
+
+	buf.Reset()
+	pdf = New(buf, 100, 100)
+	pdf.SetProfile(PDFX1a)
+	style.Fill.Color = color.RGBA{128, 0, 0, 128}
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	pdf.AddLink("https://example.com/", canvas.Rect{X: 0, Y: 0, W: 10, H: 10})
+	err = pdf.Close()
+	test.T(t, err.Error(), "PDF: PDF/X-1a does not allow transparency, annotations, documents without title")
+}
+
+func TestPDFProfileX4(t *testing.T) {
+	cmyk := make([]byte, 128)
+	binary.BigEndian.PutUint32(cmyk, 128)
+	copy(cmyk[12:], "prtrCMYKLab ")
+	copy(cmyk[36:], "acsp")
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetProfile(PDFX4)
+	pdf.SetOutputIntent("FOGRA39", cmyk)
+	pdf.SetCompression(false)
+	pdf.SetInfo("Poster", "", "", "")
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), canvas.DefaultStyle, canvas.Identity)
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	test.That(t, strings.HasPrefix(out, "%PDF-1.6\n"), "PDF version")
+	test.That(t, strings.Contains(out, "/DestOutputProfile 7 0 R /Info (FOGRA39) /OutputConditionIdentifier (FOGRA39)"), "output intent")
+	test.That(t, strings.Contains(out, "/N 4"), "CMYK output profile")
+	test.That(t, strings.Contains(out, "/Group << /Type /Group /CS [/ICCBased 6 0 R] /I true /S /Transparency >>"), "calibrated transparency group")
+	test.That(t, strings.Contains(out, "/Resources << /ColorSpace << /DefaultRGB [/ICCBased 6 0 R] >> >>"), "calibrated RGB colors")
+	test.That(t, strings.Contains(out, "<pdfxid:GTS_PDFXVersion>PDF/X-4</pdfxid:GTS_PDFXVersion>"), "PDF/X identification")
+
+	buf.Reset()
+	pdf = New(buf, 100, 100)
+	pdf.SetProfile(PDFX4)
+	pdf.SetOutputIntent("Unknown", nil)
+	err = pdf.Close()
+	test.T(t, err.Error(), "PDF: PDF/X-4 does not allow documents without title, output intents without ICC profile")
+}
+
+func TestSRGBProfile(t *testing.T) {
+	icc := sRGBProfile()
+	test.T(t, iccComponents(icc), 3)
+	test.T(t, int(binary.BigEndian.Uint32(icc)), len(icc))
+	test.T(t, iccComponents(icc[:100]), 0)
+}
+
+func TestRGBToCMYK(t *testing.T) {
+	var tts = []struct {
+		r, g, b    float64
+		c, m, y, k float64
+	}{
+		{0.0, 0.0, 0.0, 0.0, 0.0, 0.0, 1.0},
+		{1.0, 1.0, 1.0, 0.0, 0.0, 0.0, 0.0},
+		{1.0, 0.0, 0.0, 0.0, 1.0, 1.0, 0.0},
+		{0.5, 0.25, 0.0, 0.0, 0.5, 1.0, 0.5},
+	}
+	for _, tt := range tts {
+		c, m, y, k := rgbToCMYK(tt.r, tt.g, tt.b)
+		test.Float(t, c, tt.c)
+		test.Float(t, m, tt.m)
+		test.Float(t, y, tt.y)
+		test.Float(t, k, tt.k)
+	}
+}
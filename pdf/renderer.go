@@ -6,6 +6,7 @@ import (
 	"encoding/ascii85"
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"image"
 	"image/color"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/dtrenin7/canvas"
 	canvasFont "github.com/dtrenin7/canvas/font"
@@ -42,6 +44,11 @@ func (r *PDF) SetCompression(compress bool) {
 	r.w.pdf.SetCompression(compress)
 }
 
+// SetFontSubsetting sets whether only the used glyphs of fonts are embedded, which is enabled by default. It must be set before rendering any text.
+func (r *PDF) SetFontSubsetting(subset bool) {
+	r.w.pdf.SetFontSubsetting(subset)
+}
+
 func (r *PDF) SetInfo(title, subject, keywords, author string) {
 	r.w.pdf.SetTitle(title)
 	r.w.pdf.SetSubject(subject)
@@ -63,13 +70,16 @@ func (r *PDF) Size() (float64, float64) {
 }
 
 func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	fill := style.FillColor.A != 0
-	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
-	differentAlpha := fill && stroke && style.FillColor.A != style.StrokeColor.A
+	r.w.beginContent()
+	fill := style.HasFill()
+	stroke := style.HasStroke()
+	differentAlpha := fill && stroke && style.Fill.Color.A != style.Stroke.Color.A
 
-	// PDFs don't support the arcs joiner, miter joiner (not clipped), or miter joiner (clipped) with non-bevel fallback
+	// PDFs don't support variable stroke widths, the arcs joiner, miter joiner (not clipped), or miter joiner (clipped) with non-bevel fallback
 	strokeUnsupported := false
-	if _, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok {
+	if style.StrokeProfile != nil {
+		strokeUnsupported = true
+	} else if _, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok {
 		strokeUnsupported = true
 	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
 		if math.IsNaN(miter.Limit) {
@@ -87,6 +97,34 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 
 	closed := false
 	data := path.Transform(m).ToPDF()
+	if style.Fill.IsGradient() || style.Stroke.IsGradient() {
+		// paint fill and stroke separately, gradients are painted as shadings clipped to the path or stroke outline
+		if fill {
+			fillStyle := style
+			fillStyle.Stroke = canvas.Paint{}
+			if !style.Fill.IsGradient() || !r.w.FillGradient(data, style.FillRule, style.Fill.Gradient, path.Bounds(), m) {
+				fillStyle.Fill.Gradient = nil
+				r.RenderPath(path, fillStyle, m)
+			}
+		}
+		if stroke {
+			strokeStyle := style
+			strokeStyle.Fill = canvas.Paint{}
+			if style.Stroke.IsGradient() {
+				outline := path.Transform(m)
+				if 0 < len(style.Dashes) {
+					outline = outline.Dash(style.DashOffset, style.Dashes...)
+				}
+				outline = style.StrokeOutline(outline)
+				if r.w.FillGradient(outline.ToPDF(), canvas.NonZero, style.Stroke.Gradient, outline.Transform(m.Inv()).Bounds(), m) {
+					return
+				}
+			}
+			strokeStyle.Stroke.Gradient = nil
+			r.RenderPath(path, strokeStyle, m)
+		}
+		return
+	}
 	if 1 < len(data) && data[len(data)-1] == 'h' {
 		data = data[:len(data)-2]
 		closed = true
@@ -94,7 +132,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 
 	if !stroke || !strokeUnsupported {
 		if fill && !stroke {
-			r.w.SetFillColor(style.FillColor)
+			r.w.SetFillColor(style.Fill.Color)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -102,7 +140,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 				r.w.Write([]byte("*"))
 			}
 		} else if !fill && stroke {
-			r.w.SetStrokeColor(style.StrokeColor)
+			r.w.SetStrokeColor(style.Stroke.Color)
 			r.w.SetLineWidth(style.StrokeWidth)
 			r.w.SetLineCap(style.StrokeCapper)
 			r.w.SetLineJoin(style.StrokeJoiner)
@@ -119,8 +157,8 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			}
 		} else if fill && stroke {
 			if !differentAlpha {
-				r.w.SetFillColor(style.FillColor)
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.w.SetFillColor(style.Fill.Color)
+				r.w.SetStrokeColor(style.Stroke.Color)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -136,7 +174,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 					r.w.Write([]byte("*"))
 				}
 			} else {
-				r.w.SetFillColor(style.FillColor)
+				r.w.SetFillColor(style.Fill.Color)
 				r.w.Write([]byte(" "))
 				r.w.Write([]byte(data))
 				r.w.Write([]byte(" f"))
@@ -144,7 +182,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 					r.w.Write([]byte("*"))
 				}
 
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.w.SetStrokeColor(style.Stroke.Color)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -164,7 +202,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	} else {
 		// stroke && strokeUnsupported
 		if fill {
-			r.w.SetFillColor(style.FillColor)
+			r.w.SetFillColor(style.Fill.Color)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -177,9 +215,9 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 		if 0 < len(style.Dashes) {
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
-		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		path = style.StrokeOutline(path)
 
-		r.w.SetFillColor(style.StrokeColor)
+		r.w.SetFillColor(style.Stroke.Color)
 		r.w.Write([]byte(" "))
 		r.w.Write([]byte(path.ToPDF()))
 		r.w.Write([]byte(" f"))
@@ -190,13 +228,17 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 }
 
 func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
+	r.w.beginContent()
+	if text.OnPath() {
+		text.RenderAsPath(r, m)
+		return
+	}
 	r.w.StartTextObject()
 
 	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
 		r.w.SetFillColor(span.Face.Color)
 		r.w.SetFont(span.Face.Font, span.Face.Size*span.Face.Scale)
 		r.w.SetTextPosition(m.Translate(dx, y).Shear(span.Face.FauxItalic, 0.0))
-		r.w.SetTextCharSpace(span.GlyphSpacing)
 
 		if 0.0 < span.Face.FauxBold {
 			r.w.SetTextRenderMode(2)
@@ -205,15 +247,15 @@ func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
 			r.w.SetTextRenderMode(0)
 		}
 
-		TJ := []interface{}{}
-		words := span.Words()
-		for i, w := range words {
-			TJ = append(TJ, w)
-			if i != len(words)-1 {
-				TJ = append(TJ, span.WordSpacing)
-			}
+		// the glyph advances include kerning and the sentence, word and glyph spacing
+		glyphs := span.Glyphs()
+		if r.w.MapGlyphs(span.Text, glyphs) {
+			r.w.WriteText(glyphs)
+		} else {
+			r.w.StartActualText(span.Text)
+			r.w.WriteText(glyphs)
+			r.w.EndMarkedContent()
 		}
-		r.w.WriteText(TJ...)
 	})
 	r.w.EndTextObject()
 
@@ -221,9 +263,84 @@ func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
 }
 
 func (r *PDF) RenderImage(img image.Image, m canvas.Matrix) {
+	r.w.beginContent()
 	r.w.DrawImage(img, r.imgEnc, m)
 }
 
+// PushClip restricts drawing to the area filled by the path, intersected with the current clipping area.
+func (r *PDF) PushClip(path *canvas.Path, fillRule canvas.FillRule, m canvas.Matrix) {
+	data := path.Transform(m).ToPDF()
+	if data == "" {
+		data = "0 0 0 0 re" // empty clipping area
+	}
+	r.w.PushClip(data, fillRule)
+}
+
+// PopClip removes the last clipping path.
+func (r *PDF) PopClip() {
+	r.w.PopClip()
+}
+
+// RenderLink makes the area of the rectangle transformed by m clickable on the current page. Links to a page number refer to the pages in the order they were added, starting at 1 for the first page, and may refer to pages that are added later.
+func (r *PDF) RenderLink(link canvas.Link, rect canvas.Rect, m canvas.Matrix) {
+	r.w.links = append(r.w.links, pdfLink{rect.Transform(m), link})
+}
+
+// RenderDestination defines a named destination at the position transformed by m on the current page, which links can jump to. Defining a destination with the same name twice overwrites the first.
+func (r *PDF) RenderDestination(name string, pos canvas.Point, m canvas.Matrix) {
+	r.w.pdf.dests[name] = pdfDest{r.w, m.Dot(pos).Y}
+}
+
+// AddLink makes the rectangle on the current page clickable, which opens the URI when clicked.
+func (r *PDF) AddLink(uri string, rect canvas.Rect) {
+	r.RenderLink(canvas.Link{URI: uri}, rect, canvas.Identity)
+}
+
+// AddPageLink makes the rectangle on the current page clickable, which jumps to the given page number starting at 1 and shows position y at the top of the window, or the whole page if y is zero.
+func (r *PDF) AddPageLink(page int, y float64, rect canvas.Rect) {
+	r.RenderLink(canvas.Link{Page: page, Y: y}, rect, canvas.Identity)
+}
+
+// AddDestinationLink makes the rectangle on the current page clickable, which jumps to the named destination, see AddDestination.
+func (r *PDF) AddDestinationLink(name string, rect canvas.Rect) {
+	r.RenderLink(canvas.Link{Dest: name}, rect, canvas.Identity)
+}
+
+// AddDestination defines a named destination at position y on the current page, which links can jump to.
+func (r *PDF) AddDestination(name string, y float64) {
+	r.RenderDestination(name, canvas.Point{X: 0.0, Y: y}, canvas.Identity)
+}
+
+// AddTextAnnotation adds a note to the current page that is shown as an icon with its top-left corner at (x,y), and which shows its contents when opened.
+func (r *PDF) AddTextAnnotation(x, y float64, contents string) {
+	x, y = x*ptPerMm, y*ptPerMm
+	r.w.annots = append(r.w.annots, pdfDict{
+		"Type":     pdfName("Annot"),
+		"Subtype":  pdfName("Text"),
+		"Rect":     pdfArray{x, y - 20.0, x + 20.0, y},
+		"Contents": pdfTextString(contents),
+		"Name":     pdfName("Comment"),
+		"Open":     false,
+	})
+}
+
+// AddHighlight adds a highlight annotation over the rectangle on the current page using the given color, such as over a line of text. The contents are shown in a popup when not empty.
+func (r *PDF) AddHighlight(rect canvas.Rect, col color.RGBA, contents string) {
+	x0, y0 := rect.X*ptPerMm, rect.Y*ptPerMm
+	x1, y1 := (rect.X+rect.W)*ptPerMm, (rect.Y+rect.H)*ptPerMm
+	annot := pdfDict{
+		"Type":       pdfName("Annot"),
+		"Subtype":    pdfName("Highlight"),
+		"Rect":       pdfArray{x0, y0, x1, y1},
+		"QuadPoints": pdfArray{x0, y1, x1, y1, x0, y0, x1, y0},
+		"C":          pdfColor(col),
+	}
+	if contents != "" {
+		annot["Contents"] = pdfTextString(contents)
+	}
+	r.w.annots = append(r.w.annots, annot)
+}
+
 type pdfWriter struct {
 	w   io.Writer
 	err error
@@ -231,30 +348,67 @@ type pdfWriter struct {
 	pos        int
 	objOffsets []int
 
-	fonts    map[*canvas.Font]pdfRef
-	pages    []*pdfPageWriter
-	compress bool
-	title    string
-	subject  string
-	keywords string
-	author   string
+	fonts       map[*canvas.Font]*pdfFont
+	fontList    []*pdfFont
+	subsetFonts bool
+	pages       []*pdfPageWriter
+	dests       map[string]pdfDest
+	outlines    []*Outline
+	pageLabels  map[int]pdfDict
+	structRoot  *pdfStructElem // nil when not tagged
+	structElem  *pdfStructElem // current structure element
+	layers      map[string]pdfRef
+	layerNames  []string
+	layerStack  []string // open layers
+	lang        string
+	compress    bool
+	title       string
+	subject     string
+	keywords    string
+	author      string
+	header      bool
+
+	// output profile
+	profile       Profile
+	intentID      string
+	intentICC     []byte
+	defaultRGBRef pdfRef
+	violations    []string
 }
 
 func newPDFWriter(writer io.Writer) *pdfWriter {
 	w := &pdfWriter{
-		w:          writer,
-		fonts:      map[*canvas.Font]pdfRef{},
-		objOffsets: []int{0, 0, 0}, // catalog, metadata, page tree
+		w:           writer,
+		fonts:       map[*canvas.Font]*pdfFont{},
+		dests:       map[string]pdfDest{},
+		pageLabels:  map[int]pdfDict{},
+		layers:      map[string]pdfRef{},
+		objOffsets:  []int{0, 0, 0}, // catalog, metadata, page tree
+		subsetFonts: true,
 	}
-
-	w.write("%%PDF-1.7\n")
 	return w
 }
 
+// writeHeader writes the file header before anything else is written, so that the output profile can still set the version after creating the writer.
+func (w *pdfWriter) writeHeader() {
+	if w.header {
+		return
+	}
+	w.header = true
+	w.write("%%PDF-%v\n", w.profile.version())
+	if w.profile != NoProfile {
+		w.write("%%\xE2\xE3\xCF\xD3\n") // marks the file as binary
+	}
+}
+
 func (w *pdfWriter) SetCompression(compress bool) {
 	w.compress = compress
 }
 
+func (w *pdfWriter) SetFontSubsetting(subset bool) {
+	w.subsetFonts = subset
+}
+
 func (w *pdfWriter) SetTitle(title string) {
 	w.title = title
 }
@@ -275,6 +429,7 @@ func (w *pdfWriter) writeBytes(b []byte) {
 	if w.err != nil {
 		return
 	}
+	w.writeHeader()
 	n, err := w.w.Write(b)
 	w.pos += n
 	w.err = err
@@ -284,6 +439,7 @@ func (w *pdfWriter) write(s string, v ...interface{}) {
 	if w.err != nil {
 		return
 	}
+	w.writeHeader()
 	n, err := fmt.Fprintf(w.w, s, v...)
 	w.pos += n
 	w.err = err
@@ -306,6 +462,8 @@ const (
 
 func (w *pdfWriter) writeVal(i interface{}) {
 	switch v := i.(type) {
+	case nil:
+		w.write("null")
 	case bool:
 		if v {
 			w.write("true")
@@ -320,6 +478,7 @@ func (w *pdfWriter) writeVal(i interface{}) {
 		v = strings.Replace(v, `\`, `\\`, -1)
 		v = strings.Replace(v, `(`, `\(`, -1)
 		v = strings.Replace(v, `)`, `\)`, -1)
+		v = strings.Replace(v, "\r", `\r`, -1) // readers would read an unescaped CR as LF
 		w.write("(%v)", v)
 	case pdfRef:
 		w.write("%v 0 R", v)
@@ -403,6 +562,7 @@ func (w *pdfWriter) writeVal(i interface{}) {
 }
 
 func (w *pdfWriter) writeObject(val interface{}) pdfRef {
+	w.writeHeader()
 	w.objOffsets = append(w.objOffsets, w.pos)
 	w.write("%v 0 obj\n", len(w.objOffsets))
 	w.writeVal(val)
@@ -410,9 +570,53 @@ func (w *pdfWriter) writeObject(val interface{}) pdfRef {
 	return pdfRef(len(w.objOffsets))
 }
 
+// reserveObject reserves an object number so that other objects can refer to it before it is written by writeReservedObject.
+func (w *pdfWriter) reserveObject() pdfRef {
+	w.objOffsets = append(w.objOffsets, 0)
+	return pdfRef(len(w.objOffsets))
+}
+
+func (w *pdfWriter) writeReservedObject(ref pdfRef, val interface{}) {
+	w.writeHeader()
+	w.objOffsets[ref-1] = w.pos
+	w.write("%v 0 obj\n", ref)
+	w.writeVal(val)
+	w.write("\nendobj\n")
+}
+
+// pdfFont is an embedded font, it is written when the document is closed so that only the used glyphs need to be embedded.
+type pdfFont struct {
+	font     *canvas.Font
+	ref      pdfRef
+	mimetype string
+	sfnt     []byte
+	subset   bool
+	glyphIDs []uint16          // glyph IDs of the font for each glyph ID of the subset
+	indices  map[uint16]uint16 // glyph IDs of the subset for each glyph ID of the font
+	unicode  map[uint16]string // text for each glyph ID of the font, used for text extraction
+}
+
+// glyphIndices returns the glyph IDs to use in the PDF for the glyph IDs of the font, adding them to the subset when necessary.
+func (f *pdfFont) glyphIndices(glyphIDs []uint16) []uint16 {
+	if !f.subset {
+		return glyphIDs
+	}
+	indices := make([]uint16, len(glyphIDs))
+	for i, glyphID := range glyphIDs {
+		index, ok := f.indices[glyphID]
+		if !ok {
+			index = uint16(len(f.glyphIDs))
+			f.glyphIDs = append(f.glyphIDs, glyphID)
+			f.indices[glyphID] = index
+		}
+		indices[i] = index
+	}
+	return indices
+}
+
 func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
-	if ref, ok := w.fonts[font]; ok {
-		return ref
+	if f, ok := w.fonts[font]; ok {
+		return f.ref
 	}
 
 	mimetype, b := font.Raw()
@@ -422,28 +626,89 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 		if err != nil {
 			panic(err)
 		}
+		if mimetype, err = canvasFont.MediaType(b); err != nil {
+			panic(err)
+		}
 		if mimetype != "font/truetype" && mimetype != "font/opentype" {
 			panic("only TTF and OTF formats supported for embedding fonts in PDFs")
 		}
 	}
 
+	// reserve the object number, the font is written on Close
+	w.objOffsets = append(w.objOffsets, 0)
+	f := &pdfFont{
+		font:     font,
+		ref:      pdfRef(len(w.objOffsets)),
+		mimetype: mimetype,
+		sfnt:     b,
+		unicode:  map[uint16]string{},
+	}
+	if w.subsetFonts {
+		if _, err := canvasFont.Subset(b, nil); err == nil {
+			f.subset = true
+			f.glyphIDs = []uint16{0}
+			f.indices = map[uint16]uint16{0: 0}
+		}
+	}
+	w.fonts[font] = f
+	w.fontList = append(w.fontList, f)
+	return f.ref
+}
+
+// subsetTag returns the six uppercase letters that prefix the name of a font subset, it is derived from the glyphs in the subset.
+func subsetTag(glyphIDs []uint16) string {
+	h := fnv.New32a()
+	binary.Write(h, binary.BigEndian, glyphIDs)
+	sum := h.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
+
+func (w *pdfWriter) writeFont(f *pdfFont) {
 	ffSubtype := ""
 	cidSubtype := ""
-	if mimetype == "font/truetype" {
+	fontFile := pdfName("FontFile3")
+	if f.mimetype == "font/truetype" {
 		ffSubtype = "TrueType"
 		cidSubtype = "CIDFontType2"
-	} else if mimetype == "font/opentype" {
+		fontFile = pdfName("FontFile2") // FontFile3 does not support TrueType
+	} else if f.mimetype == "font/opentype" {
 		ffSubtype = "OpenType"
 		cidSubtype = "CIDFontType0"
+		if w.profile == PDFX1a {
+			w.forbid("OpenType fonts")
+		}
 	}
 
+	font := f.font
 	units := font.UnitsPerEm()
-	f := 1000 / units // factor to cancel the units and scale to 1000 (pdf spec)
+	fc := 1000 / units // factor to cancel the units and scale to 1000 (pdf spec)
 
+	b := f.sfnt
 	fWidths := font.Widths(units)
+	baseFont := strings.ReplaceAll(font.Name(), " ", "_")
+	if f.subset {
+		var err error
+		if b, err = canvasFont.Subset(b, f.glyphIDs); err != nil {
+			panic(err)
+		}
+		subsetWidths := make([]float64, len(f.glyphIDs))
+		for i, glyphID := range f.glyphIDs {
+			if int(glyphID) < len(fWidths) {
+				subsetWidths[i] = fWidths[glyphID]
+			}
+		}
+		fWidths = subsetWidths
+		baseFont = subsetTag(f.glyphIDs) + "+" + baseFont
+	}
+
 	widths := make([]int, 0, len(fWidths))
 	for _, w := range fWidths {
-		widths = append(widths, int(w*f+0.5))
+		widths = append(widths, int(w*fc+0.5))
 	}
 
 	// shorten glyph widths array
@@ -476,17 +741,20 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 		W = append(W, i, arr)
 	}
 
-	baseFont := strings.ReplaceAll(font.Name(), " ", "_")
 	bounds := font.Bounds(units)
 	metrics := font.Metrics(units)
+	fontfileDict := pdfDict{
+		"Subtype": pdfName(ffSubtype),
+		"Filter":  pdfFilterFlate,
+	}
+	if fontFile == "FontFile2" {
+		delete(fontfileDict, "Subtype")
+	}
 	fontfileRef := w.writeObject(pdfStream{
-		dict: pdfDict{
-			"Subtype": pdfName(ffSubtype),
-			"Filter":  pdfFilterFlate,
-		},
+		dict:   fontfileDict,
 		stream: b,
 	})
-	ref := w.writeObject(pdfDict{
+	dict := pdfDict{
 		"Type":     pdfName("Font"),
 		"Subtype":  pdfName("Type0"),
 		"BaseFont": pdfName(baseFont),
@@ -507,53 +775,181 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 				"Type":        pdfName("FontDescriptor"),
 				"FontName":    pdfName(baseFont),
 				"Flags":       4,
-				"FontBBox":    pdfArray{int(f * bounds.X), -int(f * (bounds.Y + bounds.H)), int(f * (bounds.X + bounds.W)), -int(f * bounds.Y)},
+				"FontBBox":    pdfArray{int(fc * bounds.X), -int(fc * (bounds.Y + bounds.H)), int(fc * (bounds.X + bounds.W)), -int(fc * bounds.Y)},
 				"ItalicAngle": font.ItalicAngle(),
-				"Ascent":      int(f * metrics.Ascent),
-				"Descent":     -int(f * metrics.Descent),
-				"CapHeight":   -int(f * metrics.CapHeight),
+				"Ascent":      int(fc * metrics.Ascent),
+				"Descent":     -int(fc * metrics.Descent),
+				"CapHeight":   -int(fc * metrics.CapHeight),
 				"StemV":       80, // taken from Inkscape, should be calculated somehow
 				"StemH":       80,
-				"FontFile3":   fontfileRef,
+				fontFile:      fontfileRef,
 			},
 		}},
-	})
-	w.fonts[font] = ref
-	return ref
+	}
+	if 0 < len(f.unicode) {
+		dict["ToUnicode"] = w.writeObject(pdfStream{
+			dict: pdfDict{
+				"Filter": pdfFilterFlate,
+			},
+			stream: f.toUnicode(),
+		})
+	}
+	w.objOffsets[f.ref-1] = w.pos
+	w.write("%v 0 obj\n", f.ref)
+	w.writeVal(dict)
+	w.write("\nendobj\n")
+}
+
+// toUnicode returns the ToUnicode CMap that maps the glyph IDs used in the PDF to their text, so that text can be extracted, searched and copied.
+func (f *pdfFont) toUnicode() []byte {
+	codes := []int{}
+	texts := map[int]string{}
+	for glyphID, text := range f.unicode {
+		code := int(glyphID)
+		if f.subset {
+			index, ok := f.indices[glyphID]
+			if !ok {
+				continue
+			}
+			code = int(index)
+		}
+		codes = append(codes, code)
+		texts[code] = text
+	}
+	sort.Ints(codes)
+
+	b := &bytes.Buffer{}
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(codes); i += 100 {
+		j := i + 100 // at most 100 entries per block
+		if len(codes) < j {
+			j = len(codes)
+		}
+		fmt.Fprintf(b, "%d beginbfchar\n", j-i)
+		for _, code := range codes[i:j] {
+			fmt.Fprintf(b, "<%04X> <", code)
+			for _, c := range utf16.Encode([]rune(texts[code])) {
+				fmt.Fprintf(b, "%04X", c)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
 }
 
 func (w *pdfWriter) Close() error {
 	// TODO: write pages directly to stream instead of using bytes.Buffer
 	kids := pdfArray{}
+	structParents := 0
 	for _, p := range w.pages {
+		p.endContent()
+		if 0 < len(p.mcids) {
+			p.structParents = structParents
+			structParents++
+		}
 		kids = append(kids, p.writePage(pdfRef(3)))
 	}
+	for _, f := range w.fontList {
+		w.writeFont(f)
+	}
+	outline := w.writeOutline()
+	structTree := w.writeStructTree()
+	layers := w.writeLayers()
+	created := time.Now().UTC()
+	id := w.documentID(created)
 
 	// document catalog
-	w.objOffsets[0] = w.pos
-	w.write("%v 0 obj\n", 1)
-	w.writeVal(pdfDict{
+	catalog := pdfDict{
 		"Type":  pdfName("Catalog"),
 		"Pages": pdfRef(3),
-	})
+	}
+	if 0 < len(w.dests) {
+		names := make([]string, 0, len(w.dests))
+		for name := range w.dests {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		dests := pdfArray{}
+		for _, name := range names {
+			dest := w.dests[name]
+			dests = append(dests, name, dest.page.destination(dest.y))
+		}
+		catalog["Names"] = pdfDict{
+			"Dests": pdfDict{"Names": dests},
+		}
+	}
+	if outline != 0 {
+		catalog["Outlines"] = outline
+		catalog["PageMode"] = pdfName("UseOutlines")
+	}
+	if 0 < len(w.pageLabels) {
+		catalog["PageLabels"] = pdfDict{"Nums": w.pageLabelNums()}
+	}
+	if structTree != 0 {
+		catalog["StructTreeRoot"] = structTree
+		catalog["MarkInfo"] = pdfDict{"Marked": true}
+		if w.title != "" {
+			catalog["ViewerPreferences"] = pdfDict{"DisplayDocTitle": true}
+		}
+	}
+	if layers != nil {
+		catalog["OCProperties"] = layers
+	}
+	if w.lang != "" {
+		catalog["Lang"] = w.lang
+	}
+	if w.profile != NoProfile {
+		if w.profile.isPDFX() && w.title == "" {
+			w.forbid("documents without title")
+		}
+		catalog["OutputIntents"] = pdfArray{w.writeOutputIntent()}
+		catalog["Metadata"] = w.writeObject(pdfStream{
+			dict: pdfDict{
+				"Type":    pdfName("Metadata"),
+				"Subtype": pdfName("XML"),
+			},
+			stream: w.xmpMetadata(created, id),
+		})
+	}
+
+	w.objOffsets[0] = w.pos
+	w.write("%v 0 obj\n", 1)
+	w.writeVal(catalog)
 	w.write("\nendobj\n")
 
 	// metadata
 	info := pdfDict{
 		"Producer":     "dtrenin7/canvas",
-		"CreationDate": time.Now().Format("D:20060102150405Z0700"),
+		"CreationDate": created.Format("D:20060102150405Z0700"),
 	}
 	if w.title != "" {
-		info["title"] = w.title
+		info["Title"] = pdfTextString(w.title)
 	}
 	if w.subject != "" {
-		info["subject"] = w.subject
+		info["Subject"] = pdfTextString(w.subject)
 	}
 	if w.keywords != "" {
-		info["keywords"] = w.keywords
+		info["Keywords"] = pdfTextString(w.keywords)
 	}
 	if w.author != "" {
-		info["author"] = w.author
+		info["Author"] = pdfTextString(w.author)
+	}
+	if w.profile != NoProfile {
+		info["ModDate"] = info["CreationDate"]
+	}
+	if w.profile == PDFX1a {
+		info["GTS_PDFXVersion"] = "PDF/X-1:2003"
+		info["GTS_PDFXConformance"] = "PDF/X-1a:2003"
+		info["Trapped"] = pdfName("False")
+	} else if w.profile == PDFX4 {
+		info["GTS_PDFXVersion"] = "PDF/X-4"
+		info["Trapped"] = pdfName("False")
 	}
 
 	w.objOffsets[1] = w.pos
@@ -576,23 +972,48 @@ func (w *pdfWriter) Close() error {
 	for _, objOffset := range w.objOffsets {
 		w.write("%010d 00000 n\n", objOffset)
 	}
-	w.write("trailer\n")
-	w.writeVal(pdfDict{
+	trailer := pdfDict{
 		"Root": pdfRef(1),
 		"Size": len(w.objOffsets) + 1,
 		"Info": pdfRef(2),
-	})
+	}
+	if w.profile != NoProfile {
+		trailer["ID"] = pdfArray{fmt.Sprintf("%x", id), fmt.Sprintf("%x", id)}
+	}
+	w.write("trailer\n")
+	w.writeVal(trailer)
 	w.write("\nstartxref\n%v\n%%%%EOF", xrefOffset)
+	if w.err == nil && 0 < len(w.violations) {
+		return fmt.Errorf("PDF: %v does not allow %v", w.profile, strings.Join(w.violations, ", "))
+	}
 	return w.err
 }
 
 type pdfPageWriter struct {
 	*bytes.Buffer
 	pdf           *pdfWriter
+	ref           pdfRef
 	width, height float64
 	resources     pdfDict
+	links         []pdfLink
+	annots        []pdfDict
+
+	// marked content of tagged PDFs
+	mcids         []*pdfStructElem // structure element for each marked-content identifier
+	structParents int
+	inContent     bool
+
+	openLayers int
 
 	graphicsStates map[float64]pdfName
+	pdfGraphicsState
+	stateStack   []pdfGraphicsState
+	inTextObject bool
+	textPosition canvas.Matrix
+}
+
+// pdfGraphicsState is the part of the graphics state that is kept track of to avoid writing redundant operators, it is saved and restored by the q and Q operators.
+type pdfGraphicsState struct {
 	alpha          float64
 	fillColor      color.RGBA
 	strokeColor    color.RGBA
@@ -603,8 +1024,6 @@ type pdfPageWriter struct {
 	dashes         []float64
 	font           *canvas.Font
 	fontSize       float64
-	inTextObject   bool
-	textPosition   canvas.Matrix
 	textCharSpace  float64
 	textRenderMode int
 }
@@ -614,33 +1033,48 @@ func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
 	page := &pdfPageWriter{
 		Buffer:         &bytes.Buffer{},
 		pdf:            w,
+		ref:            w.reserveObject(), // links can refer to pages that have not been written yet
 		width:          width,
 		height:         height,
 		resources:      pdfDict{},
 		graphicsStates: map[float64]pdfName{},
-		alpha:          1.0,
-		fillColor:      canvas.Black,
-		strokeColor:    canvas.Black,
-		lineWidth:      1.0,
-		lineCap:        0,
-		lineJoin:       0,
-		miterLimit:     10.0,
-		dashes:         []float64{0.0}, // dashArray and dashPhase
-		font:           nil,
-		fontSize:       0.0,
-		inTextObject:   false,
-		textPosition:   canvas.Identity,
-		textCharSpace:  0.0,
-		textRenderMode: 0,
+		pdfGraphicsState: pdfGraphicsState{
+			alpha:          1.0,
+			fillColor:      canvas.Black,
+			strokeColor:    canvas.Black,
+			lineWidth:      1.0,
+			lineCap:        0,
+			lineJoin:       0,
+			miterLimit:     10.0,
+			dashes:         []float64{0.0}, // dashArray and dashPhase
+			font:           nil,
+			fontSize:       0.0,
+			textCharSpace:  0.0,
+			textRenderMode: 0,
+		},
+		inTextObject: false,
+		textPosition: canvas.Identity,
 	}
 	w.pages = append(w.pages, page)
 
 	m := canvas.Identity.Scale(ptPerMm, ptPerMm)
 	fmt.Fprintf(page, " %v %v %v %v %v %v cm", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+
+	// layers that are still open continue on the new page
+	for _, name := range w.layerStack {
+		page.beginLayer(name)
+	}
 	return page
 }
 
 func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
+	for 0 < w.openLayers {
+		w.endLayer()
+	}
+	for 0 < len(w.stateStack) {
+		w.RestoreState()
+	}
+
 	b := w.Bytes()
 	if 0 < len(b) && b[0] == ' ' {
 		b = b[1:]
@@ -653,10 +1087,39 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 		stream.dict["Filter"] = pdfFilterFlate
 	}
 	contents := w.pdf.writeObject(stream)
-	return w.pdf.writeObject(pdfDict{
+
+	annots := pdfArray{}
+	for _, link := range w.links {
+		if action := w.pdf.linkAction(link.link); action != nil {
+			annot := pdfDict{
+				"Type":    pdfName("Annot"),
+				"Subtype": pdfName("Link"),
+				"Rect":    pdfRect(link.rect),
+				"Border":  pdfArray{0, 0, 0},
+				"A":       action,
+				"P":       w.ref,
+			}
+			if w.pdf.profile == PDFA2b {
+				annot["F"] = 4 // print
+			}
+			annots = append(annots, w.pdf.writeObject(annot))
+		}
+	}
+	for _, annot := range w.annots {
+		annot["P"] = w.ref
+		annots = append(annots, w.pdf.writeObject(annot))
+	}
+	if 0 < len(annots) && w.pdf.profile.isPDFX() {
+		w.pdf.forbid("annotations")
+	} else if 0 < len(w.annots) && w.pdf.profile == PDFA2b {
+		w.pdf.forbid("annotations without appearance streams")
+	}
+
+	mediaBox := pdfArray{0.0, 0.0, w.width * ptPerMm, w.height * ptPerMm}
+	page := pdfDict{
 		"Type":      pdfName("Page"),
 		"Parent":    parent,
-		"MediaBox":  pdfArray{0.0, 0.0, w.width * ptPerMm, w.height * ptPerMm},
+		"MediaBox":  mediaBox,
 		"Resources": w.resources,
 		"Group": pdfDict{
 			"Type": pdfName("Group"),
@@ -665,10 +1128,330 @@ func (w *pdfPageWriter) writePage(parent pdfRef) pdfRef {
 			"CS":   pdfName("DeviceRGB"),
 		},
 		"Contents": contents,
+	}
+	if w.pdf.profile == PDFX1a {
+		delete(page, "Group") // no transparency
+	} else if cs := w.pdf.defaultRGB(); cs != nil {
+		// DeviceRGB is not allowed when the output intent is not RGB
+		w.resources["ColorSpace"] = pdfDict{"DefaultRGB": cs}
+		page["Group"].(pdfDict)["CS"] = cs
+	}
+	if w.pdf.profile.isPDFX() {
+		page["TrimBox"] = mediaBox
+	}
+	if 0 < len(annots) {
+		page["Annots"] = annots
+	}
+	if 0 < len(w.mcids) {
+		page["StructParents"] = w.structParents
+	}
+
+	w.pdf.writeReservedObject(w.ref, page)
+	return w.ref
+}
+
+// pdfLink is a clickable area on a page in page coordinates. It is written when the document is closed so that it can refer to pages that are added later.
+type pdfLink struct {
+	rect canvas.Rect
+	link canvas.Link
+}
+
+// pdfDest is a named destination at position y on a page.
+type pdfDest struct {
+	page *pdfPageWriter
+	y    float64
+}
+
+// linkAction returns the action that is performed when clicking on the link, or nil if the link has no valid target.
+func (w *pdfWriter) linkAction(link canvas.Link) pdfDict {
+	if link.URI != "" {
+		return pdfDict{
+			"S":   pdfName("URI"),
+			"URI": link.URI,
+		}
+	} else if link.Dest != "" {
+		return pdfDict{
+			"S": pdfName("GoTo"),
+			"D": link.Dest,
+		}
+	} else if dest := w.pageDestination(link.Page, link.Y); dest != nil {
+		return pdfDict{
+			"S": pdfName("GoTo"),
+			"D": dest,
+		}
+	}
+	return nil
+}
+
+// pageDestination returns an explicit destination that shows position y of the page number starting at 1 at the top of the window, or the whole page if y is zero. It returns nil if the page does not exist.
+func (w *pdfWriter) pageDestination(page int, y float64) pdfArray {
+	if page < 1 || len(w.pages) < page {
+		return nil
+	} else if y == 0.0 {
+		return pdfArray{w.pages[page-1].ref, pdfName("Fit")}
+	}
+	return w.pages[page-1].destination(y)
+}
+
+// destination returns an explicit destination that shows position y of the page at the top of the window.
+func (w *pdfPageWriter) destination(y float64) pdfArray {
+	return pdfArray{w.ref, pdfName("XYZ"), nil, y * ptPerMm, nil}
+}
+
+// SaveState saves the graphics state, which will be restored by RestoreState.
+func (w *pdfPageWriter) SaveState() {
+	fmt.Fprintf(w, " q")
+	w.stateStack = append(w.stateStack, w.pdfGraphicsState)
+}
+
+// RestoreState restores the last saved graphics state.
+func (w *pdfPageWriter) RestoreState() {
+	fmt.Fprintf(w, " Q")
+	w.pdfGraphicsState = w.stateStack[len(w.stateStack)-1]
+	w.stateStack = w.stateStack[:len(w.stateStack)-1]
+}
+
+// PushClip saves the graphics state and intersects the clipping path with the given path data.
+func (w *pdfPageWriter) PushClip(data string, fillRule canvas.FillRule) {
+	w.SaveState()
+	fmt.Fprintf(w, " %v W", data)
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	fmt.Fprintf(w, " n")
+}
+
+// PopClip restores the graphics state from before the last clipping path.
+func (w *pdfPageWriter) PopClip() {
+	w.RestoreState()
+}
+
+// FillGradient fills the path data with a gradient, where bounds are the bounds of the path in the coordinate system of the gradient and m transforms the gradient to the page. The gradient is painted as an axial or radial shading clipped to the path, a varying transparency is painted using a soft mask. It returns false if the gradient is not supported.
+func (w *pdfPageWriter) FillGradient(data string, fillRule canvas.FillRule, gradient canvas.Gradient, bounds canvas.Rect, m canvas.Matrix) bool {
+	// PDF shadings only support the pad spread mode, other modes are expanded over the bounds
+	t0, t1 := 0.0, 1.0
+	var shading pdfDict
+	var stops canvas.Stops
+	switch g := gradient.(type) {
+	case *canvas.LinearGradient:
+		if g.Spread != canvas.PadSpread {
+			if tmin, tmax := g.ParamRange(bounds); tmin < tmax {
+				t0, t1 = tmin, tmax
+			}
+		}
+		start := g.Start.Interpolate(g.End, t0)
+		end := g.Start.Interpolate(g.End, t1)
+		shading = pdfDict{
+			"ShadingType": 2,
+			"Coords":      pdfArray{start.X, start.Y, end.X, end.Y},
+		}
+		stops = g.Stops.Expand(g.Spread, t0, t1)
+	case *canvas.RadialGradient:
+		if g.Spread != canvas.PadSpread {
+			t0, t1 = g.ParamRange(bounds)
+		}
+		c0, r0 := g.Circle(t0)
+		c1, r1 := g.Circle(t1)
+		shading = pdfDict{
+			"ShadingType": 3,
+			"Coords":      pdfArray{c0.X, c0.Y, r0, c1.X, c1.Y, r1},
+		}
+		stops = g.Stops.Expand(g.Spread, t0, t1)
+	default:
+		return false
+	}
+	if data == "" || len(stops) == 0 {
+		return true // nothing to paint
+	}
+	shading["Domain"] = pdfArray{t0, t1}
+	shading["Extend"] = pdfArray{true, true}
+
+	// colors are not premultiplied in PDF, transparent stops take the color of their neighbours
+	offsets := []float64{}
+	colors := [][]float64{}
+	alphas := [][]float64{}
+	straight := func(i int) []float64 {
+		a := float64(stops[i].Color.A)
+		return []float64{float64(stops[i].Color.R) / a, float64(stops[i].Color.G) / a, float64(stops[i].Color.B) / a}
+	}
+	constantAlpha := true
+	for i, stop := range stops {
+		if stop.Color.A != stops[0].Color.A {
+			constantAlpha = false
+		}
+		if stop.Color.A != 0 {
+			offsets = append(offsets, stop.Offset)
+			colors = append(colors, straight(i))
+			alphas = append(alphas, []float64{float64(stop.Color.A) / 255.0})
+			continue
+		}
+
+		prev, next := -1, -1
+		for j := i - 1; 0 <= j; j-- {
+			if stops[j].Color.A != 0 {
+				prev = j
+				break
+			}
+		}
+		for j := i + 1; j < len(stops); j++ {
+			if stops[j].Color.A != 0 {
+				next = j
+				break
+			}
+		}
+		if prev == -1 && next == -1 {
+			return true // fully transparent
+		} else if prev == -1 {
+			prev = next
+		} else if next == -1 {
+			next = prev
+		}
+		offsets = append(offsets, stop.Offset)
+		colors = append(colors, straight(prev))
+		alphas = append(alphas, []float64{0.0})
+		if next != prev {
+			offsets = append(offsets, stop.Offset)
+			colors = append(colors, straight(next))
+			alphas = append(alphas, []float64{0.0})
+		}
+	}
+
+	shading["ColorSpace"] = pdfName("DeviceRGB")
+	if w.pdf.profile == PDFX1a {
+		shading["ColorSpace"] = pdfName("DeviceCMYK")
+		for i, col := range colors {
+			c, m, y, k := rgbToCMYK(col[0], col[1], col[2])
+			colors[i] = []float64{c, m, y, k}
+		}
+	}
+	shading["Function"] = pdfStitchingFunction(offsets, colors)
+	name := w.embedShading(shading)
+
+	w.SaveState()
+	fmt.Fprintf(w, " %v W", data)
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	fmt.Fprintf(w, " n %v %v %v %v %v %v cm", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	if constantAlpha {
+		w.SetAlpha(float64(stops[0].Color.A) / 255.0)
+	} else {
+		if w.pdf.profile == PDFX1a {
+			w.pdf.forbid("transparency")
+		}
+		maskShading := pdfDict{}
+		for key, val := range shading {
+			maskShading[key] = val
+		}
+		maskShading["ColorSpace"] = pdfName("DeviceGray")
+		maskShading["Function"] = pdfStitchingFunction(offsets, alphas)
+		w.SetAlpha(1.0)
+		fmt.Fprintf(w, " /%v gs", w.embedSoftMask(maskShading, bounds))
+	}
+	fmt.Fprintf(w, " /%v sh", name)
+	w.RestoreState()
+	return true
+}
+
+// pdfStitchingFunction returns a function that interpolates linearly between the values at the given offsets, the domain spans from the first to the last offset.
+func pdfStitchingFunction(offsets []float64, values [][]float64) pdfDict {
+	t0, t1 := offsets[0], offsets[len(offsets)-1]
+	if len(offsets) == 1 {
+		values = append(values, values[0])
+	}
+	if len(offsets) <= 2 {
+		return pdfDict{
+			"FunctionType": 2,
+			"Domain":       pdfArray{t0, t1},
+			"C0":           pdfFloats(values[0]),
+			"C1":           pdfFloats(values[len(values)-1]),
+			"N":            1,
+		}
+	}
+
+	functions := pdfArray{}
+	bounds := pdfArray{}
+	encode := pdfArray{}
+	for i := 1; i < len(offsets); i++ {
+		functions = append(functions, pdfDict{
+			"FunctionType": 2,
+			"Domain":       pdfArray{0, 1},
+			"C0":           pdfFloats(values[i-1]),
+			"C1":           pdfFloats(values[i]),
+			"N":            1,
+		})
+		if i != len(offsets)-1 {
+			bounds = append(bounds, offsets[i])
+		}
+		encode = append(encode, 0, 1)
+	}
+	return pdfDict{
+		"FunctionType": 3,
+		"Domain":       pdfArray{t0, t1},
+		"Functions":    functions,
+		"Bounds":       bounds,
+		"Encode":       encode,
+	}
+}
+
+func pdfFloats(vals []float64) pdfArray {
+	arr := pdfArray{}
+	for _, val := range vals {
+		arr = append(arr, val)
+	}
+	return arr
+}
+
+func (w *pdfPageWriter) embedShading(shading pdfDict) pdfName {
+	ref := w.pdf.writeObject(shading)
+	if _, ok := w.resources["Shading"]; !ok {
+		w.resources["Shading"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("Sh%d", len(w.resources["Shading"].(pdfDict))))
+	w.resources["Shading"].(pdfDict)[name] = ref
+	return name
+}
+
+// embedSoftMask returns a graphics state with a luminosity soft mask that paints the gray shading within the bounds.
+func (w *pdfPageWriter) embedSoftMask(shading pdfDict, bounds canvas.Rect) pdfName {
+	shadingRef := w.pdf.writeObject(shading)
+	formRef := w.pdf.writeObject(pdfStream{
+		dict: pdfDict{
+			"Type":    pdfName("XObject"),
+			"Subtype": pdfName("Form"),
+			"BBox":    pdfArray{bounds.X, bounds.Y, bounds.X + bounds.W, bounds.Y + bounds.H},
+			"Group": pdfDict{
+				"Type": pdfName("Group"),
+				"S":    pdfName("Transparency"),
+				"CS":   pdfName("DeviceGray"),
+			},
+			"Resources": pdfDict{
+				"Shading": pdfDict{
+					"Sh0": shadingRef,
+				},
+			},
+		},
+		stream: []byte("/Sh0 sh"),
 	})
+
+	if _, ok := w.resources["ExtGState"]; !ok {
+		w.resources["ExtGState"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("M%d", len(w.resources["ExtGState"].(pdfDict))))
+	w.resources["ExtGState"].(pdfDict)[name] = pdfDict{
+		"SMask": pdfDict{
+			"Type": pdfName("Mask"),
+			"S":    pdfName("Luminosity"),
+			"G":    formRef,
+		},
+	}
+	return name
 }
 
 func (w *pdfPageWriter) SetAlpha(alpha float64) {
+	if alpha != 1.0 && w.pdf.profile == PDFX1a {
+		w.pdf.forbid("transparency")
+	}
 	if alpha != w.alpha {
 		gs := w.getOpacityGS(alpha)
 		fmt.Fprintf(w, " /%v gs", gs)
@@ -681,6 +1464,9 @@ func (w *pdfPageWriter) SetFillColor(fillColor color.RGBA) {
 	if fillColor != w.fillColor {
 		if fillColor.R == fillColor.G && fillColor.R == fillColor.B {
 			fmt.Fprintf(w, " %v g", dec(float64(fillColor.R)/255.0/a))
+		} else if w.pdf.profile == PDFX1a {
+			c, m, y, k := rgbToCMYK(float64(fillColor.R)/255.0/a, float64(fillColor.G)/255.0/a, float64(fillColor.B)/255.0/a)
+			fmt.Fprintf(w, " %v %v %v %v k", dec(c), dec(m), dec(y), dec(k))
 		} else {
 			fmt.Fprintf(w, " %v %v %v rg", dec(float64(fillColor.R)/255.0/a), dec(float64(fillColor.G)/255.0/a), dec(float64(fillColor.B)/255.0/a))
 		}
@@ -694,6 +1480,9 @@ func (w *pdfPageWriter) SetStrokeColor(strokeColor color.RGBA) {
 	if strokeColor != w.strokeColor {
 		if strokeColor.R == strokeColor.G && strokeColor.R == strokeColor.B {
 			fmt.Fprintf(w, " %v G", dec(float64(strokeColor.R)/255.0/a))
+		} else if w.pdf.profile == PDFX1a {
+			c, m, y, k := rgbToCMYK(float64(strokeColor.R)/255.0/a, float64(strokeColor.G)/255.0/a, float64(strokeColor.B)/255.0/a)
+			fmt.Fprintf(w, " %v %v %v %v K", dec(c), dec(m), dec(y), dec(k))
 		} else {
 			fmt.Fprintf(w, " %v %v %v RG", dec(float64(strokeColor.R)/255.0/a), dec(float64(strokeColor.G)/255.0/a), dec(float64(strokeColor.B)/255.0/a))
 		}
@@ -865,6 +1654,33 @@ func (w *pdfPageWriter) EndTextObject() {
 	w.inTextObject = false
 }
 
+// MapGlyphs records the text that the glyphs represent in the ToUnicode map of the current font, where the glyphs are shaped from text. It returns false when the text cannot be extracted correctly using the map, such as for reordered glyphs or for glyphs that represent different text elsewhere, in which case the glyphs must be marked with StartActualText.
+func (w *pdfPageWriter) MapGlyphs(text string, glyphs []canvas.Glyph) bool {
+	if w.font == nil {
+		return true
+	}
+
+	f := w.pdf.fonts[w.font]
+	sb := strings.Builder{}
+	for i, s := range glyphTexts(text, glyphs) {
+		if _, ok := f.unicode[glyphs[i].ID]; !ok && s != "" && glyphs[i].ID != 0 { // .notdef has no text
+			f.unicode[glyphs[i].ID] = s
+		}
+		sb.WriteString(f.unicode[glyphs[i].ID])
+	}
+	return sb.String() == expandLigatures(text)
+}
+
+// StartActualText starts a marked-content sequence that replaces the text extracted from its glyphs by the given text, it must be ended by EndMarkedContent.
+func (w *pdfPageWriter) StartActualText(text string) {
+	fmt.Fprintf(w, " /Span << /ActualText <%X> >> BDC", pdfTextString(text))
+}
+
+// EndMarkedContent ends a marked-content sequence.
+func (w *pdfPageWriter) EndMarkedContent() {
+	fmt.Fprintf(w, " EMC")
+}
+
 func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 	if !w.inTextObject {
 		panic("must be in text object")
@@ -874,8 +1690,10 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 	}
 
 	first := true
-	write := func(s string) {
-		if first {
+	write := func(indices []uint16) {
+		if len(indices) == 0 {
+			return
+		} else if first {
 			fmt.Fprintf(w, "(")
 			first = false
 		} else {
@@ -883,16 +1701,21 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 		}
 
 		buf := &bytes.Buffer{}
-		indices := w.font.IndicesOf(s)
-		binary.Write(buf, binary.BigEndian, indices)
+		binary.Write(buf, binary.BigEndian, w.pdf.fonts[w.font].glyphIndices(indices))
 
-		s = buf.String()
+		s := buf.String()
 		s = strings.Replace(s, "\\", "\\\\", -1)
 		s = strings.Replace(s, "(", "\\(", -1)
 		s = strings.Replace(s, ")", "\\)", -1)
 		fmt.Fprintf(w, "%s)", s)
 	}
+	writeSpace := func(val float64) {
+		if space := -int(val*1000.0/w.fontSize + 0.5); space != 0 {
+			fmt.Fprintf(w, " %d", space)
+		}
+	}
 
+	rise := 0.0
 	units := w.font.UnitsPerEm()
 	fmt.Fprintf(w, "[")
 	for _, tj := range TJ {
@@ -903,14 +1726,41 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 			for j, r := range val {
 				if i < j {
 					if kern, err := w.font.Kerning(rPrev, r, units); err == nil && kern != 0.0 {
-						write(val[i:j])
+						write(w.font.IndicesOf(val[i:j]))
 						fmt.Fprintf(w, " %d", -int(kern*1000/units+0.5))
 						i = j
 					}
 				}
 				rPrev = r
 			}
-			write(val[i:])
+			write(w.font.IndicesOf(val[i:]))
+		case []canvas.Glyph:
+			// glyphs are positioned by adjusting the advance of the previous glyph and by the text rise
+			indices := []uint16{}
+			for _, glyph := range val {
+				if glyph.YOffset != rise {
+					write(indices)
+					indices = indices[:0]
+					fmt.Fprintf(w, "]TJ %v Ts[", dec(glyph.YOffset))
+					first = true
+					rise = glyph.YOffset
+				}
+				if glyph.XOffset != 0.0 {
+					write(indices)
+					indices = indices[:0]
+					writeSpace(glyph.XOffset)
+				}
+				if glyph.ID == 0 && w.pdf.profile == PDFA2b {
+					w.pdf.forbid("missing glyphs")
+				}
+				indices = append(indices, glyph.ID)
+				if adjust := glyph.XAdvance - glyph.XOffset - w.font.GlyphAdvance(glyph.ID, w.fontSize); adjust != 0.0 {
+					write(indices)
+					indices = indices[:0]
+					writeSpace(adjust)
+				}
+			}
+			write(indices)
 		case float64:
 			fmt.Fprintf(w, " %d", -int(val*1000.0/w.fontSize+0.5))
 		case int:
@@ -918,6 +1768,9 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 		}
 	}
 	fmt.Fprintf(w, "]TJ")
+	if rise != 0.0 {
+		fmt.Fprintf(w, " 0 Ts")
+	}
 }
 
 func (w *pdfPageWriter) DrawImage(img image.Image, enc canvas.ImageEncoding, m canvas.Matrix) {
@@ -929,13 +1782,15 @@ func (w *pdfPageWriter) DrawImage(img image.Image, enc canvas.ImageEncoding, m c
 	br := m.Dot(canvas.Point{float64(size.X), 0})
 	tl := m.Dot(canvas.Point{0, float64(size.Y)})
 	tr := m.Dot(canvas.Point{float64(size.X), float64(size.Y)})
-	fmt.Fprintf(w, " q %v %v %v %v re W n", dec(outerRect.X), dec(outerRect.Y), dec(outerRect.W), dec(outerRect.H))
+	w.SaveState()
+	fmt.Fprintf(w, " %v %v %v %v re W n", dec(outerRect.X), dec(outerRect.Y), dec(outerRect.W), dec(outerRect.H))
 	fmt.Fprintf(w, " %v %v m %v %v l %v %v l %v %v l h W n", dec(bl.X), dec(bl.Y), dec(tl.X), dec(tl.Y), dec(tr.X), dec(tr.Y), dec(br.X), dec(br.Y))
 
 	name := w.embedImage(img, enc)
 	m = m.Scale(float64(size.X), float64(size.Y))
 	w.SetAlpha(1.0)
-	fmt.Fprintf(w, " %v %v %v %v %v %v cm /%v Do Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
+	fmt.Fprintf(w, " %v %v %v %v %v %v cm /%v Do", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
+	w.RestoreState()
 }
 
 func (w *pdfPageWriter) embedImage(img image.Image, enc canvas.ImageEncoding) pdfName {
@@ -970,19 +1825,41 @@ func (w *pdfPageWriter) embedImage(img image.Image, enc canvas.ImageEncoding) pd
 		"Interpolate":      true,
 		"Filter":           pdfFilterFlate,
 	}
+	if w.pdf.profile != NoProfile {
+		delete(dict, "Interpolate") // output profiles forbid interpolation
+	}
+	if w.pdf.profile == PDFX1a {
+		dict["ColorSpace"] = pdfName("DeviceCMYK")
+		bCMYK := make([]byte, size.X*size.Y*4)
+		for i := 0; i < size.X*size.Y; i++ {
+			c, m, y, k := rgbToCMYK(float64(b[3*i])/255.0, float64(b[3*i+1])/255.0, float64(b[3*i+2])/255.0)
+			bCMYK[4*i+0] = byte(c*255.0 + 0.5)
+			bCMYK[4*i+1] = byte(m*255.0 + 0.5)
+			bCMYK[4*i+2] = byte(y*255.0 + 0.5)
+			bCMYK[4*i+3] = byte(k*255.0 + 0.5)
+		}
+		b = bCMYK
+	}
 
 	if hasMask {
+		if w.pdf.profile == PDFX1a {
+			w.pdf.forbid("transparency")
+		}
+		mask := pdfDict{
+			"Type":             pdfName("XObject"),
+			"Subtype":          pdfName("Image"),
+			"Width":            size.X,
+			"Height":           size.Y,
+			"ColorSpace":       pdfName("DeviceGray"),
+			"BitsPerComponent": 8,
+			"Interpolate":      true,
+			"Filter":           pdfFilterFlate,
+		}
+		if w.pdf.profile != NoProfile {
+			delete(mask, "Interpolate")
+		}
 		dict["SMask"] = w.pdf.writeObject(pdfStream{
-			dict: pdfDict{
-				"Type":             pdfName("XObject"),
-				"Subtype":          pdfName("Image"),
-				"Width":            size.X,
-				"Height":           size.Y,
-				"ColorSpace":       pdfName("DeviceGray"),
-				"BitsPerComponent": 8,
-				"Interpolate":      true,
-				"Filter":           pdfFilterFlate,
-			},
+			dict:   mask,
 			stream: bMask,
 		})
 	}
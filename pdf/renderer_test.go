@@ -2,7 +2,9 @@ package pdf
 
 import (
 	"bytes"
+	"fmt"
 	"image"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -59,6 +61,34 @@ func TestPDFPath(t *testing.T) {
 	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm /A0 gs 1 0 0 rg /A1 gs 0 0 1 RG 5 w 1 J 1 j [1 2 3 1 2 3] 2 d")
 }
 
+func TestPDFClip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf.SetFillColor(canvas.Red)
+	pdf.PushClip("0 0 m 10 0 l 10 10 l h", canvas.EvenOdd)
+	pdf.SetFillColor(canvas.Blue)
+	pdf.PopClip()
+	pdf.SetFillColor(canvas.Red)
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm 1 0 0 rg q 0 0 m 10 0 l 10 10 l h W* n 0 0 1 rg Q")
+}
+
+func TestPDFGradient(t *testing.T) {
+	gradient := canvas.NewLinearGradient(canvas.Point{X: 0, Y: 0}, canvas.Point{X: 10, Y: 0})
+	gradient.Add(0, canvas.Red)
+	gradient.Add(1, canvas.Blue)
+
+	buf := &bytes.Buffer{}
+	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf.SetFillColor(canvas.Red)
+	pdf.FillGradient("0 0 m 10 0 l 10 10 l h", canvas.NonZero, gradient, canvas.Rect{X: 0, Y: 0, W: 10, H: 10}, canvas.Identity.Translate(5, 0))
+	pdf.SetFillColor(canvas.Red)
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm 1 0 0 rg q 0 0 m 10 0 l 10 10 l h W n 1 0 0 1 5 0 cm /Sh0 sh Q")
+
+	gradient.Stops[1].Color = canvas.Transparent
+	pdf.FillGradient("0 0 m 10 0 l 10 10 l h", canvas.EvenOdd, gradient, canvas.Rect{X: 0, Y: 0, W: 10, H: 10}, canvas.Identity)
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm 1 0 0 rg q 0 0 m 10 0 l 10 10 l h W n 1 0 0 1 5 0 cm /Sh0 sh Q q 0 0 m 10 0 l 10 10 l h W* n 1 0 0 1 0 0 cm /M0 gs /Sh1 sh Q")
+}
+
 func TestPDFText(t *testing.T) {
 	//dejaVuSerif := NewFontFamily("dejavu-serif")
 	//dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -106,3 +136,129 @@ func TestPDFMultipage(t *testing.T) {
 	nbPages := strings.Count(out, "/Type /Page ")
 	test.That(t, nbPages == 2, "expected 2 pages, got", nbPages)
 }
+
+func TestPDFXref(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 10, 10)
+	pdf.RenderPath(canvas.Rectangle(5.0, 5.0), canvas.DefaultStyle, canvas.Identity)
+	err := pdf.Close()
+	test.Error(t, err)
+
+	// every offset in the cross-reference table points to its object
+	out := buf.String()
+	xref := out[strings.Index(out, "xref\n"):strings.Index(out, "trailer\n")]
+	entries := strings.Split(strings.TrimSpace(xref), "\n")[3:] // skip header, subsection and free entry
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[:10])
+		test.Error(t, err)
+		test.That(t, strings.HasPrefix(out[offset:], fmt.Sprintf("%d 0 obj\n", i+1)), "offset of object", i+1)
+	}
+}
+
+func TestPDFFontSubset(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		test.Error(t, err)
+	}
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	text := canvas.NewTextLine(face, "AV", canvas.Left)
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210, 297)
+	pdf.SetCompression(false)
+	pdf.RenderText(text, canvas.Identity)
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+	test.That(t, strings.Contains(out, "[(\x00\x01) 47 (\x00\x02)]TJ"), "glyphs must be renumbered")
+	test.That(t, strings.Contains(out, "+dejavu-serif /CIDSystemInfo"), "subset font must be tagged")
+	test.That(t, strings.Contains(out, "/W [1 [722 722]]"), "widths of the subset glyphs")
+	test.That(t, len(out) < 100000, "font must be subset")
+
+	buf.Reset()
+	pdf = New(buf, 210, 297)
+	pdf.SetFontSubsetting(false)
+	pdf.RenderText(text, canvas.Identity)
+	err = pdf.Close()
+	test.Error(t, err)
+	test.That(t, 100000 < buf.Len(), "font must be embedded entirely")
+}
+
+func TestPDFLinks(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetCompression(false)
+	pdf.AddLink("https://example.com/", canvas.Rect{X: 0, Y: 0, W: 10, H: 10})
+	pdf.AddPageLink(2, 50, canvas.Rect{X: 0, Y: 10, W: 10, H: 10})
+	pdf.AddPageLink(3, 0, canvas.Rect{X: 0, Y: 20, W: 10, H: 10})
+	pdf.AddDestinationLink("chapter", canvas.Rect{X: 0, Y: 30, W: 10, H: 10})
+	pdf.RenderLink(canvas.Link{Page: 2}, canvas.Rect{X: 0, Y: 0, W: 10, H: 10}, canvas.Identity.Translate(10, 0))
+	pdf.NewPage(100, 100)
+	pdf.AddDestination("chapter", 100)
+	pdf.AddTextAnnotation(10, 90, "note")
+	pdf.AddTextAnnotation(10, 80, "č")
+	pdf.AddHighlight(canvas.Rect{X: 10, Y: 10, W: 10, H: 10}, canvas.Red, "día")
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	test.That(t, strings.Contains(out, "/Type /Annot /Subtype /Link /A << /S /URI /URI (https://example.com/) >> /Border [0 0 0] /P 4 0 R /Rect [0 0 28.346457 28.346457]"), "URI link")
+	test.That(t, strings.Contains(out, "/A << /D [5 0 R /XYZ null 141.73228 null] /S /GoTo >>"), "page link to position")
+	test.That(t, strings.Contains(out, "/A << /D [5 0 R /Fit] /S /GoTo >> /Border [0 0 0] /P 4 0 R /Rect [28.346457 0 56.692913 28.346457]"), "transformed page link")
+	test.That(t, strings.Count(out, "/Subtype /Link") == 4, "link to page that does not exist must be skipped")
+	test.That(t, strings.Contains(out, "/A << /D (chapter) /S /GoTo >>"), "link to named destination")
+	test.That(t, strings.Contains(out, "/Names << /Dests << /Names [(chapter) [5 0 R /XYZ null 283.46457 null]] >> >>"), "named destinations in catalog")
+	test.That(t, strings.Contains(out, "/Type /Annot /Subtype /Text /Contents (note) /Name /Comment /Open false /P 5 0 R /Rect [28.346457 235.11811 48.346457 255.11811]"), "text annotation")
+	test.That(t, strings.Contains(out, "/Type /Annot /Subtype /Highlight /C [1 0 0] /Contents (\xFE\xFF\x00d\x00\xED\x00a) /P 5 0 R /QuadPoints [28.346457 56.692913 56.692913 56.692913 28.346457 28.346457 56.692913 28.346457]"), "highlight annotation")
+	test.That(t, strings.Contains(out, "/Contents (\xFE\xFF\x01\\r)"), "text string with CR byte must be escaped")
+	test.That(t, strings.Count(out, "/Annots [") == 2, "annotations of both pages")
+}
+
+func TestPDFGlyphTexts(t *testing.T) {
+	var tts = []struct {
+		text   string
+		glyphs []canvas.Glyph
+		texts  []string
+	}{
+		{"ab", []canvas.Glyph{{ID: 1, Cluster: 0}, {ID: 2, Cluster: 1}}, []string{"a", "b"}},
+		{"ﬁx", []canvas.Glyph{{ID: 1, Cluster: 0}, {ID: 2, Cluster: 3}}, []string{"fi", "x"}},
+		{"ffi", []canvas.Glyph{{ID: 1, Cluster: 0}}, []string{"ffi"}},
+		{"é", []canvas.Glyph{{ID: 1, Cluster: 0}, {ID: 2, Cluster: 0}}, []string{"é", ""}},
+		{"ab", []canvas.Glyph{{ID: 2, Cluster: 1}, {ID: 1, Cluster: 0}}, []string{"b", "a"}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.text, func(t *testing.T) {
+			test.T(t, glyphTexts(tt.text, tt.glyphs), tt.texts)
+		})
+	}
+}
+
+func TestPDFToUnicode(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		test.Error(t, err)
+	}
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210, 297)
+	pdf.RenderText(canvas.NewTextLine(face, "AV A", canvas.Left), canvas.Identity)
+	cmap := string(pdf.w.pdf.fonts[face.Font].toUnicode())
+	test.That(t, strings.Contains(cmap, "3 beginbfchar\n<0001> <0041>\n<0002> <0056>\n<0003> <0020>\nendbfchar"), "glyphs of the subset must be mapped")
+	test.That(t, !strings.Contains(pdf.w.String(), "/ActualText"), "no actual text needed")
+
+	// reordered glyphs and glyphs with different text need actual text
+	pdf.w.StartTextObject()
+	pdf.w.SetFont(face.Font, 12.0)
+	test.That(t, !pdf.w.MapGlyphs("VA", []canvas.Glyph{{ID: 36, Cluster: 1}, {ID: 57, Cluster: 0}}))
+	test.That(t, !pdf.w.MapGlyphs("Ā", []canvas.Glyph{{ID: 36, Cluster: 0}}))
+	test.That(t, !pdf.w.MapGlyphs("Aﬁ", []canvas.Glyph{{ID: 36, Cluster: 0}, {ID: 0, Cluster: 1}}), ".notdef is not mapped")
+	pdf.w.EndTextObject()
+
+	text := canvas.NewTextLine(face, "שלום", canvas.Left)
+	pdf.RenderText(text, canvas.Identity)
+	test.That(t, strings.Contains(pdf.w.String(), " /Span << /ActualText <FEFF05E905DC05D505DD> >> BDC["), "missing glyphs need actual text")
+	err := pdf.Close()
+	test.Error(t, err)
+	test.That(t, strings.Contains(buf.String(), "/ToUnicode"), "font must have ToUnicode map")
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"fmt"
+)
+
+// Tag is the type of a structure element in a tagged PDF, which describes the role of the content for accessibility, such as for screen readers.
+type Tag string
+
+// see Tag
+const (
+	SectionTag   Tag = "Sect"
+	ParagraphTag Tag = "P"
+	Heading1Tag  Tag = "H1"
+	Heading2Tag  Tag = "H2"
+	Heading3Tag  Tag = "H3"
+	Heading4Tag  Tag = "H4"
+	Heading5Tag  Tag = "H5"
+	Heading6Tag  Tag = "H6"
+	FigureTag    Tag = "Figure"
+	CaptionTag   Tag = "Caption"
+)
+
+// SetTagged sets whether the document is a tagged PDF with a structure tree, which is disabled by default. It must be set before rendering. Content is tagged using BeginTag and EndTag, while content outside of tags is marked as an artifact, such as page decorations.
+func (r *PDF) SetTagged(tagged bool) {
+	if tagged && r.w.pdf.structRoot == nil {
+		r.w.pdf.structRoot = &pdfStructElem{tag: "Document"}
+		r.w.pdf.structElem = r.w.pdf.structRoot
+	} else if !tagged {
+		r.w.pdf.structRoot = nil
+		r.w.pdf.structElem = nil
+	}
+}
+
+// SetLanguage sets the natural language of the document's text as a language tag such as "en-US", which is used by screen readers.
+func (r *PDF) SetLanguage(lang string) {
+	r.w.pdf.lang = lang
+}
+
+// BeginTag starts a structure element that contains the content rendered until the matching EndTag, and which may contain nested structure elements. The alternate description describes content that is not text, such as figures, and is ignored when empty. Tags are ignored when the document is not tagged, see SetTagged.
+func (r *PDF) BeginTag(tag Tag, alt string) {
+	if r.w.pdf.structElem == nil {
+		return
+	}
+	r.w.endContent()
+
+	parent := r.w.pdf.structElem
+	elem := &pdfStructElem{
+		tag:    tag,
+		alt:    alt,
+		parent: parent,
+	}
+	parent.kids = append(parent.kids, elem)
+	r.w.pdf.structElem = elem
+}
+
+// EndTag ends the last structure element started by BeginTag.
+func (r *PDF) EndTag() {
+	if r.w.pdf.structElem == nil || r.w.pdf.structElem.parent == nil {
+		return
+	}
+	r.w.endContent()
+	r.w.pdf.structElem = r.w.pdf.structElem.parent
+}
+
+// pdfStructElem is a structure element of a tagged PDF.
+type pdfStructElem struct {
+	tag    Tag
+	alt    string
+	parent *pdfStructElem
+	kids   []interface{} // *pdfStructElem or pdfMarkedContent
+	ref    pdfRef
+}
+
+// pdfMarkedContent is a reference to the marked-content sequence with the given identifier on a page.
+type pdfMarkedContent struct {
+	page *pdfPageWriter
+	mcid int
+}
+
+// beginContent starts a marked-content sequence for the content that follows when the document is tagged, which is part of the current structure element or an artifact otherwise. It does nothing if a sequence has already been started.
+func (w *pdfPageWriter) beginContent() {
+	elem := w.pdf.structElem
+	if elem == nil || w.inContent {
+		return
+	} else if w.inTextObject {
+		panic("must not be in text object")
+	}
+
+	if elem.parent == nil {
+		fmt.Fprintf(w, " /Artifact BMC")
+	} else {
+		mcid := len(w.mcids)
+		w.mcids = append(w.mcids, elem)
+		elem.kids = append(elem.kids, pdfMarkedContent{w, mcid})
+		fmt.Fprintf(w, " /%v << /MCID %d >> BDC", elem.tag, mcid)
+	}
+	w.inContent = true
+}
+
+// endContent ends the marked-content sequence started by beginContent.
+func (w *pdfPageWriter) endContent() {
+	if w.inContent {
+		w.EndMarkedContent()
+		w.inContent = false
+	}
+}
+
+// writeStructTree writes the structure tree of a tagged PDF and returns its reference, or zero if the document is not tagged. The pages must have been written already.
+func (w *pdfWriter) writeStructTree() pdfRef {
+	if w.structRoot == nil {
+		return 0
+	}
+
+	// reserve object numbers so that elements can refer to their parent and children
+	ref := w.reserveObject()
+	var reserve func(*pdfStructElem)
+	reserve = func(elem *pdfStructElem) {
+		elem.ref = w.reserveObject()
+		for _, kid := range elem.kids {
+			if child, ok := kid.(*pdfStructElem); ok {
+				reserve(child)
+			}
+		}
+	}
+	reserve(w.structRoot)
+
+	var write func(*pdfStructElem, pdfRef)
+	write = func(elem *pdfStructElem, parent pdfRef) {
+		kids := pdfArray{}
+		for _, kid := range elem.kids {
+			if child, ok := kid.(*pdfStructElem); ok {
+				write(child, elem.ref)
+				kids = append(kids, child.ref)
+			} else if mc, ok := kid.(pdfMarkedContent); ok {
+				kids = append(kids, pdfDict{
+					"Type": pdfName("MCR"),
+					"Pg":   mc.page.ref,
+					"MCID": mc.mcid,
+				})
+			}
+		}
+
+		dict := pdfDict{
+			"Type": pdfName("StructElem"),
+			"S":    pdfName(elem.tag),
+			"P":    parent,
+			"K":    kids,
+		}
+		if elem.alt != "" {
+			dict["Alt"] = pdfTextString(elem.alt)
+		}
+		w.writeReservedObject(elem.ref, dict)
+	}
+	write(w.structRoot, ref)
+
+	// the parent tree maps the marked-content sequences of each page to their structure elements
+	nums := pdfArray{}
+	for _, page := range w.pages {
+		if 0 < len(page.mcids) {
+			parents := pdfArray{}
+			for _, elem := range page.mcids {
+				parents = append(parents, elem.ref)
+			}
+			nums = append(nums, page.structParents, parents)
+		}
+	}
+	w.writeReservedObject(ref, pdfDict{
+		"Type":              pdfName("StructTreeRoot"),
+		"K":                 w.structRoot.ref,
+		"ParentTree":        pdfDict{"Nums": nums},
+		"ParentTreeNextKey": len(nums) / 2,
+	})
+	return ref
+}
@@ -0,0 +1,63 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/dtrenin7/canvas"
+	"github.com/dtrenin7/test"
+)
+
+func TestPDFStructure(t *testing.T) {
+	rect := canvas.Rectangle(10.0, 10.0)
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 100, 100)
+	pdf.SetCompression(false)
+	pdf.SetTagged(true)
+	pdf.SetLanguage("en-US")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.BeginTag(SectionTag, "")
+	pdf.BeginTag(Heading1Tag, "")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.EndTag()
+	pdf.BeginTag(FigureTag, "A black square")
+	pdf.RenderImage(image.NewGray(image.Rect(0, 0, 1, 1)), canvas.Identity)
+	pdf.NewPage(100, 100)
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.EndTag()
+	pdf.EndTag()
+	pdf.EndTag() // unbalanced tags are ignored
+	err := pdf.Close()
+	test.Error(t, err)
+	out := buf.String()
+
+	test.That(t, strings.Contains(out, "cm /Artifact BMC 0 0 m 10 0 l 10 10 l 0 10 l f EMC /H1 << /MCID 0 >> BDC 0 0 m 10 0 l 10 10 l 0 10 l f 0 0 m 10 0 l 10 10 l 0 10 l f EMC /Figure << /MCID 1 >> BDC"), "marked content of the first page")
+	test.That(t, strings.Contains(out, "cm /Figure << /MCID 0 >> BDC 0 0 m 10 0 l 10 10 l 0 10 l f EMC\n"), "marked content of the second page")
+	test.That(t, strings.Contains(out, "/StructParents 0"), "first page in parent tree")
+	test.That(t, strings.Contains(out, "/StructParents 1"), "second page in parent tree")
+
+	// pages are 4 and 6, the structure tree root is 9, and the elements are 10 (Document), 11 (Sect), 12 (H1) and 13 (Figure)
+	test.That(t, strings.Contains(out, "9 0 obj\n<< /Type /StructTreeRoot /K 10 0 R /ParentTree << /Nums [0 [12 0 R 13 0 R] 1 [13 0 R]] >> /ParentTreeNextKey 2 >>"), "structure tree root")
+	test.That(t, strings.Contains(out, "10 0 obj\n<< /Type /StructElem /K [11 0 R] /P 9 0 R /S /Document >>"), "document element")
+	test.That(t, strings.Contains(out, "11 0 obj\n<< /Type /StructElem /K [12 0 R 13 0 R] /P 10 0 R /S /Sect >>"), "section element")
+	test.That(t, strings.Contains(out, "12 0 obj\n<< /Type /StructElem /K [<< /Type /MCR /MCID 0 /Pg 4 0 R >>] /P 11 0 R /S /H1 >>"), "heading element")
+	test.That(t, strings.Contains(out, "13 0 obj\n<< /Type /StructElem /Alt (A black square) /K [<< /Type /MCR /MCID 1 /Pg 4 0 R >> << /Type /MCR /MCID 0 /Pg 6 0 R >>] /P 11 0 R /S /Figure >>"), "figure element across pages")
+	test.That(t, strings.Contains(out, "/Lang (en-US) /MarkInfo << /Marked true >>"), "language and marked catalog")
+	test.That(t, strings.Contains(out, "/StructTreeRoot 9 0 R"), "structure tree in catalog")
+
+	// untagged documents ignore tags
+	buf.Reset()
+	pdf = New(buf, 100, 100)
+	pdf.SetCompression(false)
+	pdf.BeginTag(ParagraphTag, "")
+	pdf.RenderPath(rect, canvas.DefaultStyle, canvas.Identity)
+	pdf.EndTag()
+	err = pdf.Close()
+	test.Error(t, err)
+	test.That(t, !strings.Contains(buf.String(), "BDC"), "no marked content")
+	test.That(t, !strings.Contains(buf.String(), "StructTreeRoot"), "no structure tree")
+}
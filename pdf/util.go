@@ -2,8 +2,11 @@ package pdf
 
 import (
 	"fmt"
+	"image/color"
 	"math"
+	"sort"
 	"strings"
+	"unicode/utf16"
 
 	"github.com/dtrenin7/canvas"
 	"github.com/dtrenin7/minify/v2"
@@ -37,3 +40,93 @@ func (f dec) String() string {
 	}
 	return s
 }
+
+// pdfRect returns the rectangle in page coordinates as a PDF rectangle in points.
+func pdfRect(rect canvas.Rect) pdfArray {
+	return pdfArray{rect.X * ptPerMm, rect.Y * ptPerMm, (rect.X + rect.W) * ptPerMm, (rect.Y + rect.H) * ptPerMm}
+}
+
+// pdfColor returns the color as an array of RGB components between 0 and 1, ignoring the alpha channel.
+func pdfColor(col color.RGBA) pdfArray {
+	if col.A == 0 {
+		return pdfArray{0.0, 0.0, 0.0}
+	}
+	a := float64(col.A) / 255.0
+	return pdfArray{float64(col.R) / 255.0 / a, float64(col.G) / 255.0 / a, float64(col.B) / 255.0 / a}
+}
+
+// pdfTextString encodes a text string such as the contents of an annotation, which is encoded as UTF-16BE with a byte order mark unless it is ASCII.
+func pdfTextString(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if 0x80 <= s[i] {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("\xFE\xFF")
+	for _, c := range utf16.Encode([]rune(s)) {
+		sb.WriteByte(byte(c >> 8))
+		sb.WriteByte(byte(c))
+	}
+	return sb.String()
+}
+
+// presentationForms are the Latin ligatures of the alphabetic presentation forms, which are expanded for text extraction.
+var presentationForms = map[rune]string{
+	'\uFB00': "ff",
+	'\uFB01': "fi",
+	'\uFB02': "fl",
+	'\uFB03': "ffi",
+	'\uFB04': "ffl",
+	'\uFB05': "st",
+	'\uFB06': "st",
+}
+
+// expandLigatures replaces the ligatures of the alphabetic presentation forms by their characters.
+func expandLigatures(s string) string {
+	if !strings.ContainsAny(s, "\uFB00\uFB01\uFB02\uFB03\uFB04\uFB05\uFB06") {
+		return s
+	}
+
+	sb := strings.Builder{}
+	for _, r := range s {
+		if lig, ok := presentationForms[r]; ok {
+			sb.WriteString(lig)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// glyphTexts returns the text that each glyph represents, where the glyphs are shaped from text. The first glyph of each cluster represents the text of the cluster, while the other glyphs of the cluster represent no text.
+func glyphTexts(text string, glyphs []canvas.Glyph) []string {
+	clusters := make([]int, 0, len(glyphs))
+	for _, glyph := range glyphs {
+		clusters = append(clusters, glyph.Cluster)
+	}
+	sort.Ints(clusters)
+
+	texts := make([]string, len(glyphs))
+	seen := map[int]bool{}
+	for i, glyph := range glyphs {
+		if seen[glyph.Cluster] || glyph.Cluster < 0 || len(text) < glyph.Cluster {
+			continue
+		}
+		seen[glyph.Cluster] = true
+
+		// the cluster ends at the next cluster in text order
+		end := len(text)
+		if j := sort.SearchInts(clusters, glyph.Cluster+1); j < len(clusters) {
+			end = clusters[j]
+		}
+		texts[i] = expandLigatures(text[glyph.Cluster:end])
+	}
+	return texts
+}
@@ -2,11 +2,11 @@ package rasterizer
 
 import (
 	"image"
+	"image/color"
 
 	"github.com/dtrenin7/canvas"
 	"golang.org/x/image/draw"
 	"golang.org/x/image/math/f64"
-	"golang.org/x/image/vector"
 )
 
 // Draw draws the canvas on a new image with given resolution (in dots-per-millimeter).
@@ -21,6 +21,7 @@ func Draw(c *canvas.Canvas, resolution canvas.DPMM) *image.RGBA {
 type Renderer struct {
 	img        draw.Image
 	resolution canvas.DPMM
+	clips      []*image.Alpha // coverage masks of the clipping paths, each intersected with the previous one
 }
 
 // New creates a renderer that draws to a rasterized image.
@@ -38,16 +39,28 @@ func (r *Renderer) Size() (float64, float64) {
 }
 
 func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	// TODO: use fill rule (EvenOdd, NonZero) for rasterizer
 	path = path.Transform(m)
 
+	var outline *canvas.Path
 	strokeWidth := 0.0
-	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
-		strokeWidth = style.StrokeWidth
+	if style.HasStroke() {
+		if style.StrokeProfile != nil {
+			// the stroke width varies along the path, use the bounds of the stroke outline instead
+			outline = path
+			if 0 < len(style.Dashes) {
+				outline = outline.Dash(style.DashOffset, style.Dashes...)
+			}
+			outline = style.StrokeOutline(outline)
+		} else {
+			strokeWidth = style.StrokeWidth
+		}
 	}
 
 	size := r.img.Bounds().Size()
 	bounds := path.Bounds()
+	if outline != nil && !outline.Empty() {
+		bounds = bounds.Add(outline.Bounds())
+	}
 	dx, dy := 0, 0
 	resolution := float64(r.resolution)
 	x := int((bounds.X - strokeWidth) * resolution)
@@ -77,28 +90,86 @@ func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Ma
 	}
 
 	path = path.Translate(-float64(x)/resolution, -float64(y)/resolution)
-	if style.FillColor.A != 0 {
-		ras := vector.NewRasterizer(w, h)
-		path.ToRasterizer(ras, resolution)
-		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.FillColor), image.Point{dx, dy})
-	}
-	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
-		if 0 < len(style.Dashes) {
-			path = path.Dash(style.DashOffset, style.Dashes...)
+	if style.HasFill() {
+		ras := newScanlineRasterizer(w, h)
+		ras.AddPath(path, resolution)
+		r.draw(ras.Mask(style.FillRule), image.Rect(x, size.Y-y, x+w, size.Y-y-h), r.paint(style.Fill, m), image.Point{dx, dy})
+	}
+	if style.HasStroke() {
+		if outline != nil {
+			path = outline.Translate(-float64(x)/resolution, -float64(y)/resolution)
+		} else {
+			if 0 < len(style.Dashes) {
+				path = path.Dash(style.DashOffset, style.Dashes...)
+			}
+			path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
 		}
-		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
 
-		ras := vector.NewRasterizer(w, h)
-		path.ToRasterizer(ras, resolution)
-		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.StrokeColor), image.Point{dx, dy})
+		ras := newScanlineRasterizer(w, h)
+		ras.AddPath(path, resolution)
+		r.draw(ras.Mask(canvas.NonZero), image.Rect(x, size.Y-y, x+w, size.Y-y-h), r.paint(style.Stroke, m), image.Point{dx, dy})
+	}
+}
+
+// paint returns the source image for a solid color or a gradient, where m transforms the gradient to the canvas coordinate system.
+func (r *Renderer) paint(paint canvas.Paint, m canvas.Matrix) image.Image {
+	if !paint.IsGradient() {
+		return image.NewUniform(paint.Color)
 	}
+	return gradientImage{
+		gradient:   paint.Gradient,
+		inv:        m.Inv(),
+		resolution: float64(r.resolution),
+		height:     float64(r.img.Bounds().Size().Y),
+	}
+}
+
+// gradientImage is an infinite image with the colors of a gradient evaluated at the pixel centers, it has the same coordinate system as the destination image.
+type gradientImage struct {
+	gradient   canvas.Gradient
+	inv        canvas.Matrix
+	resolution float64
+	height     float64
+}
+
+func (img gradientImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img gradientImage) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (img gradientImage) At(x, y int) color.Color {
+	p := canvas.Point{X: (float64(x) + 0.5) / img.resolution, Y: (img.height - float64(y) - 0.5) / img.resolution}
+	p = img.inv.Dot(p)
+	return img.gradient.At(p.X, p.Y)
+}
+
+// draw draws src onto the image within rect using the coverage mask, restricted to the current clipping area.
+func (r *Renderer) draw(mask *image.Alpha, rect image.Rectangle, src image.Image, sp image.Point) {
+	rect = rect.Canon()
+	if _, ok := src.(gradientImage); ok {
+		sp = rect.Min // gradient images are in the coordinate system of the destination
+	}
+	if 0 < len(r.clips) {
+		clip := r.clips[len(r.clips)-1]
+		for j := 0; j < rect.Dy(); j++ {
+			for i := 0; i < rect.Dx(); i++ {
+				a := uint32(mask.Pix[j*mask.Stride+i])
+				c := uint32(clip.AlphaAt(rect.Min.X+i, rect.Min.Y+j).A)
+				mask.Pix[j*mask.Stride+i] = uint8(a * c / 0xff)
+			}
+		}
+	}
+	draw.DrawMask(r.img, rect, src, sp, mask, image.Point{}, draw.Over)
 }
 
 func (r *Renderer) RenderText(text *canvas.Text, m canvas.Matrix) {
 	paths, colors := text.ToPaths()
 	for i, path := range paths {
 		style := canvas.DefaultStyle
-		style.FillColor = colors[i]
+		style.Fill.Color = colors[i]
 		r.RenderPath(path, style, m)
 	}
 }
@@ -119,5 +190,32 @@ func (r *Renderer) RenderImage(img image.Image, m canvas.Matrix) {
 
 	h := float64(r.img.Bounds().Size().Y)
 	aff3 := f64.Aff3{m[0][0], -m[0][1], origin.X, -m[1][0], m[1][1], h - origin.Y}
-	draw.CatmullRom.Transform(r.img, aff3, img2, img2.Bounds(), draw.Over, nil)
+	var opts *draw.Options
+	if 0 < len(r.clips) {
+		opts = &draw.Options{DstMask: r.clips[len(r.clips)-1]}
+	}
+	draw.CatmullRom.Transform(r.img, aff3, img2, img2.Bounds(), draw.Over, opts)
+}
+
+// PushClip restricts drawing to the area filled by the path, intersected with the current clipping area.
+func (r *Renderer) PushClip(path *canvas.Path, fillRule canvas.FillRule, m canvas.Matrix) {
+	size := r.img.Bounds().Size()
+	ras := newScanlineRasterizer(size.X, size.Y)
+	ras.AddPath(path.Transform(m), float64(r.resolution))
+
+	clip := ras.Mask(fillRule)
+	if 0 < len(r.clips) {
+		prev := r.clips[len(r.clips)-1]
+		for i := range clip.Pix {
+			clip.Pix[i] = uint8(uint32(clip.Pix[i]) * uint32(prev.Pix[i]) / 0xff)
+		}
+	}
+	r.clips = append(r.clips, clip)
+}
+
+// PopClip removes the last clipping path.
+func (r *Renderer) PopClip() {
+	if 0 < len(r.clips) {
+		r.clips = r.clips[:len(r.clips)-1]
+	}
 }
@@ -0,0 +1,224 @@
+package rasterizer
+
+import (
+	"image"
+	"math"
+
+	"github.com/dtrenin7/canvas"
+)
+
+// scanlineRasterizer rasterizes paths into a coverage mask by accumulating the signed area that each line covers per pixel, similar to golang.org/x/image/vector. Keeping the signed area allows filling with both the non-zero and the even-odd fill rule.
+type scanlineRasterizer struct {
+	w, h   int
+	acc    []float32 // signed area per pixel, with two extra columns per row for lines at the right edge
+	start  canvas.Point
+	pen    canvas.Point
+	closed bool
+}
+
+func newScanlineRasterizer(w, h int) *scanlineRasterizer {
+	return &scanlineRasterizer{
+		w:      w,
+		h:      h,
+		acc:    make([]float32, (w+2)*h),
+		closed: true,
+	}
+}
+
+// AddPath adds the path, given in millimeters with the y-axis pointing up, using the resolution in dots-per-millimeter. Open subpaths are closed implicitly.
+func (r *scanlineRasterizer) AddPath(path *canvas.Path, dpm float64) {
+	h := float64(r.h)
+	toPixel := func(p canvas.Point) canvas.Point {
+		return canvas.Point{X: p.X * dpm, Y: h - p.Y*dpm}
+	}
+
+	path.ReplaceArcs().Iterate(func(start, end canvas.Point) {
+		r.moveTo(toPixel(end))
+	}, func(start, end canvas.Point) {
+		r.lineTo(toPixel(end))
+	}, func(start, cp, end canvas.Point) {
+		r.quadTo(toPixel(cp), toPixel(end))
+	}, func(start, cp1, cp2, end canvas.Point) {
+		r.cubeTo(toPixel(cp1), toPixel(cp2), toPixel(end))
+	}, func(start canvas.Point, rx, ry, rot float64, large, sweep bool, end canvas.Point) {
+		panic("arcs should have been replaced")
+	}, func(start, end canvas.Point) {
+		r.closePath()
+	})
+	r.closePath()
+}
+
+func (r *scanlineRasterizer) moveTo(p canvas.Point) {
+	r.closePath()
+	r.start = p
+	r.pen = p
+	r.closed = false
+}
+
+func (r *scanlineRasterizer) lineTo(p canvas.Point) {
+	r.line(r.pen, p)
+	r.pen = p
+}
+
+// quadTo flattens the quadratic Bézier into lines, the number of lines depends on the deviation of the control point (as in golang.org/x/image/vector).
+func (r *scanlineRasterizer) quadTo(cp, end canvas.Point) {
+	n := flattenSegments(devSquared(r.pen, cp, end))
+	p0 := r.pen
+	for i := 1; i < n; i++ {
+		t := float64(i) / float64(n)
+		q0 := p0.Interpolate(cp, t)
+		q1 := cp.Interpolate(end, t)
+		r.lineTo(q0.Interpolate(q1, t))
+	}
+	r.lineTo(end)
+}
+
+// cubeTo flattens the cubic Bézier into lines, the number of lines depends on the deviation of the control points (as in golang.org/x/image/vector).
+func (r *scanlineRasterizer) cubeTo(cp1, cp2, end canvas.Point) {
+	n := flattenSegments(math.Max(devSquared(r.pen, cp1, end), devSquared(r.pen, cp2, end)))
+	p0 := r.pen
+	for i := 1; i < n; i++ {
+		t := float64(i) / float64(n)
+		q0 := p0.Interpolate(cp1, t)
+		q1 := cp1.Interpolate(cp2, t)
+		q2 := cp2.Interpolate(end, t)
+		q0 = q0.Interpolate(q1, t)
+		q1 = q1.Interpolate(q2, t)
+		r.lineTo(q0.Interpolate(q1, t))
+	}
+	r.lineTo(end)
+}
+
+func (r *scanlineRasterizer) closePath() {
+	if !r.closed {
+		r.lineTo(r.start)
+		r.closed = true
+	}
+}
+
+// devSquared returns the squared distance between q and the midpoint of p and s.
+func devSquared(p, q, s canvas.Point) float64 {
+	d := q.Mul(2.0).Sub(p).Sub(s)
+	return d.Dot(d)
+}
+
+func flattenSegments(devsq float64) int {
+	const tolerance = 3.0
+	if devsq < 0.333 {
+		return 1
+	}
+	return 1 + int(math.Sqrt(math.Sqrt(tolerance*devsq)))
+}
+
+// line adds a line in pixel coordinates. Parts outside the left and right edges are clamped onto the edges, as they still change the winding for the pixels to their right.
+func (r *scanlineRasterizer) line(p0, p1 canvas.Point) {
+	w := float64(r.w)
+	for _, x := range [2]float64{0.0, w} {
+		if (p0.X < x) != (p1.X < x) && p0.X != x && p1.X != x {
+			// split at the edge
+			p := p0.Interpolate(p1, (x-p0.X)/(p1.X-p0.X))
+			p.X = x
+			r.line(p0, p)
+			r.line(p, p1)
+			return
+		}
+	}
+	p0.X = math.Max(0.0, math.Min(w, p0.X))
+	p1.X = math.Max(0.0, math.Min(w, p1.X))
+	r.accumulateLine(float32(p0.X), float32(p0.Y), float32(p1.X), float32(p1.Y))
+}
+
+// accumulateLine adds the signed area of a line between x=0 and x=w to the accumulation buffer, see https://medium.com/@raphlinus/inside-the-fastest-font-renderer-in-the-world-75ae5270c445
+func (r *scanlineRasterizer) accumulateLine(x0, y0, x1, y1 float32) {
+	if y0 == y1 {
+		return
+	}
+	dir := float32(1.0)
+	if y1 < y0 {
+		dir = -1.0
+		x0, y0, x1, y1 = x1, y1, x0, y0
+	}
+	dxdy := (x1 - x0) / (y1 - y0)
+	x := x0
+	if y0 < 0.0 {
+		x -= y0 * dxdy
+	}
+
+	stride := r.w + 2
+	yStart := int(math.Max(0.0, float64(y0)))
+	yEnd := int(math.Min(float64(r.h), math.Ceil(float64(y1))))
+	for y := yStart; y < yEnd; y++ {
+		row := r.acc[y*stride : (y+1)*stride]
+		dy := float32(math.Min(float64(y+1), float64(y1)) - math.Max(float64(y), float64(y0)))
+		xNext := x + dxdy*dy
+		d := dy * dir
+		xa, xb := x, xNext
+		if xb < xa {
+			xa, xb = xb, xa
+		}
+		if xa < 0.0 {
+			xa = 0.0 // rounding errors
+		}
+		if float32(r.w) < xb {
+			xb = float32(r.w)
+		}
+		xaFloor := float32(math.Floor(float64(xa)))
+		xaInt := int(xaFloor)
+		xbCeil := float32(math.Ceil(float64(xb)))
+		xbInt := int(xbCeil)
+		if xbInt <= xaInt+1 {
+			// within a single pixel
+			xmf := 0.5*(x+xNext) - xaFloor
+			row[xaInt] += d - d*xmf
+			row[xaInt+1] += d * xmf
+		} else {
+			s := 1.0 / (xb - xa)
+			xaf := xa - xaFloor
+			a0 := 0.5 * s * (1.0 - xaf) * (1.0 - xaf)
+			xbf := xb - xbCeil + 1.0
+			am := 0.5 * s * xbf * xbf
+			row[xaInt] += d * a0
+			if xbInt == xaInt+2 {
+				row[xaInt+1] += d * (1.0 - a0 - am)
+			} else {
+				a1 := s * (1.5 - xaf)
+				row[xaInt+1] += d * (a1 - a0)
+				for xi := xaInt + 2; xi < xbInt-1; xi++ {
+					row[xi] += d * s
+				}
+				a2 := a1 + float32(xbInt-xaInt-3)*s
+				row[xbInt-1] += d * (1.0 - a2 - am)
+			}
+			row[xbInt] += d * am
+		}
+		x = xNext
+	}
+}
+
+// Mask returns the coverage of the added paths using the fill rule.
+func (r *scanlineRasterizer) Mask(fillRule canvas.FillRule) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, r.w, r.h))
+	stride := r.w + 2
+	for y := 0; y < r.h; y++ {
+		acc := float32(0.0)
+		row := r.acc[y*stride : (y+1)*stride]
+		pix := mask.Pix[y*mask.Stride : y*mask.Stride+r.w]
+		for x := range pix {
+			acc += row[x]
+			a := acc
+			if a < 0.0 {
+				a = -a
+			}
+			if fillRule == canvas.EvenOdd {
+				a -= 2.0 * float32(math.Floor(float64(a)/2.0))
+				if 1.0 < a {
+					a = 2.0 - a
+				}
+			} else if 1.0 < a {
+				a = 1.0
+			}
+			pix[x] = uint8(a*255.0 + 0.5)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,59 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/dtrenin7/canvas"
+	"github.com/dtrenin7/test"
+)
+
+func TestScanlineRasterizer(t *testing.T) {
+	var tts = []struct {
+		p        string
+		fillRule canvas.FillRule
+		x, y     int
+		a        uint8
+	}{
+		{"M1 1H9V9H1z", canvas.NonZero, 5, 5, 255},
+		{"M1 1H9V9H1z", canvas.NonZero, 0, 5, 0},
+		{"M1.5 1H9V9H1.5z", canvas.NonZero, 1, 5, 128},
+		{"M1 1.25H9V9H1z", canvas.NonZero, 5, 8, 191},
+		{"M-5 -5H5V5H-5z", canvas.NonZero, 0, 9, 255}, // outside the left edge
+		{"M-5 -5H5V5H-5z", canvas.NonZero, 5, 9, 0},
+		{"M0 0H10V10H0zM2 2H8V8H2z", canvas.NonZero, 5, 5, 255},
+		{"M0 0H10V10H0zM2 2H8V8H2z", canvas.EvenOdd, 5, 5, 0},
+		{"M0 0H10V10H0zM2 2H8V8H2z", canvas.EvenOdd, 1, 5, 255},
+		{"M0 0H10V10H0zM2 2V8H8V2z", canvas.NonZero, 5, 5, 0},
+		{"M0 0H10V10H0zM2 2V8H8V2z", canvas.EvenOdd, 5, 5, 0},
+		{"M0 0H10V10H0zM0 0H10V10H0zM0 0H10V10H0z", canvas.EvenOdd, 5, 5, 255},
+		{"M0 0H10V10", canvas.NonZero, 8, 5, 255}, // implicitly closed
+		{"M0 0H10V10", canvas.NonZero, 1, 5, 0},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			ras := newScanlineRasterizer(10, 10)
+			ras.AddPath(canvas.MustParseSVG(tt.p), 1.0)
+			test.T(t, ras.Mask(tt.fillRule).AlphaAt(tt.x, tt.y), color.Alpha{tt.a})
+		})
+	}
+}
+
+func TestRendererFillRule(t *testing.T) {
+	donut := canvas.Circle(4.0).Translate(5.0, 5.0).Append(canvas.Circle(2.0).Translate(5.0, 5.0))
+
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	r := New(img, 1.0)
+	style := canvas.DefaultStyle
+	style.FillRule = canvas.EvenOdd
+	r.RenderPath(donut, style, canvas.Identity)
+	test.T(t, img.RGBAAt(5, 5), color.RGBA{})
+	test.T(t, img.RGBAAt(5, 2), canvas.Black)
+
+	img = image.NewRGBA(image.Rect(0, 0, 10, 10))
+	r = New(img, 1.0)
+	style.FillRule = canvas.NonZero
+	r.RenderPath(donut, style, canvas.Identity)
+	test.T(t, img.RGBAAt(5, 5), canvas.Black)
+}
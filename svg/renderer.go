@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"html"
 	"image"
 	"image/color"
 	"image/jpeg"
@@ -13,14 +14,23 @@ import (
 	"strings"
 
 	"github.com/dtrenin7/canvas"
+	canvasFont "github.com/dtrenin7/canvas/font"
 )
 
 type SVG struct {
 	w             io.Writer
 	width, height float64
 	embedFonts    bool
+	subsetFonts   bool
 	fonts         map[*canvas.Font]bool
+	fontGlyphs    map[*canvas.Font][]uint16
+	fontList      []*canvas.Font
 	maskID        int
+	gradientID    int
+	clipID        int
+	layers        []string // names of the open layer groups
+	clips         []string // IDs of the open clipping groups, which are nested inside the layer groups
+	textPathID    int
 	imgEnc        canvas.ImageEncoding
 
 	classes []string
@@ -30,18 +40,32 @@ type SVG struct {
 func New(w io.Writer, width, height float64) *SVG {
 	fmt.Fprintf(w, `<svg version="1.1" width="%vmm" height="%vmm" viewBox="0 0 %v %v" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`, dec(width), dec(height), dec(width), dec(height))
 	return &SVG{
-		w:          w,
-		width:      width,
-		height:     height,
-		embedFonts: true,
-		fonts:      map[*canvas.Font]bool{},
-		maskID:     0,
-		imgEnc:     canvas.Lossless,
-		classes:    []string{},
+		w:           w,
+		width:       width,
+		height:      height,
+		embedFonts:  true,
+		subsetFonts: true,
+		fonts:       map[*canvas.Font]bool{},
+		fontGlyphs:  map[*canvas.Font][]uint16{},
+		maskID:      0,
+		gradientID:  0,
+		clipID:      0,
+		textPathID:  0,
+		imgEnc:      canvas.Lossless,
+		classes:     []string{},
 	}
 }
 
 func (r *SVG) Close() error {
+	r.closeClips()
+	r.clips = nil
+	for range r.layers {
+		fmt.Fprintf(r.w, "</g>")
+	}
+	r.layers = nil
+	if 0 < len(r.fontList) {
+		r.writeFontSubsets()
+	}
 	_, err := fmt.Fprintf(r.w, "</svg>")
 	return err
 }
@@ -77,6 +101,11 @@ func (r *SVG) EmbedFonts(embedFonts bool) {
 	r.embedFonts = embedFonts
 }
 
+// SetFontSubsetting sets whether only the used glyphs of fonts are embedded, which is enabled by default. Font subsets are written when closing the SVG.
+func (r *SVG) SetFontSubsetting(subsetFonts bool) {
+	r.subsetFonts = subsetFonts
+}
+
 func (r *SVG) SetImageEncoding(enc canvas.ImageEncoding) {
 	r.imgEnc = enc
 }
@@ -104,19 +133,70 @@ func (r *SVG) writeFonts(fonts []*canvas.Font) {
 	}
 }
 
+// addGlyphs records the glyphs used by the text for the font subsets.
+func (r *SVG) addGlyphs(text *canvas.Text) {
+	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+		font := span.Face.Font
+		if _, ok := r.fontGlyphs[font]; !ok {
+			r.fontList = append(r.fontList, font)
+		}
+		r.fontGlyphs[font] = append(r.fontGlyphs[font], font.IndicesOf(span.Text)...)
+		if span.Face.Variant&canvas.FontSmallcaps != 0 {
+			// small-caps are synthesized from the uppercase glyphs
+			r.fontGlyphs[font] = append(r.fontGlyphs[font], font.IndicesOf(strings.ToUpper(span.Text))...)
+		}
+		// glyphs substituted while shaping, such as ligatures, contextual forms and mirrored characters
+		for _, glyph := range span.Glyphs() {
+			r.fontGlyphs[font] = append(r.fontGlyphs[font], glyph.ID)
+		}
+	})
+}
+
+// writeFontSubsets writes the fonts with only the outlines of the glyphs used in the document, falling back to the entire font when it cannot be subset. The glyph indices and the layout tables are kept since the text is shaped by the viewer.
+func (r *SVG) writeFontSubsets() {
+	fmt.Fprintf(r.w, "<style>")
+	for _, font := range r.fontList {
+		mimetype, raw := font.Raw()
+		if b, err := canvasFont.ToSFNT(raw); err == nil {
+			if subset, err := canvasFont.SubsetKeepIndices(b, r.fontGlyphs[font]); err == nil {
+				if subsetMimetype, err := canvasFont.MediaType(subset); err == nil {
+					mimetype, raw = subsetMimetype, subset
+				}
+			}
+		}
+		fmt.Fprintf(r.w, "\n@font-face{font-family:'%s';src:url('data:%s;base64,", font.Name(), mimetype)
+		encoder := base64.NewEncoder(base64.StdEncoding, r.w)
+		encoder.Write(raw)
+		encoder.Close()
+		fmt.Fprintf(r.w, "');}")
+	}
+	fmt.Fprintf(r.w, "\n</style>")
+}
+
 func (r *SVG) Size() (float64, float64) {
 	return r.width, r.height
 }
 
 func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	fill := style.FillColor.A != 0
-	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+	fill := style.HasFill()
+	stroke := style.HasStroke()
 
-	path = path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m))
+	m = canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m)
+	fillPaint, strokePaint := "", ""
+	if fill {
+		fillPaint = r.writePaint(style.Fill, m)
+	}
+	if stroke {
+		strokePaint = r.writePaint(style.Stroke, m)
+	}
+
+	path = path.Transform(m)
 	fmt.Fprintf(r.w, `<path d="%s`, path.ToSVG())
 
 	strokeUnsupported := false
-	if arcs, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok && math.IsNaN(arcs.Limit) {
+	if style.StrokeProfile != nil {
+		strokeUnsupported = true
+	} else if arcs, ok := style.StrokeJoiner.(canvas.ArcsJoiner); ok && math.IsNaN(arcs.Limit) {
 		strokeUnsupported = true
 	} else if miter, ok := style.StrokeJoiner.(canvas.MiterJoiner); ok {
 		if math.IsNaN(miter.Limit) {
@@ -128,8 +208,8 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 
 	if !stroke {
 		if fill {
-			if style.FillColor != canvas.Black {
-				fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(style.FillColor))
+			if style.Fill.IsGradient() || style.Fill.Color != canvas.Black {
+				fmt.Fprintf(r.w, `" fill="%v`, fillPaint)
 			}
 			if style.FillRule == canvas.EvenOdd {
 				fmt.Fprintf(r.w, `" fill-rule="evenodd`)
@@ -140,8 +220,8 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	} else {
 		b := &strings.Builder{}
 		if fill {
-			if style.FillColor != canvas.Black {
-				fmt.Fprintf(b, ";fill:%v", canvas.CSSColor(style.FillColor))
+			if style.Fill.IsGradient() || style.Fill.Color != canvas.Black {
+				fmt.Fprintf(b, ";fill:%v", fillPaint)
 			}
 			if style.FillRule == canvas.EvenOdd {
 				fmt.Fprintf(b, ";fill-rule:evenodd")
@@ -150,7 +230,7 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			fmt.Fprintf(b, ";fill:none")
 		}
 		if stroke && !strokeUnsupported {
-			fmt.Fprintf(b, `;stroke:%v`, canvas.CSSColor(style.StrokeColor))
+			fmt.Fprintf(b, `;stroke:%v`, strokePaint)
 			if style.StrokeWidth != 1.0 {
 				fmt.Fprintf(b, ";stroke-width:%v", dec(style.StrokeWidth))
 			}
@@ -201,10 +281,10 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 		if 0 < len(style.Dashes) {
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
-		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		path = style.StrokeOutline(path)
 		fmt.Fprintf(r.w, `<path d="%s`, path.ToSVG())
-		if style.StrokeColor != canvas.Black {
-			fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(style.StrokeColor))
+		if style.Stroke.IsGradient() || style.Stroke.Color != canvas.Black {
+			fmt.Fprintf(r.w, `" fill="%v`, strokePaint)
 		}
 		if style.FillRule == canvas.EvenOdd {
 			fmt.Fprintf(r.w, `" fill-rule="evenodd`)
@@ -214,6 +294,141 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	}
 }
 
+// writePaint returns the SVG paint for a solid color or a gradient, where gradients are written as definitions that are transformed by m. Unsupported gradients are painted with the color.
+func (r *SVG) writePaint(paint canvas.Paint, m canvas.Matrix) string {
+	if paint.IsGradient() {
+		if ref := r.writeGradient(paint.Gradient, m); ref != "" {
+			return "url(#" + ref + ")"
+		}
+	}
+	return canvas.CSSColor(paint.Color).String()
+}
+
+// writeGradient writes a gradient definition that is transformed by m and returns its ID, or an empty string if the gradient is not supported.
+func (r *SVG) writeGradient(gradient canvas.Gradient, m canvas.Matrix) string {
+	var stops canvas.Stops
+	var spread canvas.Spread
+	ref := fmt.Sprintf("g%v", r.gradientID)
+	switch g := gradient.(type) {
+	case *canvas.LinearGradient:
+		fmt.Fprintf(r.w, `<linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%v" y1="%v" x2="%v" y2="%v`, ref, dec(g.Start.X), dec(g.Start.Y), dec(g.End.X), dec(g.End.Y))
+		stops, spread = g.Stops, g.Spread
+	case *canvas.RadialGradient:
+		fmt.Fprintf(r.w, `<radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%v" cy="%v" r="%v`, ref, dec(g.C1.X), dec(g.C1.Y), dec(g.R1))
+		if !g.C0.Equals(g.C1) {
+			fmt.Fprintf(r.w, `" fx="%v" fy="%v`, dec(g.C0.X), dec(g.C0.Y))
+		}
+		if g.R0 != 0.0 {
+			fmt.Fprintf(r.w, `" fr="%v`, dec(g.R0))
+		}
+		stops, spread = g.Stops, g.Spread
+	default:
+		return ""
+	}
+	r.gradientID++
+
+	if spread == canvas.ReflectSpread {
+		fmt.Fprintf(r.w, `" spreadMethod="reflect`)
+	} else if spread == canvas.RepeatSpread {
+		fmt.Fprintf(r.w, `" spreadMethod="repeat`)
+	}
+	if !m.Equals(canvas.Identity) {
+		fmt.Fprintf(r.w, `" gradientTransform="matrix(%v,%v,%v,%v,%v,%v)`, dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]))
+	}
+	fmt.Fprintf(r.w, `">`)
+	for i, stop := range stops {
+		if stop.Color.A != 0 {
+			r.writeStop(stop.Offset, stop.Color, stop.Color.A)
+			continue
+		}
+
+		// SVG interpolates colors that are not premultiplied, transparent stops take the color of their neighbours
+		if 0 < i && stops[i-1].Color.A != 0 {
+			r.writeStop(stop.Offset, stops[i-1].Color, 0)
+		}
+		if i+1 < len(stops) && stops[i+1].Color.A != 0 && (i == 0 || stops[i-1].Color.A == 0 || stops[i-1].Color != stops[i+1].Color) {
+			r.writeStop(stop.Offset, stops[i+1].Color, 0)
+		} else if (i == 0 || stops[i-1].Color.A == 0) && (i+1 == len(stops) || stops[i+1].Color.A == 0) {
+			r.writeStop(stop.Offset, stop.Color, 0)
+		}
+	}
+	if _, ok := gradient.(*canvas.LinearGradient); ok {
+		fmt.Fprintf(r.w, `</linearGradient>`)
+	} else {
+		fmt.Fprintf(r.w, `</radialGradient>`)
+	}
+	return ref
+}
+
+// writeStop writes a gradient stop with the color of the premultiplied color col and opacity a.
+func (r *SVG) writeStop(offset float64, col color.RGBA, a uint8) {
+	if col.A != 0 && col.A != 255 {
+		f := float64(col.A) / 255.0
+		col = color.RGBA{uint8(float64(col.R)/f + 0.5), uint8(float64(col.G)/f + 0.5), uint8(float64(col.B)/f + 0.5), 255}
+	}
+	col.A = 255
+	fmt.Fprintf(r.w, `<stop offset="%v" stop-color="%v`, dec(offset), canvas.CSSColor(col))
+	if a != 255 {
+		fmt.Fprintf(r.w, `" stop-opacity="%v`, dec(float64(a)/255.0))
+	}
+	fmt.Fprintf(r.w, `"/>`)
+}
+
+// PushClip restricts drawing to the area filled by the path, intersected with the current clipping area. It opens a group that is closed by PopClip.
+func (r *SVG) PushClip(path *canvas.Path, fillRule canvas.FillRule, m canvas.Matrix) {
+	refClip := fmt.Sprintf("c%v", r.clipID)
+	r.clipID++
+
+	path = path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m))
+	fmt.Fprintf(r.w, `<clipPath id="%s"><path d="%s`, refClip, path.ToSVG())
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(r.w, `" clip-rule="evenodd`)
+	}
+	fmt.Fprintf(r.w, `"/></clipPath><g clip-path="url(#%s)">`, refClip)
+	r.clips = append(r.clips, refClip)
+}
+
+// PopClip removes the last clipping path by closing its group.
+func (r *SVG) PopClip() {
+	if len(r.clips) == 0 {
+		return
+	}
+	fmt.Fprintf(r.w, "</g>")
+	r.clips = r.clips[:len(r.clips)-1]
+}
+
+// BeginLayer starts the named layer by opening a group that Inkscape recognizes as a layer, it is closed by EndLayer. Clipping groups are always nested inside the layer groups, so that a layer is never split into several groups.
+func (r *SVG) BeginLayer(name string) {
+	r.closeClips()
+	fmt.Fprintf(r.w, `<g xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" inkscape:groupmode="layer" inkscape:label="%s">`, html.EscapeString(name))
+	r.layers = append(r.layers, name)
+	r.openClips()
+}
+
+// EndLayer ends the last layer by closing its group.
+func (r *SVG) EndLayer() {
+	if len(r.layers) == 0 {
+		return
+	}
+	r.closeClips()
+	fmt.Fprintf(r.w, "</g>")
+	r.layers = r.layers[:len(r.layers)-1]
+	r.openClips()
+}
+
+// closeClips closes the groups of the open clipping paths, which are reopened by openClips.
+func (r *SVG) closeClips() {
+	for range r.clips {
+		fmt.Fprintf(r.w, "</g>")
+	}
+}
+
+func (r *SVG) openClips() {
+	for _, refClip := range r.clips {
+		fmt.Fprintf(r.w, `<g clip-path="url(#%s)">`, refClip)
+	}
+}
+
 func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 	boldness := ff.Boldness()
 	differences := 0
@@ -276,7 +491,9 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 }
 
 func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
-	if r.embedFonts {
+	if r.embedFonts && r.subsetFonts {
+		r.addGlyphs(text)
+	} else if r.embedFonts {
 		r.writeFonts(text.Fonts())
 	}
 
@@ -284,10 +501,27 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 		return
 	}
 
+	// text along a path is written as a textPath when possible, otherwise as glyph outlines
+	refPath := ""
+	start, offset := 0.0, 0.0
+	if text.OnPath() {
+		var path *canvas.Path
+		var ok bool
+		if path, start, offset, ok = text.TextPath(); !ok {
+			text.RenderAsPath(r, m)
+			return
+		}
+		refPath = fmt.Sprintf("tp%v", r.textPathID)
+		r.textPathID++
+
+		path = path.Transform(canvas.Identity.ReflectY()) // in the coordinate system of the text element
+		fmt.Fprintf(r.w, `<defs><path id="%s" d="%s"/></defs>`, refPath, path.ToSVG())
+	}
+
 	ffMain := text.MostCommonFontFace()
 
 	x0, y0 := 0.0, 0.0
-	if m.IsTranslation() {
+	if m.IsTranslation() && refPath == "" {
 		x0, y0 = m.Pos()
 		y0 = r.height - y0
 		fmt.Fprintf(r.w, `<text x="%v" y="%v`, num(x0), num(y0))
@@ -310,9 +544,28 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 	}
 	r.writeClasses(r.w)
 	fmt.Fprintf(r.w, `">`)
+	if refPath != "" {
+		fmt.Fprintf(r.w, `<textPath xlink:href="#%s">`, refPath)
+	}
 
+	first := true
 	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
-		fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+dx), num(y0-y-span.Face.Voffset))
+		if refPath != "" {
+			// positions are arc lengths along the path and the offset is perpendicular to it
+			fmt.Fprintf(r.w, `<tspan x="%v`, num(start+dx))
+			if first && offset != 0.0 {
+				fmt.Fprintf(r.w, `" dy="%v`, num(-offset))
+			}
+			first = false
+		} else if span.Direction() == canvas.RightToLeft {
+			// right-to-left text starts at the right
+			for _, glyph := range span.Glyphs() {
+				dx += glyph.XAdvance
+			}
+			fmt.Fprintf(r.w, `<tspan x="%v" y="%v" direction="rtl" unicode-bidi="bidi-override`, num(x0+dx), num(y0-y-span.Face.Voffset))
+		} else {
+			fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+dx), num(y0-y-span.Face.Voffset))
+		}
 		if span.WordSpacing > 0.0 {
 			fmt.Fprintf(r.w, `" word-spacing="%v`, num(span.WordSpacing))
 		}
@@ -325,6 +578,9 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 		r.writeClasses(r.w)
 		fmt.Fprintf(r.w, `">%s</tspan>`, s)
 	})
+	if refPath != "" {
+		fmt.Fprintf(r.w, `</textPath>`)
+	}
 	fmt.Fprintf(r.w, `</text>`)
 	text.RenderDecoration(r, m)
 }
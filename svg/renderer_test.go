@@ -1,9 +1,81 @@
 package svg
 
 import (
+	"bytes"
+	"encoding/base64"
+	"strings"
 	"testing"
+
+	"github.com/dtrenin7/canvas"
+	"github.com/dtrenin7/test"
 )
 
+func TestSVGClip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10, 10)
+	buf.Reset()
+	svg.PushClip(canvas.MustParseSVG("L10 0L10 10z"), canvas.EvenOdd, canvas.Identity)
+	svg.RenderPath(canvas.MustParseSVG("L5 0L5 5z"), canvas.DefaultStyle, canvas.Identity)
+	svg.PopClip()
+	svg.PushClip(canvas.MustParseSVG("L10 0L10 10z"), canvas.NonZero, canvas.Identity)
+	svg.Close()
+	test.String(t, buf.String(), `<clipPath id="c0"><path d="M0 10H10V0z" clip-rule="evenodd"/></clipPath><g clip-path="url(#c0)"><path d="M0 10H5V5z"/></g><clipPath id="c1"><path d="M0 10H10V0z"/></clipPath><g clip-path="url(#c1)"></g></svg>`)
+}
+
+func TestSVGLayer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10, 10)
+	buf.Reset()
+	svg.BeginLayer("Dimensions & text")
+	svg.RenderPath(canvas.MustParseSVG("L5 0L5 5z"), canvas.DefaultStyle, canvas.Identity)
+	svg.EndLayer()
+	svg.EndLayer()
+	svg.BeginLayer("Background")
+	svg.Close()
+	test.String(t, buf.String(), `<g xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" inkscape:groupmode="layer" inkscape:label="Dimensions &amp; text"><path d="M0 10H5V5z"/></g><g xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" inkscape:groupmode="layer" inkscape:label="Background"></g></svg>`)
+}
+
+func TestSVGClipLayer(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10, 10)
+	buf.Reset()
+	svg.BeginLayer("A")
+	svg.PushClip(canvas.MustParseSVG("L10 0L10 10z"), canvas.NonZero, canvas.Identity)
+	svg.EndLayer() // clip continues outside the layer
+	svg.RenderPath(canvas.MustParseSVG("L5 0L5 5z"), canvas.DefaultStyle, canvas.Identity)
+	svg.BeginLayer("B")
+	svg.PopClip() // layer continues without the clip
+	svg.RenderPath(canvas.MustParseSVG("L5 0L5 5z"), canvas.DefaultStyle, canvas.Identity)
+	svg.Close()
+	layerA := `<g xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" inkscape:groupmode="layer" inkscape:label="A">`
+	layerB := `<g xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" inkscape:groupmode="layer" inkscape:label="B">`
+	test.String(t, buf.String(), layerA+`<clipPath id="c0"><path d="M0 10H10V0z"/></clipPath><g clip-path="url(#c0)"></g></g><g clip-path="url(#c0)"><path d="M0 10H5V5z"/></g>`+layerB+`<g clip-path="url(#c0)"></g><path d="M0 10H5V5z"/></g></svg>`)
+	test.T(t, strings.Count(buf.String(), `inkscape:label="B"`), 1, "layer must not be split")
+}
+
+func TestSVGGradient(t *testing.T) {
+	linear := canvas.NewLinearGradient(canvas.Point{X: 0, Y: 0}, canvas.Point{X: 10, Y: 0})
+	linear.Add(0, canvas.Red)
+	linear.Add(1, canvas.Transparent)
+	linear.Spread = canvas.ReflectSpread
+
+	radial := canvas.NewRadialGradient(canvas.Point{X: 1, Y: 1}, 0, canvas.Point{X: 2, Y: 2}, 3)
+	radial.Add(0, canvas.Black)
+	radial.Add(1, canvas.White)
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10, 10)
+	buf.Reset()
+	style := canvas.DefaultStyle
+	style.Fill.Gradient = linear
+	svg.RenderPath(canvas.Rectangle(5, 5), style, canvas.Identity)
+
+	style.Fill = canvas.Paint{}
+	style.Stroke = canvas.Paint{Color: canvas.Red, Gradient: radial}
+	svg.RenderPath(canvas.Rectangle(5, 5), style, canvas.Identity.Translate(1, 0))
+	test.String(t, buf.String(), `<linearGradient id="g0" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="10" y2="0" spreadMethod="reflect" gradientTransform="matrix(1,0,0,-1,0,10)"><stop offset="0" stop-color="#f00"/><stop offset="1" stop-color="#f00" stop-opacity="0"/></linearGradient><path d="M0 10H5V5H0z" fill="url(#g0)"/><radialGradient id="g1" gradientUnits="userSpaceOnUse" cx="2" cy="2" r="3" fx="1" fy="1" gradientTransform="matrix(1,0,0,-1,1,10)"><stop offset="0" stop-color="#000"/><stop offset="1" stop-color="#fff"/></radialGradient><path d="M1 10H6V5H1z" style="fill:none;stroke:url(#g1)"/>`)
+}
+
 func TestSVGText(t *testing.T) {
 	//dejaVuSerif := NewFontFamily("dejavu-serif")
 	//dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -30,3 +102,80 @@ func TestSVGText(t *testing.T) {
 	//s := regexp.MustCompile(`base64,.+'`).ReplaceAllString(buf.String(), "base64,'") // remove embedded font
 	//test.String(t, s, `<style>`+"\n"+`@font-face{font-family:'dejavu-serif';src:url('data:font/truetype;base64,');}`+"\n"+`@font-face{font-family:'eb-garamond';src:url('data:font/opentype;base64,');}`+"\n"+`</style><text x="0" y="0" style="font: 12px dejavu-serif"><tspan x="0" y="7.421875" style="font:8px dejavu-serif">dejaVu8</tspan><tspan x="0" y="20.453125" letter-spacing="1" style="font-style:italic;fill:#f00">glyphspacing</tspan><tspan x="0" y="33.725625" style="font:700 6.996px dejavu-serif">dejaVu12sub</tspan><tspan x="0" y="38.5" style="font:700 10px eb-garamond">garamond10</tspan></text><path d="M0 22.703125H91.71875V21.803125H0z" fill="#f00"/>`)
 }
+
+func TestSVGFontSubset(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		test.Error(t, err)
+	}
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	text := canvas.NewTextLine(face, "AV", canvas.Left)
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, 100, 80)
+	svg.RenderText(text, canvas.Identity)
+	test.That(t, !strings.Contains(buf.String(), "<style>"), "fonts are written on close")
+	svg.Close()
+	s := buf.String()
+	test.That(t, strings.HasSuffix(s, "');}\n</style></svg>"))
+	prefix := "@font-face{font-family:'dejavu-serif';src:url('data:font/truetype;base64,"
+	test.That(t, strings.Contains(s, prefix))
+
+	// the viewer shapes the text, so the layout tables must be kept
+	data := s[strings.Index(s, prefix)+len(prefix):]
+	data = data[:strings.Index(data, "'")]
+	font, err := base64.StdEncoding.DecodeString(data)
+	test.Error(t, err)
+	test.That(t, bytes.Contains(font, []byte("GPOS")), "GPOS table must be kept")
+	test.That(t, bytes.Contains(font, []byte("GSUB")), "GSUB table must be kept")
+
+	buf.Reset()
+	svg = New(buf, 100, 80)
+	svg.SetFontSubsetting(false)
+	svg.RenderText(text, canvas.Identity)
+	svg.Close()
+	test.That(t, 100000 < buf.Len(), "font must be embedded entirely")
+	test.That(t, len(s) < buf.Len()/4, "font must be subset")
+}
+
+func TestSVGTextPath(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		test.Error(t, err)
+	}
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	text := canvas.NewTextLine(face, "AV", canvas.Left)
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, 100, 80)
+	svg.RenderText(canvas.NewTextOnPath(text, canvas.MustParseSVG("M0 0L100 0"), canvas.Left, 2.0, canvas.OverflowHidden), canvas.Identity)
+	test.That(t, strings.Contains(buf.String(), `<defs><path id="tp0" d="M0 0H100"/></defs>`))
+	test.That(t, strings.Contains(buf.String(), `<textPath xlink:href="#tp0"><tspan x="0" dy="-2">AV</tspan></textPath></text>`))
+
+	// text that is scaled along the path is drawn as glyph outlines
+	buf.Reset()
+	svg = New(buf, 100, 80)
+	svg.RenderText(canvas.NewTextOnPath(text, canvas.MustParseSVG("M0 0L1 0"), canvas.Left, 0.0, canvas.OverflowShrink), canvas.Identity)
+	test.That(t, !strings.Contains(buf.String(), "<text"))
+	test.That(t, strings.Contains(buf.String(), "<path d="))
+
+	// text that wraps around a closed path is drawn as glyph outlines, since a text path hides it
+	buf.Reset()
+	svg = New(buf, 100, 80)
+	long := canvas.NewTextLine(face, "a long badge text around", canvas.Left)
+	svg.RenderText(canvas.NewTextOnPath(long, canvas.Circle(5.0), canvas.Right, 0.0, canvas.OverflowHidden), canvas.Identity)
+	test.That(t, !strings.Contains(buf.String(), "<text"))
+	test.That(t, strings.Contains(buf.String(), "<path d="))
+}
+
+func TestSVGStrokeProfile(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10, 10)
+	buf.Reset()
+	style := canvas.DefaultStyle
+	style.Fill = canvas.Paint{}
+	style.Stroke = canvas.Paint{Color: canvas.Red}
+	style.StrokeProfile = canvas.WidthStops(canvas.WidthStop{Offset: 0.0, Width: 2.0}, canvas.WidthStop{Offset: 1.0, Width: 0.0})
+	svg.RenderPath(canvas.MustParseSVG("M0 5L10 5"), style, canvas.Identity)
+	test.String(t, buf.String(), `<path d="M0 5H10" style="fill:none"/><path d="M0 4L10 5L0 6z" fill="#f00"/>`)
+}
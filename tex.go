@@ -56,6 +56,26 @@ func (r *TeX) RenderPath(path *Path, style Style, m Matrix) {
 	if path.Empty() {
 		return
 	}
+	if style.StrokeProfile != nil && style.Stroke.Color.A != 0 {
+		// variable stroke widths are not supported, fill the stroke outline instead
+		outline := path.Transform(m)
+		if 0 < len(style.Dashes) {
+			outline = outline.Dash(style.DashOffset, style.Dashes...)
+		}
+		outline = style.StrokeOutline(outline)
+
+		style.StrokeProfile = nil
+		strokeStyle := style
+		strokeStyle.Fill = style.Stroke
+		strokeStyle.Stroke = Paint{}
+		strokeStyle.FillRule = NonZero
+		if style.Fill.Color.A != 0 {
+			style.Stroke = Paint{}
+			r.RenderPath(path, style, m)
+		}
+		r.RenderPath(outline, strokeStyle, Identity)
+		return
+	}
 	path = path.Transform(m)
 	path = path.ReplaceArcs()
 
@@ -81,15 +101,15 @@ func (r *TeX) RenderPath(path *Path, style Style, m Matrix) {
 		fmt.Fprintf(r.w, "\n\\pgfpathclose")
 	})
 
-	fill := style.FillColor.A != 0
-	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+	fill := style.Fill.Color.A != 0
+	stroke := style.Stroke.Color.A != 0 && 0.0 < style.StrokeWidth
 
 	if fill {
-		if style.FillColor.R != r.style.FillColor.R || style.FillColor.G != r.style.FillColor.G || style.FillColor.B != r.style.FillColor.B {
-			fmt.Fprintf(r.w, "\n\\pgfsetfillcolor{%v}", r.getColor(style.FillColor))
+		if style.Fill.Color.R != r.style.Fill.Color.R || style.Fill.Color.G != r.style.Fill.Color.G || style.Fill.Color.B != r.style.Fill.Color.B {
+			fmt.Fprintf(r.w, "\n\\pgfsetfillcolor{%v}", r.getColor(style.Fill.Color))
 		}
-		if style.FillColor.A != r.style.FillColor.A {
-			fmt.Fprintf(r.w, "\n\\pgfsetfillopacity{%v}", dec(float64(style.FillColor.A)/255.0))
+		if style.Fill.Color.A != r.style.Fill.Color.A {
+			fmt.Fprintf(r.w, "\n\\pgfsetfillopacity{%v}", dec(float64(style.Fill.Color.A)/255.0))
 		}
 	}
 
@@ -135,11 +155,11 @@ func (r *TeX) RenderPath(path *Path, style Style, m Matrix) {
 			fmt.Fprintf(r.w, "\n\\pgfsetlinewidth{%vmm}", dec(style.StrokeWidth))
 		}
 
-		if style.StrokeColor.R != r.style.StrokeColor.R || style.StrokeColor.G != r.style.StrokeColor.G || style.StrokeColor.B != r.style.StrokeColor.B {
-			fmt.Fprintf(r.w, "\n\\pgfsetstrokecolor{%v}", r.getColor(style.StrokeColor))
+		if style.Stroke.Color.R != r.style.Stroke.Color.R || style.Stroke.Color.G != r.style.Stroke.Color.G || style.Stroke.Color.B != r.style.Stroke.Color.B {
+			fmt.Fprintf(r.w, "\n\\pgfsetstrokecolor{%v}", r.getColor(style.Stroke.Color))
 		}
-		if style.StrokeColor.A != r.style.StrokeColor.A {
-			fmt.Fprintf(r.w, "\n\\pgfsetstrokeopacity{%v}", dec(float64(style.StrokeColor.A)/255.0))
+		if style.Stroke.Color.A != r.style.Stroke.Color.A {
+			fmt.Fprintf(r.w, "\n\\pgfsetstrokeopacity{%v}", dec(float64(style.Stroke.Color.A)/255.0))
 		}
 	}
 	if fill && stroke {
@@ -157,7 +177,7 @@ func (r *TeX) RenderText(text *Text, m Matrix) {
 	paths, colors := text.ToPaths()
 	for i, path := range paths {
 		style := DefaultStyle
-		style.FillColor = colors[i]
+		style.Fill.Color = colors[i]
 		r.RenderPath(path, style, m)
 	}
 }
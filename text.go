@@ -17,7 +17,7 @@ const MaxWordSpacing = 2.5
 // MaxGlyphSpacing is the maximum amount times the x-height of the font that glyphs can be spaced.
 const MaxGlyphSpacing = 0.5
 
-// TextAlign specifies how the text should align or whether it should be justified.
+// TextAlign specifies how the text should align or whether it should be justified. Left and Right are mirrored for right-to-left paragraphs in RichText.
 type TextAlign int
 
 // see TextAlign
@@ -34,6 +34,7 @@ type line struct {
 	spans []TextSpan
 	decos []decoSpan
 	y     float64
+	rtl   bool // paragraph is right-to-left
 }
 
 func (l line) Heights() (float64, float64, float64, float64) {
@@ -54,6 +55,7 @@ func (l line) Heights() (float64, float64, float64, float64) {
 type Text struct {
 	lines []line
 	fonts map[*Font]bool
+	path  *textPath
 }
 
 // NewTextLine is a simple text line using a font face, a string (supporting new lines) and horizontal alignment (Left, Center, Right).
@@ -63,21 +65,44 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 	i := 0
 	y := 0.0
 	lines := []line{}
+	fonts := map[*Font]bool{ff.Font: true}
+	levels, _ := bidiLevels(s, AutoDirection)
 	for _, boundary := range calcTextBoundaries(s, 0, len(s)) {
 		if boundary.kind == lineBoundary || boundary.kind == eofBoundary {
 			j := boundary.pos + boundary.size
 			if i < j {
 				l := line{y: y}
-				span := newTextSpan(ff, s[:j], i)
+				width := 0.0
+				positions := []int{}
+				faces, starts := ff.faceRuns(s[i:j])
+				for k, face := range faces {
+					end := j
+					if k+1 < len(starts) {
+						end = i + starts[k+1]
+					}
+					span := newTextSpan(face, s[:end], i+starts[k])
+					span.dx = width
+					l.spans = append(l.spans, span)
+					positions = append(positions, i+starts[k])
+					fonts[face.Font] = true
+					width += span.width
+				}
+				if levels != nil {
+					l.spans = bidiReorder(l.spans, positions, levels)
+				}
+
+				dx := 0.0
 				if halign == Center {
-					span.dx = -span.width / 2.0
+					dx = -width / 2.0
 				} else if halign == Right {
-					span.dx = -span.width
+					dx = -width
+				}
+				for k := range l.spans {
+					l.spans[k].dx += dx
 				}
 
-				l.spans = append(l.spans, span)
 				if len(ff.deco) != 0 {
-					l.decos = append(l.decos, decoSpan{ff, span.dx, span.dx + span.width})
+					l.decos = append(l.decos, decoSpan{ff, dx, dx + width})
 				}
 				lines = append(lines, l)
 			}
@@ -85,7 +110,7 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 			i = j
 		}
 	}
-	return &Text{lines, map[*Font]bool{ff.Font: true}}
+	return &Text{lines, fonts, nil}
 }
 
 // NewTextBox is an advanced text formatter that will calculate text placement based on the setteings. It takes a font face, a string, the width or height of the box (can be zero for no limit), horizontal and vertical alignment (Left, Center, Right, Top, Bottom or Justify), text indentation for the first line and line stretch (percentage to stretch the line based on the line height).
@@ -95,9 +120,12 @@ func NewTextBox(ff FontFace, s string, width, height float64, halign, valign Tex
 
 // RichText allows to build up a rich text with text spans of different font faces and by fitting that into a box.
 type RichText struct {
-	spans []TextSpan
-	fonts map[*Font]bool
-	text  string
+	spans      []TextSpan
+	fonts      map[*Font]bool
+	text       string
+	direction  Direction
+	hyphenator *Hyphenator
+	breaker    *KnuthPlass
 }
 
 // NewRichText returns a new RichText.
@@ -107,7 +135,25 @@ func NewRichText() *RichText {
 	}
 }
 
-// Add adds a new text span element.
+// SetDirection sets the base direction of the paragraphs, by default it is determined by the first strong character of each paragraph. Right-to-left and bidirectional text is reordered using the Unicode Bidirectional Algorithm.
+func (rt *RichText) SetDirection(direction Direction) *RichText {
+	rt.direction = direction
+	return rt
+}
+
+// SetHyphenator sets the hyphenator that adds hyphenation points to the words when text is broken into lines, by default words are not hyphenated.
+func (rt *RichText) SetHyphenator(hyphenator *Hyphenator) *RichText {
+	rt.hyphenator = hyphenator
+	return rt
+}
+
+// SetLineBreaker sets the Knuth-Plass line breaker to choose the line breaks of each paragraph as a whole, by default each line is filled greedily. This gives better results for justified text.
+func (rt *RichText) SetLineBreaker(breaker *KnuthPlass) *RichText {
+	rt.breaker = breaker
+	return rt
+}
+
+// Add adds a new text span element. Characters missing in the font are added in the font faces of the fallback font families.
 func (rt *RichText) Add(ff FontFace, s string) *RichText {
 	if 0 < len(s) {
 		rPrev := ' '
@@ -120,6 +166,18 @@ func (rt *RichText) Add(ff FontFace, s string) *RichText {
 		}
 	}
 
+	faces, starts := ff.faceRuns(s)
+	for k, face := range faces {
+		end := len(s)
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		rt.add(face, s[starts[k]:end])
+	}
+	return rt
+}
+
+func (rt *RichText) add(ff FontFace, s string) {
 	start := len(rt.text)
 	rt.text += s
 
@@ -153,28 +211,35 @@ func (rt *RichText) Add(ff FontFace, s string) *RichText {
 		}
 	}
 	rt.fonts[ff.Font] = true
-	return rt
 }
 
 func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign TextAlign) {
-	if halign == Right || halign == Center {
-		for _, l := range lines {
+	n := len(lines) - 1 // number of lines to justify
+	if yoverflow {
+		n++
+	}
+	for j, l := range lines {
+		align := halign
+		if l.rtl {
+			// right-to-left paragraphs start at the right
+			if align == Left || align == Justify && n <= j {
+				align = Right
+			} else if align == Right {
+				align = Left
+			}
+		}
+
+		if align == Right || align == Center {
 			firstSpan := l.spans[0]
 			lastSpan := l.spans[len(l.spans)-1]
 			dx := width - lastSpan.dx - lastSpan.width - firstSpan.dx
-			if halign == Center {
+			if align == Center {
 				dx /= 2.0
 			}
 			for i := range l.spans {
 				l.spans[i].dx += dx
 			}
-		}
-	} else if 0.0 < width && halign == Justify {
-		n := len(lines) - 1
-		if yoverflow {
-			n++
-		}
-		for _, l := range lines[:n] {
+		} else if 0.0 < width && align == Justify && j < n {
 			// get the width range of our spans (eg. for text width can increase with extra character spacing)
 			textWidth, maxSentenceSpacing, maxWordSpacing, maxGlyphSpacing := 0.0, 0.0, 0.0, 0.0
 			for i, span := range l.spans {
@@ -304,35 +369,76 @@ func (rt *RichText) decorate(lines []line) {
 // ToText takes the added text spans and fits them within a given box of certain width and height.
 func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, indent, lineStretch float64) *Text {
 	if len(rt.spans) == 0 {
-		return &Text{[]line{}, rt.fonts}
+		return &Text{[]line{}, rt.fonts, nil}
+	}
+
+	rtSpans := rt.spans
+	if rt.hyphenator != nil {
+		rtSpans = make([]TextSpan, len(rt.spans))
+		for k, span := range rt.spans {
+			rtSpans[k] = span.hyphenate(rt.hyphenator)
+		}
+	}
+	spans := []TextSpan{rtSpans[0]}
+
+	// byte offsets of the optimal line breaks
+	var breaks []int
+	if rt.breaker != nil && width != 0.0 {
+		breaks = rt.breaker.lineBreaks(rtSpans, width, indent)
+	}
+
+	// the byte offsets of the spans into the text are used to look up the bidirectional embedding levels
+	levels, paragraphLevels := bidiLevels(rt.text, rt.direction)
+	starts := make([]int, len(rtSpans))
+	for k := 1; k < len(rtSpans); k++ {
+		starts[k] = starts[k-1] + len(rtSpans[k-1].Text)
 	}
-	spans := []TextSpan{rt.spans[0]}
+	pos := 0 // byte offset of spans[0]
 
-	k := 0 // index into rt.spans and rt.positions
+	k := 0 // index into rtSpans
 	lines := []line{}
 	yoverflow := false
 	y, prevLineSpacing := 0.0, 0.0
-	for k < len(rt.spans) {
+	for k < len(rtSpans) {
 		dx := indent
 		indent = 0.0
 
 		// trim left spaces
+		n := len(spans[0].Text)
 		spans[0] = spans[0].TrimLeft()
+		pos += n - len(spans[0].Text)
 		for spans[0].Text == "" {
 			// TODO: reachable?
-			if k+1 == len(rt.spans) {
+			if k+1 == len(rtSpans) {
 				break
 			}
 			k++
-			spans = []TextSpan{rt.spans[k]}
+			spans = []TextSpan{rtSpans[k]}
 			spans[0] = spans[0].TrimLeft()
+			pos = starts[k] + len(rtSpans[k].Text) - len(spans[0].Text)
 		}
 
 		// accumulate line spans for a full line, ie. either split span1 to fit or if it fits retrieve the next span1 and repeat
 		ss := []TextSpan{}
+		positions := []int{}
 		for {
-			// space or inter-word splitting
-			if width != 0.0 && len(spans) == 1 {
+			// split at the optimal line break
+			endOfLine := false
+			if breaks != nil && len(spans) == 1 && 0 < len(breaks) && breaks[0] < pos+len(spans[0].Text) {
+				for i, boundary := range spans[0].boundaries {
+					if pos+boundary.pos == breaks[0] {
+						span0, span1 := spans[0].split(i)
+						spans = []TextSpan{span0, span1}
+						if span1.Text == "" {
+							spans = spans[:1]
+							endOfLine = true
+						}
+						break
+					}
+				}
+				breaks = breaks[1:]
+			} else if breaks == nil && width != 0.0 && len(spans) == 1 {
+				// space or inter-word splitting
 				// there is a width limit and we have only one (unsplit) span to process
 				var ok bool
 				spans, ok = spans[0].Split(width - dx)
@@ -350,19 +456,22 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 
 			spans[0].dx = dx
 			ss = append(ss, spans[0])
+			positions = append(positions, pos)
 			dx += spans[0].width
 
 			spans = spans[1:]
 			if len(spans) == 0 {
 				k++
-				if k == len(rt.spans) {
+				if k == len(rtSpans) {
 					break
 				}
-				spans = []TextSpan{rt.spans[k]}
+				spans = []TextSpan{rtSpans[k]}
+				pos = starts[k]
 			} else {
+				pos = starts[k] + len(rtSpans[k].Text) - len(spans[0].Text)
 				break // span couldn't fully fit, we have a full line
 			}
-			if newline {
+			if newline || endOfLine {
 				break
 			}
 		}
@@ -372,12 +481,24 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 			ss[len(ss)-1] = ss[len(ss)-1].TrimRight()
 			if 1 < len(ss) && ss[len(ss)-1].Text == "" {
 				ss = ss[:len(ss)-1]
+				positions = positions[:len(positions)-1]
 			} else {
 				break
 			}
 		}
 
-		l := line{ss, []decoSpan{}, 0.0}
+		// reorder bidirectional text
+		rtl := false
+		if levels != nil {
+			ss = bidiReorder(ss, positions, levels)
+			if positions[0] < len(paragraphLevels) {
+				rtl = paragraphLevels[positions[0]] == 1
+			} else {
+				rtl = paragraphLevels[len(paragraphLevels)-1] == 1
+			}
+		}
+
+		l := line{ss, []decoSpan{}, 0.0, rtl}
 		top, ascent, descent, bottom := l.Heights()
 		lineSpacing := math.Max(top-ascent, prevLineSpacing)
 		if len(lines) != 0 {
@@ -397,7 +518,7 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 	}
 
 	if len(lines) == 0 {
-		return &Text{lines, rt.fonts}
+		return &Text{lines, rt.fonts, nil}
 	}
 
 	// apply horizontal alignment
@@ -409,7 +530,7 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 	// set decorations
 	rt.decorate(lines)
 
-	return &Text{lines, rt.fonts}
+	return &Text{lines, rt.fonts, nil}
 }
 
 // Empty is true if there are no text lines or no text spans.
@@ -436,6 +557,8 @@ func (t *Text) Height() float64 {
 func (t *Text) Bounds() Rect {
 	if len(t.lines) == 0 || len(t.lines[0].spans) == 0 {
 		return Rect{}
+	} else if t.path != nil {
+		return t.OutlineBounds()
 	}
 	r := Rect{}
 	for _, line := range t.lines {
@@ -452,6 +575,15 @@ func (t *Text) OutlineBounds() Rect {
 		return Rect{}
 	}
 	r := Rect{}
+	if t.path != nil {
+		paths, _ := t.ToPaths()
+		for _, p := range paths {
+			if !p.Empty() {
+				r = r.Add(p.Bounds())
+			}
+		}
+		return r
+	}
 	for _, line := range t.lines {
 		for _, span := range line.spans {
 			spanBounds := span.Bounds(span.width)
@@ -530,6 +662,9 @@ func (t *Text) MostCommonFontFace() FontFace {
 
 // ToPaths makes a path out of the text, with x,y the top-left point of the rectangle that fits the text (ie. y is not the text base)
 func (t *Text) ToPaths() ([]*Path, []color.RGBA) {
+	if t.path != nil {
+		return t.path.toPaths(t)
+	}
 	paths := []*Path{}
 	colors := []color.RGBA{}
 	for _, line := range t.lines {
@@ -554,11 +689,22 @@ func (t *Text) ToPaths() ([]*Path, []color.RGBA) {
 // TODO: check compliance with https://drafts.csswg.org/css-text-decor-4/#text-line-constancy
 func (t *Text) RenderDecoration(r Renderer, m Matrix) {
 	style := DefaultStyle
+	if t.path != nil {
+		layout := t.path.layout(t)
+		for _, line := range t.lines {
+			for _, deco := range line.decos {
+				p := deco.face.Decorate(deco.x1-deco.x0).Translate(deco.x0, line.y)
+				style.Fill.Color = deco.face.Color
+				r.RenderPath(layout.warp(p, t.path), style, m)
+			}
+		}
+		return
+	}
 	for _, line := range t.lines {
 		for _, deco := range line.decos {
 			p := deco.face.Decorate(deco.x1 - deco.x0)
 			p = p.Transform(Identity.Mul(m).Translate(deco.x0, line.y+deco.face.Voffset))
-			style.FillColor = deco.face.Color
+			style.Fill.Color = deco.face.Color
 			r.RenderPath(p, style, Identity)
 		}
 	}
@@ -586,6 +732,7 @@ type TextSpan struct {
 	boundaries []textBoundary
 
 	dx              float64
+	level           int8 // bidirectional embedding level, odd levels are right-to-left
 	SentenceSpacing float64
 	WordSpacing     float64
 	GlyphSpacing    float64
@@ -628,7 +775,7 @@ func (span TextSpan) Bounds(width float64) Rect {
 
 func (span TextSpan) split(i int) (TextSpan, TextSpan) {
 	dash := ""
-	if span.boundaries[i].kind == breakBoundary {
+	if span.boundaries[i].kind == breakBoundary || span.boundaries[i].kind == hyphenBoundary {
 		dash = "-"
 	}
 
@@ -652,25 +799,58 @@ func (span TextSpan) split(i int) (TextSpan, TextSpan) {
 	return span0, span1
 }
 
+// cut splits the span at a byte position without removing characters.
+func (span TextSpan) cut(pos int) (TextSpan, TextSpan) {
+	span0, span1 := span, span
+	span0.Text = span.Text[:pos]
+	span0.width = span.Face.TextWidth(span0.Text)
+	span0.boundaries = []textBoundary{}
+	span1.Text = span.Text[pos:]
+	span1.width = span.Face.TextWidth(span1.Text)
+	span1.boundaries = []textBoundary{}
+	for _, boundary := range span.boundaries[:len(span.boundaries)-1] {
+		if boundary.pos < pos {
+			if pos < boundary.pos+boundary.size {
+				span1.boundaries = append(span1.boundaries, textBoundary{boundary.kind, 0, boundary.pos + boundary.size - pos})
+				boundary.size = pos - boundary.pos
+			}
+			span0.boundaries = append(span0.boundaries, boundary)
+		} else {
+			span1.boundaries = append(span1.boundaries, textBoundary{boundary.kind, boundary.pos - pos, boundary.size})
+		}
+	}
+	span0.boundaries = append(span0.boundaries, textBoundary{eofBoundary, len(span0.Text), 0})
+	span1.boundaries = append(span1.boundaries, textBoundary{eofBoundary, len(span1.Text), 0})
+	return span0, span1
+}
+
 func (span TextSpan) Split(width float64) ([]TextSpan, bool) {
 	if width == 0.0 || span.width <= width {
 		return []TextSpan{span}, true // span fits
 	}
-	for i := len(span.boundaries) - 2; i >= 0; i-- {
-		if span.boundaries[i].pos == 0 {
-			return []TextSpan{span}, false // boundary is at the beginning, do not split
-		}
 
-		span0, span1 := span.split(i)
-		if span0.width <= width {
-			// span fits up to this boundary
-			if span1.width == 0.0 {
-				return []TextSpan{span0}, true // there is no text between the last two boundaries (e.g. space followed by end)
-			}
-			return []TextSpan{span0, span1}, true
+	// find the last boundary up to which the span fits, the width increases with the boundary position
+	lo, hi := 0, len(span.boundaries)-1 // the last one is EOF
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if span0, _ := span.split(mid); span0.width <= width {
+			lo = mid + 1
+		} else {
+			hi = mid
 		}
 	}
-	return []TextSpan{span}, false // does not fit, but there are no boundaries to split
+	if lo == 0 {
+		return []TextSpan{span}, false // does not fit, but there are no boundaries to split
+	} else if span.boundaries[lo-1].pos == 0 {
+		return []TextSpan{span}, false // boundary is at the beginning, do not split
+	}
+
+	// span fits up to this boundary
+	span0, span1 := span.split(lo - 1)
+	if span1.width == 0.0 {
+		return []TextSpan{span0}, true // there is no text between the last two boundaries (e.g. space followed by end)
+	}
+	return []TextSpan{span0, span1}, true
 }
 
 // CountGlyphs counts all the glyphs, where ligatures are separated into their constituent parts
@@ -688,50 +868,117 @@ func (span TextSpan) CountGlyphs() int {
 
 // ReplaceLigatures replaces all ligatures by their constituent parts
 func (span TextSpan) ReplaceLigatures() TextSpan {
+	text := ""
 	shift := 0
+	boundaries := append([]textBoundary{}, span.boundaries...)
 	iBoundary := 0
 	for i, r := range span.Text {
-		if span.boundaries[iBoundary].pos == i {
-			span.boundaries[iBoundary].pos += shift
+		for iBoundary < len(boundaries) && boundaries[iBoundary].pos == i {
+			boundaries[iBoundary].pos += shift
 			iBoundary++
-		} else if s, ok := ligatures[r]; ok {
-			span.Text = span.Text[:i] + s + span.Text[i+utf8.RuneLen(r):]
-			shift += len(s) - 1
+		}
+		if s, ok := ligatures[r]; ok {
+			text += s
+			shift += len(s) - utf8.RuneLen(r)
+		} else {
+			text += string(r)
 		}
 	}
-	span.boundaries[len(span.boundaries)-1].pos = len(span.Text)
+	for ; iBoundary < len(boundaries); iBoundary++ {
+		boundaries[iBoundary].pos += shift
+	}
+	span.Text = text
+	span.boundaries = boundaries
 	span.width = span.Face.TextWidth(span.Text)
 	return span
 }
 
-// TODO: transform to Draw to canvas and cache the glyph rasterizations?
-// TODO: remove width argument and use span.width?
-func (span TextSpan) ToPath(width float64) (*Path, *Path, color.RGBA) {
+// hyphenate adds the hyphenation points of the words in the span as boundaries.
+func (span TextSpan) hyphenate(h *Hyphenator) TextSpan {
+	boundaries := []textBoundary{}
 	iBoundary := 0
+	addHyphens := func(start, end int) {
+		for _, pos := range h.Hyphenate(span.Text[start:end]) {
+			pos += start
+			for iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos < pos {
+				boundaries = append(boundaries, span.boundaries[iBoundary])
+				iBoundary++
+			}
+			if iBoundary == len(span.boundaries) || pos < span.boundaries[iBoundary].pos {
+				boundaries = append(boundaries, textBoundary{hyphenBoundary, pos, 0})
+			}
+		}
+	}
 
-	x := 0.0
-	p := &Path{}
-	var rPrev rune
+	start := -1 // start of the current word
 	for i, r := range span.Text {
-		if i > 0 {
-			x += span.Face.Kerning(rPrev, r)
+		if unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) {
+			if start == -1 {
+				start = i
+			}
+		} else if start != -1 {
+			addHyphens(start, i)
+			start = -1
 		}
+	}
+	if start != -1 {
+		addHyphens(start, len(span.Text))
+	}
+	span.boundaries = append(boundaries, span.boundaries[iBoundary:]...)
+	return span
+}
+
+// Direction returns the direction of the span, which is RightToLeft for right-to-left text in bidirectional text.
+func (span TextSpan) Direction() Direction {
+	if span.level%2 == 1 {
+		return RightToLeft
+	}
+	return LeftToRight
+}
 
-		pr, advance := span.Face.ToPath(string(r))
-		pr = pr.Translate(x, 0.0)
-		p = p.Append(pr)
+// Glyphs returns the shaped glyphs of the span in visual order, the advances include the sentence, word and glyph spacing.
+func (span TextSpan) Glyphs() []Glyph {
+	rtl := span.level%2 == 1
+	glyphs := span.Face.glyphs(span.Text, rtl)
+	for i := range glyphs {
+		glyphs[i].XAdvance += span.GlyphSpacing
+	}
+	if span.SentenceSpacing == 0.0 && span.WordSpacing == 0.0 {
+		return glyphs
+	}
 
-		x += advance + span.GlyphSpacing
-		if iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos == i {
-			boundary := span.boundaries[iBoundary]
-			if boundary.kind == sentenceBoundary {
-				x += span.SentenceSpacing
-			} else if boundary.kind == wordBoundary {
-				x += span.WordSpacing
+	// add spacing to the glyph of the boundary's characters
+	for _, boundary := range span.boundaries {
+		spacing := 0.0
+		if boundary.kind == sentenceBoundary {
+			spacing = span.SentenceSpacing
+		} else if boundary.kind == wordBoundary {
+			spacing = span.WordSpacing
+		} else {
+			continue
+		}
+
+		j := -1
+		for i, glyph := range glyphs {
+			if glyph.Cluster <= boundary.pos && (j == -1 || glyphs[j].Cluster < glyph.Cluster || glyphs[j].Cluster == glyph.Cluster && !rtl) {
+				j = i
 			}
-			iBoundary++
 		}
-		rPrev = r
+		if j != -1 {
+			glyphs[j].XAdvance += spacing
+		}
+	}
+	return glyphs
+}
+
+// TODO: transform to Draw to canvas and cache the glyph rasterizations?
+// TODO: remove width argument and use span.width?
+func (span TextSpan) ToPath(width float64) (*Path, *Path, color.RGBA) {
+	x := 0.0
+	p := &Path{}
+	for _, glyph := range span.Glyphs() {
+		p = p.Append(span.Face.GlyphPath(glyph.ID).Translate(x+glyph.XOffset, glyph.YOffset))
+		x += glyph.XAdvance
 	}
 	return p, span.Face.Decorate(width), span.Face.Color
 }
@@ -763,7 +1010,9 @@ const (
 	lineBoundary
 	sentenceBoundary
 	wordBoundary
-	breakBoundary // zero-width space indicates word boundary
+	breakBoundary       // zero-width space indicates word boundary
+	opportunityBoundary // line break opportunity between characters, see UAX #14
+	hyphenBoundary      // hyphenation point, a hyphen is added when broken
 )
 
 type textBoundary struct {
@@ -797,8 +1046,13 @@ func calcTextBoundaries(s string, a, b int) []textBoundary {
 			rPrevPrev, _ = utf8.DecodeLastRuneInString(s[:a-size])
 		}
 	}
+	breaks := lineBreaks(s[a:b])
 	for i, r := range s[a:b] {
 		size := utf8.RuneLen(r)
+		if 0 < len(breaks) && breaks[0] == i {
+			boundaries = mergeBoundaries(boundaries, []textBoundary{{opportunityBoundary, i, 0}})
+			breaks = breaks[1:]
+		}
 		if isNewline(r) {
 			if r == '\n' && 0 < i && s[i-1] == '\r' {
 				boundaries[len(boundaries)-1].size++
@@ -144,6 +144,92 @@ func TestRichText(t *testing.T) {
 	test.T(t, len(text.lines), 1)
 }
 
+func TestRichTextBidi(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	rt := NewRichText()
+	rt.Add(face, "אבג (x) דה")
+	text := rt.ToText(100.0, 50.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 1)
+	test.That(t, text.lines[0].rtl, "paragraph must be right-to-left")
+	spans := text.lines[0].spans
+	test.T(t, len(spans), 3)
+	test.T(t, spans[0].Text, ") דה")
+	test.T(t, spans[1].Text, "x")
+	test.T(t, spans[2].Text, "אבג (")
+	test.T(t, spans[0].Direction(), RightToLeft)
+	test.T(t, spans[1].Direction(), LeftToRight)
+	test.Float(t, spans[1].dx, spans[0].dx+spans[0].width)
+	test.Float(t, spans[2].dx+spans[2].width, 100.0) // aligned at the start of the paragraph
+	glyphs := spans[0].Glyphs()
+	test.T(t, glyphs[len(glyphs)-1].ID, face.Font.IndicesOf("(")[0]) // mirrored
+
+	text = rt.SetDirection(LeftToRight).ToText(100.0, 50.0, Left, Top, 0.0, 0.0)
+	test.That(t, !text.lines[0].rtl, "paragraph must be left-to-right")
+	test.T(t, text.lines[0].spans[0].Text, "אבג")
+	test.Float(t, text.lines[0].spans[0].dx, 0.0)
+
+	text = NewTextLine(face, "mm אבג mm", Left)
+	test.T(t, len(text.lines[0].spans), 3)
+	test.T(t, text.lines[0].spans[1].Direction(), RightToLeft)
+}
+
+// lineTexts returns the text of each line
+func lineTexts(text *Text) []string {
+	s := []string{}
+	for _, line := range text.lines {
+		lineText := ""
+		for _, span := range line.spans {
+			lineText += span.Text
+		}
+		s = append(s, lineText)
+	}
+	return s
+}
+
+func TestRichTextLineBreak(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewRichText().Add(face, "see http://example.com/path/to/file").ToText(100.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"see http://", "example.com/", "path/to/file"})
+
+	rt := NewRichText().Add(face, "the hyphenation notation")
+	text = rt.ToText(80.0, 0.0, Justify, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"the", "hyphenation", "notation"})
+
+	text = rt.SetHyphenator(NewHyphenator(testPatterns, nil)).ToText(80.0, 0.0, Justify, Top, 0.0, 0.0)
+	test.T(t, lineTexts(text), []string{"the hyphen-", "ation nota-", "tion"})
+	test.Float(t, text.lines[0].spans[0].width, 80.0)
+	test.Float(t, text.lines[1].spans[0].width, 80.0)
+}
+
+func TestRichTextFallback(t *testing.T) {
+	fallback := NewFontFamily("eb-garamond")
+	fallback.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular)
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	family.SetFallbacks(fallback)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewRichText().Add(face, "5 \u2113 water").ToText(100.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 1)
+	test.T(t, len(text.lines[0].spans), 3)
+	test.T(t, text.lines[0].spans[1].Text, "\u2113 ")
+	test.T(t, text.lines[0].spans[1].Face.Font, fallback.fonts[FontRegular])
+	test.Float(t, text.lines[0].spans[1].dx, text.lines[0].spans[0].width)
+	test.T(t, len(text.Fonts()), 2)
+
+	text = NewTextLine(face, "5 \u2113 water", Right)
+	test.T(t, len(text.lines[0].spans), 3)
+	test.T(t, text.lines[0].spans[1].Face.Font, fallback.fonts[FontRegular])
+	test.Float(t, text.lines[0].spans[2].dx+text.lines[0].spans[2].width, 0.0)
+	test.T(t, len(text.Fonts()), 2)
+}
+
 func TestTextBounds(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -0,0 +1,229 @@
+package canvas
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+// TextOverflow specifies how text is laid out when it is longer than the path it follows.
+type TextOverflow int
+
+// see TextOverflow
+const (
+	OverflowHidden  TextOverflow = iota // glyphs beyond the ends of an open path are not drawn, on closed paths they wrap around
+	OverflowVisible                     // the path is extended along its tangents at the ends, on closed paths glyphs wrap around
+	OverflowShrink                      // the text is scaled down to fit the length of the path
+)
+
+type textPath struct {
+	path     *Path
+	align    TextAlign
+	offset   float64
+	overflow TextOverflow
+}
+
+// NewTextOnPath lays out the text along a path, where each glyph is placed and rotated at the arc length position of its center and following the tangent of the path. The baseline of the first line follows the path at a perpendicular offset, with positive offsets to the left of the path direction. The text is aligned to the start, middle or end of the path with Left, Center or Right respectively.
+func NewTextOnPath(text *Text, path *Path, align TextAlign, offset float64, overflow TextOverflow) *Text {
+	t := &Text{text.lines, text.fonts, nil}
+	t.path = &textPath{path, align, offset, overflow}
+	return t
+}
+
+// OnPath returns true if the text is laid out along a path.
+func (t *Text) OnPath() bool {
+	return t.path != nil
+}
+
+// TextPath returns the path followed by the text in the coordinate system of the text, the arc length along the path where the text starts and the perpendicular offset from the path. It is used by renderers that support text on paths natively and returns false if the text is not laid out along a path, or when its layout cannot be expressed as a single line of text following the path, such as text with multiple lines, right-to-left or vertically offset spans, or glyphs beyond the ends of the path, which renderers handle differently from a native text path.
+func (t *Text) TextPath() (*Path, float64, float64, bool) {
+	if t.path == nil || len(t.lines) != 1 {
+		return nil, 0.0, 0.0, false
+	}
+	layout := t.path.layout(t)
+	if layout.scale != 1.0 {
+		return nil, 0.0, 0.0, false
+	}
+	for _, span := range t.lines[0].spans {
+		if span.Direction() == RightToLeft || span.Face.Voffset != 0.0 {
+			return nil, 0.0, 0.0, false
+		}
+		x := span.dx
+		for _, glyph := range span.Glyphs() {
+			s := layout.start + x + glyph.XAdvance/2.0
+			if s < 0.0 || layout.length < s {
+				return nil, 0.0, 0.0, false
+			}
+			x += glyph.XAdvance
+		}
+	}
+	return t.path.path, layout.start, t.path.offset, true
+}
+
+// RenderAsPath renders the text, including its decorations, as filled glyph outlines using the RenderPath method of the Renderer.
+func (t *Text) RenderAsPath(r Renderer, m Matrix) {
+	paths, colors := t.ToPaths()
+	for i, path := range paths {
+		style := DefaultStyle
+		style.Fill.Color = colors[i]
+		r.RenderPath(path, style, m)
+	}
+}
+
+// textPathLayout maps the horizontal positions of the text to arc lengths along the path.
+type textPathLayout struct {
+	arcLengthPath
+	start float64 // arc length of x = 0
+	scale float64 // scale of the text to fit the path
+	y0    float64 // baseline of the first line
+}
+
+func (tp *textPath) layout(t *Text) textPathLayout {
+	layout := textPathLayout{arcLengthPath: newArcLengthPath(tp.path), scale: 1.0}
+	if len(t.lines) == 0 {
+		return layout
+	}
+	layout.y0 = t.lines[0].y
+
+	x0, x1 := math.Inf(1), math.Inf(-1)
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			x0 = math.Min(x0, span.dx)
+			x1 = math.Max(x1, span.dx+span.width)
+		}
+	}
+	if math.IsInf(x0, 1) {
+		return layout
+	}
+
+	width := x1 - x0
+	if tp.overflow == OverflowShrink && layout.length < width {
+		layout.scale = layout.length / width
+		width = layout.length
+	}
+	if tp.align == Center {
+		layout.start = (layout.length - width) / 2.0
+	} else if tp.align == Right {
+		layout.start = layout.length - width
+	}
+	layout.start -= x0 * layout.scale
+	return layout
+}
+
+// transform returns the transformation of a glyph with its center at xCenter on a baseline at y to its position along the path. It returns false if the glyph is beyond the ends of the path and hidden.
+func (layout textPathLayout) transform(xCenter, y float64, tp *textPath) (Matrix, bool) {
+	s := layout.start + xCenter*layout.scale
+	if layout.closed && tp.overflow != OverflowShrink && 0.0 < layout.length {
+		s = math.Mod(s, layout.length)
+		if s < 0.0 {
+			s += layout.length
+		}
+	} else if tp.overflow == OverflowHidden && (s < 0.0 || layout.length < s) {
+		return Identity, false
+	}
+
+	pos, tangent := layout.at(s)
+	m := Identity.Translate(pos.X, pos.Y).Rotate(tangent.Angle()*180.0/math.Pi).Translate(0.0, tp.offset)
+	m = m.Scale(layout.scale, layout.scale).Translate(-xCenter, y-layout.y0)
+	return m, true
+}
+
+// toPaths returns the glyph outlines and decorations of the text placed along the path.
+func (tp *textPath) toPaths(t *Text) ([]*Path, []color.RGBA) {
+	layout := tp.layout(t)
+	paths := []*Path{}
+	colors := []color.RGBA{}
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			p := &Path{}
+			x := span.dx
+			for _, glyph := range span.Glyphs() {
+				xCenter := x + glyph.XAdvance/2.0
+				if m, ok := layout.transform(xCenter, line.y, tp); ok {
+					p = p.Append(span.Face.GlyphPath(glyph.ID).Translate(x+glyph.XOffset, glyph.YOffset).Transform(m))
+				}
+				x += glyph.XAdvance
+			}
+			paths = append(paths, p)
+			colors = append(colors, span.Face.Color)
+		}
+		for _, deco := range line.decos {
+			p := deco.face.Decorate(deco.x1-deco.x0).Translate(deco.x0, line.y)
+			paths = append(paths, layout.warp(p, tp))
+			colors = append(colors, deco.face.Color)
+		}
+	}
+	return paths, colors
+}
+
+// warp bends a path of the text along the path by mapping each point of the flattened path.
+func (layout textPathLayout) warp(p *Path, tp *textPath) *Path {
+	p = p.Flatten()
+	q := &Path{}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		if cmd == closeCmd {
+			q.Close()
+		} else {
+			x, y := p.d[i+1], p.d[i+2]
+			m, ok := layout.transform(x, y, tp)
+			if !ok {
+				// points beyond the path ends follow the tangent at the ends
+				m, _ = layout.transform(x, y, &textPath{tp.path, tp.align, tp.offset, OverflowVisible})
+			}
+			pos := m.Dot(Point{x, 0.0})
+			if cmd == moveToCmd {
+				q.MoveTo(pos.X, pos.Y)
+			} else {
+				q.LineTo(pos.X, pos.Y)
+			}
+		}
+		i += cmdLen(cmd)
+	}
+	return q
+}
+
+////////////////////////////////////////////////////////////////
+
+// arcLengthPath is a flattened path that maps arc lengths to positions and tangents.
+type arcLengthPath struct {
+	starts  []Point   // start of each segment
+	ends    []Point   // end of each segment
+	lengths []float64 // arc length at the start of each segment
+	length  float64
+	closed  bool
+}
+
+func newArcLengthPath(p *Path) arcLengthPath {
+	a := arcLengthPath{closed: p.Closed() && len(p.Split()) == 1}
+	p = p.Flatten()
+	var start, end Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		i += cmdLen(cmd)
+		start, end = end, Point{p.d[i-3], p.d[i-2]}
+		if cmd == moveToCmd {
+			continue
+		}
+		if d := end.Sub(start).Length(); Epsilon < d {
+			a.starts = append(a.starts, start)
+			a.ends = append(a.ends, end)
+			a.lengths = append(a.lengths, a.length)
+			a.length += d
+		}
+	}
+	return a
+}
+
+// at returns the position and unit tangent at arc length s, positions before the start or after the end of the path extend along the tangents at the ends.
+func (a arcLengthPath) at(s float64) (Point, Point) {
+	if len(a.starts) == 0 {
+		return Point{}, Point{1.0, 0.0}
+	}
+	i := sort.SearchFloat64s(a.lengths, s) - 1 // the last segment that starts before s
+	if i < 0 {
+		i = 0
+	}
+	tangent := a.ends[i].Sub(a.starts[i]).Norm(1.0)
+	return a.starts[i].Add(tangent.Mul(s - a.lengths[i])), tangent
+}
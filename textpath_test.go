@@ -0,0 +1,106 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/dtrenin7/test"
+)
+
+func textPathBounds(text *Text) Rect {
+	r := Rect{}
+	paths, _ := text.ToPaths()
+	for _, p := range paths {
+		if !p.Empty() {
+			r = r.Add(p.Bounds())
+		}
+	}
+	return r
+}
+
+func TestTextOnPath(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "mm", Left)
+	width := 2.0 * 11.375
+	b := textPathBounds(text)
+
+	horizontal := MustParseSVG("M0 0L100 0")
+	vertical := MustParseSVG("M0 0L0 100")
+	var tts = []struct {
+		name     string
+		path     *Path
+		align    TextAlign
+		offset   float64
+		overflow TextOverflow
+		bounds   Rect
+	}{
+		{"left", horizontal, Left, 0.0, OverflowHidden, b},
+		{"center", horizontal, Center, 0.0, OverflowHidden, b.Move(Point{(100.0 - width) / 2.0, 0.0})},
+		{"right", horizontal, Right, 0.0, OverflowHidden, b.Move(Point{100.0 - width, 0.0})},
+		{"offset", horizontal, Left, 5.0, OverflowHidden, b.Move(Point{0.0, 5.0})},
+		{"vertical", vertical, Left, 5.0, OverflowHidden, Rect{-b.Y - b.H - 5.0, b.X, b.H, b.W}},
+		{"hidden", MustParseSVG("M0 0L10 0"), Left, 0.0, OverflowHidden, Rect{b.X, b.Y, b.W - 11.375, b.H}},
+		{"visible", MustParseSVG("M0 0L10 0"), Left, 0.0, OverflowVisible, b},
+		{"shrink", MustParseSVG("M0 0L10 0"), Left, 0.0, OverflowShrink, Rect{b.X * 10.0 / width, b.Y * 10.0 / width, b.W * 10.0 / width, b.H * 10.0 / width}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			pathText := NewTextOnPath(text, tt.path, tt.align, tt.offset, tt.overflow)
+			test.That(t, pathText.OnPath())
+			bounds := pathText.OutlineBounds()
+			test.Float(t, bounds.X, tt.bounds.X)
+			test.Float(t, bounds.Y, tt.bounds.Y)
+			test.Float(t, bounds.W, tt.bounds.W)
+			test.Float(t, bounds.H, tt.bounds.H)
+		})
+	}
+}
+
+func TestTextOnPathNative(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "mm", Left)
+	_, _, _, ok := text.TextPath()
+	test.That(t, !ok, "text is not on a path")
+
+	path := MustParseSVG("M0 0L100 0")
+	p, start, offset, ok := NewTextOnPath(text, path, Center, 2.0, OverflowHidden).TextPath()
+	test.That(t, ok)
+	test.T(t, p, path)
+	test.Float(t, start, (100.0-2.0*11.375)/2.0)
+	test.Float(t, offset, 2.0)
+
+	_, _, _, ok = NewTextOnPath(text, MustParseSVG("M0 0L10 0"), Left, 0.0, OverflowVisible).TextPath()
+	test.That(t, !ok, "text overflows the path")
+	_, _, _, ok = NewTextOnPath(text, MustParseSVG("M0 0L10 0"), Left, 0.0, OverflowShrink).TextPath()
+	test.That(t, !ok, "text is scaled")
+	_, _, _, ok = NewTextOnPath(NewTextLine(face, "m\nm", Left), path, Left, 0.0, OverflowHidden).TextPath()
+	test.That(t, !ok, "text has multiple lines")
+}
+
+func TestArcLengthPath(t *testing.T) {
+	a := newArcLengthPath(Rectangle(10.0, 10.0))
+	test.That(t, a.closed)
+	test.Float(t, a.length, 40.0)
+
+	var tts = []struct {
+		s       float64
+		pos     Point
+		tangent Point
+	}{
+		{-1.0, Point{-1.0, 0.0}, Point{1.0, 0.0}},
+		{5.0, Point{5.0, 0.0}, Point{1.0, 0.0}},
+		{15.0, Point{10.0, 5.0}, Point{0.0, 1.0}},
+		{25.0, Point{5.0, 10.0}, Point{-1.0, 0.0}},
+		{40.0, Point{0.0, 0.0}, Point{0.0, -1.0}},
+	}
+	for _, tt := range tts {
+		pos, tangent := a.at(tt.s)
+		test.T(t, pos, tt.pos)
+		test.T(t, tangent, tt.tangent)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/dtrenin7/minify/v2"
+	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -120,6 +121,17 @@ func fromI26_6(f fixed.Int26_6) float64 {
 	return float64(f) / 64.0
 }
 
+// scaleUnits scales font units to 26.6 fixed point with the same rounding as golang.org/x/image/font/sfnt.
+func scaleUnits(x int32, ppem fixed.Int26_6, unitsPerEm sfnt.Units) fixed.Int26_6 {
+	v := int64(x) * int64(ppem)
+	if 0 <= v {
+		v += int64(unitsPerEm) / 2
+	} else {
+		v -= int64(unitsPerEm) / 2
+	}
+	return fixed.Int26_6(v / int64(unitsPerEm))
+}
+
 ////////////////////////////////////////////////////////////////
 
 // Point is a coordinate in 2D space. OP refers to the line that goes through the origin (0,0) and this point (x,y).
@@ -664,12 +676,12 @@ func gaussLegendre3(f func(float64) float64, a, b float64) float64 {
 func gaussLegendre5(f func(float64) float64, a, b float64) float64 {
 	c := (b - a) / 2.0
 	d := (a + b) / 2.0
-	Qd1 := f(-0.90618*c + d)
-	Qd2 := f(-0.538469*c + d)
+	Qd1 := f(-0.9061798459386640*c + d)
+	Qd2 := f(-0.5384693101056831*c + d)
 	Qd3 := f(d)
-	Qd4 := f(0.538469*c + d)
-	Qd5 := f(0.90618*c + d)
-	return c * (0.236927*(Qd1+Qd5) + 0.478629*(Qd2+Qd4) + 0.568889*Qd3)
+	Qd4 := f(0.5384693101056831*c + d)
+	Qd5 := f(0.9061798459386640*c + d)
+	return c * (0.2369268850561891*(Qd1+Qd5) + 0.4786286704993665*(Qd2+Qd4) + 0.5688888888888889*Qd3)
 }
 
 // Gauss-Legendre quadrature integration from a to b with n=7